@@ -28,6 +28,9 @@ import (
 type NodeConfStatus struct {
 	RouterId string `json:"routerId,omitempty"`
 	As       uint32 `json:"as,omitempty"`
+	// PendingFields are the fields of the global config changed but not
+	// applied, they take effect once the as or the router id changes.
+	PendingFields []string `json:"pendingFields,omitempty"`
 }
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
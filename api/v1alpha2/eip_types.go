@@ -32,6 +32,7 @@ import (
 	cnet "github.com/openelb/openelb/pkg/util/net"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -59,14 +60,49 @@ func (e Eip) GetSpeakerName() string {
 	return e.GetProtocol()
 }
 
+// GetSpeakerNames returns the names of all the speakers announcing the eip.
+func (e Eip) GetSpeakerNames() []string {
+	if util.DutyOfCNI(nil, &e.ObjectMeta) {
+		return []string{constant.OpenELBProtocolDummy}
+	}
+
+	return e.GetProtocols()
+}
+
+// GetProtocol returns the first protocol of the eip.
 func (e Eip) GetProtocol() string {
-	if e.Spec.Protocol == constant.OpenELBProtocolLayer2 {
-		return constant.OpenELBProtocolLayer2
+	return e.GetProtocols()[0]
+}
+
+// GetProtocols returns the protocols announcing the eip, the protocols field
+// takes precedence over the protocol field.
+func (e Eip) GetProtocols() []string {
+	if len(e.Spec.Protocols) == 0 {
+		return []string{normalizeProtocol(e.Spec.Protocol)}
 	}
-	if e.Spec.Protocol == constant.OpenELBProtocolVip {
-		return constant.OpenELBProtocolVip
+
+	protocols := []string{}
+	for _, p := range e.Spec.Protocols {
+		p = normalizeProtocol(p)
+		if !util.ContainsString(protocols, p) {
+			protocols = append(protocols, p)
+		}
 	}
-	return constant.OpenELBProtocolBGP
+	return protocols
+}
+
+// HasProtocol returns whether the eip is announced by the protocol.
+func (e Eip) HasProtocol(protocol string) bool {
+	return util.ContainsString(e.GetProtocols(), protocol)
+}
+
+// normalizeProtocol defaults the protocol to bgp, the protocols other than
+// bgp, layer2 and vip are served by the speaker plugins.
+func normalizeProtocol(protocol string) string {
+	if protocol == "" {
+		return constant.OpenELBProtocolBGP
+	}
+	return protocol
 }
 
 func (e Eip) GetSize() (net.IP, int64, error) {
@@ -106,11 +142,14 @@ var _ webhook.Validator = &Eip{}
 type EipSpec struct {
 	// +kubebuilder:validation:Required
 	Address string `json:"address,required"`
-	// +kubebuilder:validation:Enum=bgp;layer2;vip
-	Protocol      string `json:"protocol,omitempty"`
-	Interface     string `json:"interface,omitempty"`
-	Disable       bool   `json:"disable,omitempty"`
-	UsingKnownIPs bool   `json:"usingKnownIPs,omitempty"`
+	// bgp, layer2, vip or the protocol of a speaker plugin
+	Protocol string `json:"protocol,omitempty"`
+	// announce the eip via all the protocols, e.g. layer2 on the local segment
+	// and bgp to the core, the protocol is ignored if it's set
+	Protocols     []string `json:"protocols,omitempty"`
+	Interface     string   `json:"interface,omitempty"`
+	Disable       bool     `json:"disable,omitempty"`
+	UsingKnownIPs bool     `json:"usingKnownIPs,omitempty"`
 	// priority for automatically assigning addresses
 	Priority int `json:"priority,omitempty"`
 	// specify the namespace for the allocation by name
@@ -119,6 +158,16 @@ type EipSpec struct {
 	NamespaceSelector map[string]string `json:"namespaceSelector,omitempty"`
 }
 
+// NodeEipStatus is the status of the eip on a node
+type NodeEipStatus struct {
+	// the interface resolved from the interface of the eip
+	Interface string `json:"interface,omitempty"`
+	// the speaker of the node can serve the eip
+	Ready bool `json:"ready,omitempty"`
+	// the last error of configuring the speaker with the eip
+	Error string `json:"error,omitempty"`
+}
+
 // EipStatus defines the observed state of EIP
 type EipStatus struct {
 	Occupied bool              `json:"occupied,omitempty"`
@@ -129,6 +178,8 @@ type EipStatus struct {
 	LastIP   string            `json:"lastIP,omitempty"`
 	Ready    bool              `json:"ready,omitempty"`
 	V4       bool              `json:"v4,omitempty"`
+	// the status of the eip on the nodes running a speaker
+	NodesStatus map[string]NodeEipStatus `json:"nodesStatus,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -191,12 +242,78 @@ func (e Eip) ValidateCreate() (admission.Warnings, error) {
 		return nil, err
 	}
 
-	if (e.Spec.Protocol == constant.OpenELBProtocolLayer2 || e.Spec.Protocol == constant.OpenELBProtocolVip) && e.Spec.Interface == "" {
-		return nil, fmt.Errorf("if protocol is layer2 or vip, interface should not be empty")
+	if err := e.validateProtocols(); err != nil {
+		return nil, err
+	}
+	if err := e.validateShrinkPolicy(); err != nil {
+		return nil, err
 	}
 	return nil, e.validate(true)
 }
 
+// validateProtocols validates the protocols of the eip, layer2 and vip both
+// answer the arp requests of the eip so they can't be mixed.
+func (e Eip) validateProtocols() error {
+	for _, p := range append([]string{e.Spec.Protocol}, e.Spec.Protocols...) {
+		if p == "" {
+			continue
+		}
+		if p == constant.OpenELBProtocolDummy {
+			return fmt.Errorf("invalid protocol %s", p)
+		}
+		if msgs := validation.IsDNS1123Label(p); len(msgs) != 0 {
+			return fmt.Errorf("invalid protocol %s, should be bgp, layer2, vip or the protocol of a speaker plugin: %s", p, strings.Join(msgs, ","))
+		}
+	}
+	if len(e.Spec.Protocols) != 0 && e.Spec.Protocol != "" && !util.ContainsString(e.Spec.Protocols, e.Spec.Protocol) {
+		return fmt.Errorf("protocol %s is not in protocols %v", e.Spec.Protocol, e.Spec.Protocols)
+	}
+
+	if e.HasProtocol(constant.OpenELBProtocolLayer2) && e.HasProtocol(constant.OpenELBProtocolVip) {
+		return fmt.Errorf("protocols layer2 and vip can't be used together")
+	}
+	if (e.HasProtocol(constant.OpenELBProtocolLayer2) || e.HasProtocol(constant.OpenELBProtocolVip)) && e.Spec.Interface == "" {
+		return fmt.Errorf("if protocol is layer2 or vip, interface should not be empty")
+	}
+	return nil
+}
+
+// validateResize validates the address range of the eip can be grown or
+// shrunk in place, the services out of the new range are handled according
+// to the shrink policy.
+func (e Eip) validateResize(old *Eip) error {
+	base, _, err := e.GetSize()
+	if err != nil {
+		return err
+	}
+
+	oldBase, _, err := old.GetSize()
+	if err != nil {
+		return err
+	}
+
+	if (base.To4() == nil) != (oldBase.To4() == nil) {
+		return fmt.Errorf("the address family is not allowed to be modified")
+	}
+
+	return nil
+}
+
+func (e Eip) validateShrinkPolicy() error {
+	switch e.Annotations[constant.OpenELBEIPAnnotationShrinkPolicy] {
+	case "", constant.OpenELBEipShrinkPolicyKeep, constant.OpenELBEipShrinkPolicyReassign:
+	default:
+		return fmt.Errorf("invalid shrink policy %s, should be %s or %s", e.Annotations[constant.OpenELBEIPAnnotationShrinkPolicy],
+			constant.OpenELBEipShrinkPolicyKeep, constant.OpenELBEipShrinkPolicyReassign)
+	}
+
+	if e.Annotations[constant.OpenELBEIPAnnotationShrinkTarget] == e.Name && e.Name != "" {
+		return fmt.Errorf("the shrink target should not be the eip itself")
+	}
+
+	return nil
+}
+
 func (e Eip) validate(overlap bool) error {
 	eips := &EipList{}
 	if err := client.Client.List(context.Background(), eips); err != nil {
@@ -261,14 +378,21 @@ func (e Eip) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
 		}
 	}
 
-	if !reflect.DeepEqual(e.Spec, oldE.Spec) {
-		if e.Spec.Address != oldE.Spec.Address {
-			return nil, fmt.Errorf("the address field is not allowed to be modified")
+	if e.Spec.Address != oldE.Spec.Address {
+		if err := e.validateResize(oldE); err != nil {
+			return nil, err
+		}
+		if err := e.validate(true); err != nil {
+			return nil, err
 		}
 	}
 
-	if (e.Spec.Protocol == constant.OpenELBProtocolLayer2 || e.Spec.Protocol == constant.OpenELBProtocolVip) && e.Spec.Interface == "" {
-		return nil, fmt.Errorf("if protocol is layer2 or vip, interface should not be empty")
+	if err := e.validateShrinkPolicy(); err != nil {
+		return nil, err
+	}
+
+	if err := e.validateProtocols(); err != nil {
+		return nil, err
 	}
 
 	return nil, nil
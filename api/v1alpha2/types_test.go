@@ -6,8 +6,12 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/openelb/openelb/pkg/client"
+	"github.com/openelb/openelb/pkg/constant"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
@@ -59,6 +63,46 @@ var _ = Describe("Test eip types", func() {
 		Expect(err).Should(HaveOccurred())
 	})
 
+	It("Test GetProtocols", func() {
+		e := &Eip{Spec: EipSpec{Address: "192.168.0.1"}}
+		Expect(e.GetProtocols()).Should(Equal([]string{constant.OpenELBProtocolBGP}))
+
+		e.Spec.Protocol = constant.OpenELBProtocolLayer2
+		Expect(e.GetProtocols()).Should(Equal([]string{constant.OpenELBProtocolLayer2}))
+
+		e.Spec.Protocols = []string{constant.OpenELBProtocolLayer2, constant.OpenELBProtocolBGP, constant.OpenELBProtocolLayer2}
+		Expect(e.GetProtocols()).Should(Equal([]string{constant.OpenELBProtocolLayer2, constant.OpenELBProtocolBGP}))
+		Expect(e.GetProtocol()).Should(Equal(constant.OpenELBProtocolLayer2))
+		Expect(e.HasProtocol(constant.OpenELBProtocolBGP)).Should(BeTrue())
+		Expect(e.HasProtocol(constant.OpenELBProtocolVip)).Should(BeFalse())
+	})
+
+	It("Test validateProtocols", func() {
+		e := &Eip{Spec: EipSpec{Address: "192.168.0.1", Protocols: []string{constant.OpenELBProtocolBGP, constant.OpenELBProtocolLayer2}}}
+		Expect(e.validateProtocols()).Should(HaveOccurred())
+
+		e.Spec.Interface = "eth0"
+		Expect(e.validateProtocols()).ShouldNot(HaveOccurred())
+
+		e.Spec.Protocol = constant.OpenELBProtocolVip
+		Expect(e.validateProtocols()).Should(HaveOccurred())
+
+		e.Spec.Protocol = ""
+		e.Spec.Protocols = []string{constant.OpenELBProtocolLayer2, constant.OpenELBProtocolVip}
+		Expect(e.validateProtocols()).Should(HaveOccurred())
+
+		e.Spec.Protocols = []string{"Invalid_Protocol"}
+		Expect(e.validateProtocols()).Should(HaveOccurred())
+
+		e.Spec.Protocols = []string{constant.OpenELBProtocolDummy}
+		Expect(e.validateProtocols()).Should(HaveOccurred())
+
+		// served by a speaker plugin
+		e.Spec.Protocols = []string{"cloud", constant.OpenELBProtocolBGP}
+		Expect(e.validateProtocols()).ShouldNot(HaveOccurred())
+		Expect(e.GetProtocols()).Should(Equal([]string{"cloud", constant.OpenELBProtocolBGP}))
+	})
+
 	It("Test IPToOrdinal", func() {
 		e := &Eip{
 			TypeMeta:   metav1.TypeMeta{},
@@ -136,11 +180,53 @@ var _ = Describe("Test eip types", func() {
 			Status: EipStatus{},
 		}
 
+		other := &Eip{
+			ObjectMeta: metav1.ObjectMeta{Name: "other"},
+			Spec:       EipSpec{Address: "192.168.0.220-192.168.0.250"},
+		}
+		scheme := runtime.NewScheme()
+		Expect(AddToScheme(scheme)).ShouldNot(HaveOccurred())
+		client.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(other).Build()
+
+		// the range can be shrunk and grown in place
 		e2 := e.DeepCopy()
 		e2.Spec.Address = "192.168.0.100"
 		_, err := e2.ValidateUpdate(e)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		e2.Spec.Address = "192.168.0.0/25"
+		_, err = e2.ValidateUpdate(e)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		e2.Spec.Address = "192.168.0.100-192.168.0.230"
+		_, err = e2.ValidateUpdate(e)
+		Expect(err).Should(HaveOccurred())
+
+		e2.Spec.Address = "fd00::1-fd00::10"
+		_, err = e2.ValidateUpdate(e)
+		Expect(err).Should(HaveOccurred())
+
+		e2.Spec.Address = "192.168.0.200-192.168.0.100"
+		_, err = e2.ValidateUpdate(e)
+		Expect(err).Should(HaveOccurred())
+
+		// the range of the old eip can't be compared
+		invalid := e.DeepCopy()
+		invalid.Spec.Address = "192.168.0.300"
+		e2.Spec.Address = "192.168.0.100"
+		_, err = e2.ValidateUpdate(invalid)
 		Expect(err).Should(HaveOccurred())
 
+		e2 = e.DeepCopy()
+		e2.Annotations = map[string]string{constant.OpenELBEIPAnnotationShrinkPolicy: "Drop"}
+		_, err = e2.ValidateUpdate(e)
+		Expect(err).Should(HaveOccurred())
+
+		e2.Annotations[constant.OpenELBEIPAnnotationShrinkPolicy] = constant.OpenELBEipShrinkPolicyReassign
+		e2.Annotations[constant.OpenELBEIPAnnotationShrinkTarget] = "other"
+		_, err = e2.ValidateUpdate(e)
+		Expect(err).ShouldNot(HaveOccurred())
+
 		e2 = e.DeepCopy()
 		e2.Spec.Disable = true
 		_, err = e2.ValidateUpdate(e)
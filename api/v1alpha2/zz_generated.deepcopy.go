@@ -214,7 +214,7 @@ func (in *BgpConfStatus) DeepCopyInto(out *BgpConfStatus) {
 		in, out := &in.NodesConfStatus, &out.NodesConfStatus
 		*out = make(map[string]NodeConfStatus, len(*in))
 		for key, val := range *in {
-			(*out)[key] = val
+			(*out)[key] = *val.DeepCopy()
 		}
 	}
 }
@@ -443,6 +443,11 @@ func (in *EipList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EipSpec) DeepCopyInto(out *EipSpec) {
 	*out = *in
+	if in.Protocols != nil {
+		in, out := &in.Protocols, &out.Protocols
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Namespaces != nil {
 		in, out := &in.Namespaces, &out.Namespaces
 		*out = make([]string, len(*in))
@@ -477,6 +482,13 @@ func (in *EipStatus) DeepCopyInto(out *EipStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.NodesStatus != nil {
+		in, out := &in.NodesStatus, &out.NodesStatus
+		*out = make(map[string]NodeEipStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EipStatus.
@@ -597,6 +609,11 @@ func (in *MpGracefulRestartConfig) DeepCopy() *MpGracefulRestartConfig {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeConfStatus) DeepCopyInto(out *NodeConfStatus) {
 	*out = *in
+	if in.PendingFields != nil {
+		in, out := &in.PendingFields, &out.PendingFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeConfStatus.
@@ -609,6 +626,21 @@ func (in *NodeConfStatus) DeepCopy() *NodeConfStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeEipStatus) DeepCopyInto(out *NodeEipStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeEipStatus.
+func (in *NodeEipStatus) DeepCopy() *NodeEipStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeEipStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodePeerStatus) DeepCopyInto(out *NodePeerStatus) {
 	*out = *in
@@ -6,12 +6,17 @@ import (
 	"os"
 
 	"github.com/openelb/openelb/cmd/apiserver/app/options"
+	"github.com/openelb/openelb/pkg/manager"
+	"github.com/openelb/openelb/pkg/server"
 	"github.com/openelb/openelb/pkg/version"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/component-base/term"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 func NewOpenELBApiServerCommand() *cobra.Command {
@@ -68,12 +73,22 @@ func NewOpenELBApiServerCommand() *cobra.Command {
 }
 
 func Run(c *options.OpenELBApiServerOptions) error {
-	// client, err := client.NewClient(ctrl.GetConfigOrDie())
-	// stopCh := ctrl.SetupSignalHandler()
-	// err = server.SetupHTTPServer(stopCh, client, c.HTTPOptions)
-	// if err != nil {
-	// 	klog.Fatalf("unable to setup http server: %v", err)
-	// }
+	cfg := ctrl.GetConfigOrDie()
+	// The manager is only used to set up the client of the handlers and the
+	// cache of the watch endpoints
+	mgr, err := manager.NewManager(cfg, nil)
+	if err != nil {
+		return fmt.Errorf("unable to new client: %v", err)
+	}
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to new kubernetes client: %v", err)
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+	if err := server.SetupHTTPServer(ctx, kubeClient, mgr.GetCache(), c.HTTPOptions); err != nil {
+		klog.Fatalf("unable to setup http server: %v", err)
+	}
 
 	return nil
 }
@@ -0,0 +1,24 @@
+package app
+
+import (
+	"github.com/openelb/openelb/pkg/nodeproxy"
+	"github.com/spf13/cobra"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// newNodeProxyCommand runs the node-proxy agent from the speaker image, so
+// that the node-proxy pods do not need images of their own.
+func newNodeProxyCommand() *cobra.Command {
+	opt := nodeproxy.NewOptions()
+
+	cmd := &cobra.Command{
+		Use:   "node-proxy",
+		Short: "Forward the traffic received by a node-proxy pod to the service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return nodeproxy.Run(opt, ctrl.SetupSignalHandler().Done())
+		},
+	}
+	opt.AddFlags(cmd.Flags())
+
+	return cmd
+}
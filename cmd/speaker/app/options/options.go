@@ -3,9 +3,11 @@ package options
 import (
 	"flag"
 	"strings"
+	"time"
 
 	"github.com/openelb/openelb/pkg/speaker/bgp/bgp"
 	"github.com/openelb/openelb/pkg/speaker/layer2"
+	"github.com/openelb/openelb/pkg/speaker/plugin"
 	"github.com/openelb/openelb/pkg/speaker/vip"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/klog/v2"
@@ -16,6 +18,11 @@ type OpenELBSpeakerOptions struct {
 	Bgp         *bgp.BgpOptions
 	Layer2      *layer2.Options
 	Vip         *vip.VipOptions
+	Plugin      *plugin.Options
+
+	// enforce the loadBalancerSourceRanges of the services on the ips owned by the node
+	EnableSourceRanges       bool
+	SourceRangesResyncPeriod time.Duration
 }
 
 func NewOpenELBSpeakerOptions() *OpenELBSpeakerOptions {
@@ -24,11 +31,16 @@ func NewOpenELBSpeakerOptions() *OpenELBSpeakerOptions {
 		Bgp:         bgp.NewBgpOptions(),
 		Layer2:      layer2.NewOptions(),
 		Vip:         vip.NewVipOptions(),
+		Plugin:      plugin.NewOptions(),
+
+		EnableSourceRanges:       false,
+		SourceRangesResyncPeriod: 5 * time.Second,
 	}
 }
 
 func (s *OpenELBSpeakerOptions) Validate() []error {
 	var errs []error
+	errs = append(errs, s.Plugin.Validate()...)
 
 	return errs
 }
@@ -39,9 +51,12 @@ func (s *OpenELBSpeakerOptions) Flags() cliflag.NamedFlagSets {
 	s.Bgp.AddFlags(fss.FlagSet("bgp"))
 	s.Layer2.AddFlags(fss.FlagSet("layer2"))
 	s.Vip.AddFlags(fss.FlagSet("vip"))
+	s.Plugin.AddFlags(fss.FlagSet("plugin"))
 
 	fs := fss.FlagSet("generic")
 	fs.StringVar(&s.MetricsAddr, "metrics-addr", s.MetricsAddr, "The address the metric endpoint binds to.")
+	fs.BoolVar(&s.EnableSourceRanges, "enable-source-ranges", s.EnableSourceRanges, "Drop the traffic to the ips owned by the node by the layer2 and vip speakers from the sources out of the loadBalancerSourceRanges of the services, requires iptables on the node.")
+	fs.DurationVar(&s.SourceRangesResyncPeriod, "source-ranges-resync-period", s.SourceRangesResyncPeriod, "The interval to resync the source ranges rules, for the ownership changes of the ips not notified by the speakers, e.g. keepalived.")
 
 	kfs := fss.FlagSet("klog")
 	local := flag.NewFlagSet("klog", flag.ExitOnError)
@@ -8,10 +8,13 @@ import (
 	"github.com/openelb/openelb/cmd/speaker/app/options"
 	"github.com/openelb/openelb/pkg/constant"
 	_ "github.com/openelb/openelb/pkg/metrics"
+	"github.com/openelb/openelb/pkg/nettool"
 	"github.com/openelb/openelb/pkg/speaker"
 	"github.com/openelb/openelb/pkg/speaker/bgp"
 	bgpd "github.com/openelb/openelb/pkg/speaker/bgp/bgp"
+	"github.com/openelb/openelb/pkg/speaker/bgp/frr"
 	"github.com/openelb/openelb/pkg/speaker/layer2"
+	"github.com/openelb/openelb/pkg/speaker/plugin"
 	"github.com/openelb/openelb/pkg/speaker/vip"
 	"github.com/openelb/openelb/pkg/version"
 	"github.com/spf13/cobra"
@@ -78,6 +81,7 @@ func NewOpenELBSpeakerCommand() *cobra.Command {
 		},
 	}
 	cmd.AddCommand(versionCmd)
+	cmd.AddCommand(newNodeProxyCommand())
 
 	return cmd
 }
@@ -97,8 +101,19 @@ func Run(opt *options.OpenELBSpeakerOptions) error {
 	ctx := ctrl.SetupSignalHandler()
 	spmanager := speaker.NewSpeakerManager(mgr)
 
-	//For gobgp
-	bgpServer := bgpd.NewGoBgpd(opt.Bgp)
+	// the speakers ask the eip controller to resync their balancers
+	reloadChan := make(chan event.GenericEvent)
+
+	//For gobgp or frr
+	var bgpServer bgp.BgpServer
+	switch opt.Bgp.Backend {
+	case constant.BgpBackendGoBgp:
+		bgpServer = bgpd.NewGoBgpd(opt.Bgp, reloadChan)
+	case constant.BgpBackendFrr:
+		bgpServer = frr.NewFrr(opt.Bgp)
+	default:
+		klog.Fatalf("unsupported bgp backend %s", opt.Bgp.Backend)
+	}
 	if err := bgp.SetupBgpConfReconciler(bgpServer, mgr); err != nil {
 		klog.Fatalf("unable to setup bgpconf: %v", err)
 	}
@@ -107,6 +122,15 @@ func Run(opt *options.OpenELBSpeakerOptions) error {
 		klog.Fatalf("unable to setup bgppeer: %v", err)
 	}
 
+	// gobgpd co-exists with bird on the nodes of calico by listening on another port
+	if opt.Bgp.Backend == constant.BgpBackendGoBgp {
+		if ipt, err := nettool.NewIPTables(false); err != nil {
+			klog.Warningf("the bgp connections are not forwarded to gobgpd, unable to new iptables: %v", err)
+		} else if err := bgp.SetupPortForwardReconciler(ipt, opt.Bgp.PortForwardResyncPeriod, mgr); err != nil {
+			klog.Fatalf("unable to setup bgp port forward: %v", err)
+		}
+	}
+
 	if err := spmanager.RegisterSpeaker(ctx, constant.OpenELBProtocolBGP, bgpServer); err != nil {
 		klog.Fatalf("unable to register bgp speaker: %v", err)
 	}
@@ -114,19 +138,18 @@ func Run(opt *options.OpenELBSpeakerOptions) error {
 	//For keepalive
 	k8sClient := clientset.NewForConfigOrDie(ctrl.GetConfigOrDie())
 	if opt.Vip.EnableVIP {
-		keepalive, err := vip.NewKeepAlived(k8sClient, opt.Vip.LogPath, opt.Vip.KeepAlivedArgs)
+		vipSpeaker, err := vip.NewSpeaker(k8sClient, opt.Vip)
 		if err != nil {
 			klog.Fatalf("unable to new vip speaker: %v", err)
 		}
-		if err := spmanager.RegisterSpeaker(ctx, constant.OpenELBProtocolVip, keepalive); err != nil {
+		if err := spmanager.RegisterSpeaker(ctx, constant.OpenELBProtocolVip, vipSpeaker); err != nil {
 			klog.Fatalf("unable to register vip speaker: %v", err)
 		}
 	}
 
 	// for layer2 mode
-	reloadChan := make(chan event.GenericEvent)
 	if opt.Layer2.EnableLayer2 {
-		layer2speaker, err := layer2.NewSpeaker(k8sClient, opt.Layer2, reloadChan)
+		layer2speaker, err := layer2.NewSpeaker(k8sClient, mgr.GetEventRecorderFor("layer2"), opt.Layer2, reloadChan)
 		if err != nil {
 			klog.Fatalf("unable to new layer2 speaker: %v", err)
 		}
@@ -136,6 +159,22 @@ func Run(opt *options.OpenELBSpeakerOptions) error {
 		}
 	}
 
+	// for the external speakers
+	for protocol, endpoint := range opt.Plugin.Plugins {
+		pluginSpeaker, err := plugin.NewSpeaker(protocol, endpoint, opt.Plugin.Timeout, reloadChan)
+		if err != nil {
+			klog.Fatalf("unable to new speaker plugin %s: %v", protocol, err)
+		}
+
+		if err := spmanager.RegisterSpeaker(ctx, protocol, pluginSpeaker); err != nil {
+			klog.Fatalf("unable to register speaker plugin %s: %v", protocol, err)
+		}
+	}
+
+	if opt.EnableSourceRanges {
+		setupSourceRanges(opt, spmanager, mgr)
+	}
+
 	if err := (&speaker.LBReconciler{
 		Handler:       spmanager.HandleService,
 		Client:        mgr.GetClient(),
@@ -160,3 +199,21 @@ func Run(opt *options.OpenELBSpeakerOptions) error {
 
 	return nil
 }
+
+// setupSourceRanges enforces the loadBalancerSourceRanges of the services, the
+// speaker keeps announcing the ips without enforcing them if iptables isn't
+// available on the node.
+func setupSourceRanges(opt *options.OpenELBSpeakerOptions, spmanager *speaker.Manager, mgr ctrl.Manager) {
+	ipt, err := nettool.NewIPTables(false)
+	if err != nil {
+		klog.Warningf("the source ranges are not enforced, unable to new iptables: %v", err)
+		return
+	}
+	ip6t, err := nettool.NewIPTables(true)
+	if err != nil {
+		klog.Warningf("the source ranges of ipv6 are not enforced: %v", err)
+	}
+	if err := speaker.SetupSourceRangesReconciler(ipt, ip6t, spmanager, opt.SourceRangesResyncPeriod, mgr); err != nil {
+		klog.Fatalf("unable to setup source ranges: %v", err)
+	}
+}
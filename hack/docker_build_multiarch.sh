@@ -39,18 +39,3 @@ ${CONTAINER_CLI} ${CONTAINER_BUILDER} \
   -f build/speaker/Dockerfile \
   -t "${REPO}"/openelb-speaker:"${TAG}" .
 
-
-# shellcheck disable=SC2086 # inteneded splitting of CONTAINER_BUILDER
-${CONTAINER_CLI} ${CONTAINER_BUILDER} \
-  --platform ${PLATFORMS} \
-  ${PUSH} \
-  -f build/proxy/Dockerfile \
-  -t "${REPO}"/openelb-proxy:"${TAG}" .
-
-
-# shellcheck disable=SC2086 # inteneded splitting of CONTAINER_BUILDER
-${CONTAINER_CLI} ${CONTAINER_BUILDER} \
-  --platform ${PLATFORMS} \
-  ${PUSH} \
-  -f build/forward/Dockerfile \
-  -t "${REPO}"/openelb-forward:"${TAG}" .
@@ -12,25 +12,32 @@ const (
 	// Indicates the node to which layer2 traffic is sent
 	OpenELBLayer2Annotation string = "layer2.openelb.kubesphere.io/v1alpha1"
 
-	NodeProxyTypeAnnotationKey        string = "node-proxy.openelb.kubesphere.io/type"
-	NodeProxyTypeDeployment           string = "deployment"
-	NodeProxyTypeDaemonSet            string = "daemonset"
-	LabelNodeProxyExternalIPPreffered string = "node-proxy.openelb.kubesphere.io/external-ip-preffered"
-	LabelNodeProxyExcludeNode         string = "node-proxy.openelb.kubesphere.io/exclude-node"
-	NodeProxyExternalIPAnnotationKey  string = "node-proxy.openelb.kubesphere.io/external-ip"
-	NodeProxyInternalIPAnnotationKey  string = "node-proxy.openelb.kubesphere.io/internal-ip"
-	NameSeparator                     string = "-"
-	IPSeparator                       string = ","
-	EnvArgSplitter                    string = " "
-	NodeProxyWorkloadPrefix           string = "node-proxy-"
-	NodeProxyFinalizerName            string = "node-proxy.openelb.kubesphere.io/finalizer"
+	NodeProxyTypeAnnotationKey         string = "node-proxy.openelb.kubesphere.io/type"
+	NodeProxyTypeDeployment            string = "deployment"
+	NodeProxyTypeDaemonSet             string = "daemonset"
+	LabelNodeProxyExternalIPPreffered  string = "node-proxy.openelb.kubesphere.io/external-ip-preffered"
+	LabelNodeProxyExcludeNode          string = "node-proxy.openelb.kubesphere.io/exclude-node"
+	NodeProxyExternalIPAnnotationKey   string = "node-proxy.openelb.kubesphere.io/external-ip"
+	NodeProxyInternalIPAnnotationKey   string = "node-proxy.openelb.kubesphere.io/internal-ip"
+	NodeProxyPortMappingAnnotationKey  string = "node-proxy.openelb.kubesphere.io/port-mapping"
+	NodeProxyTemplateHashAnnotationKey string = "node-proxy.openelb.kubesphere.io/template-hash"
+	NodeProxyConfigAnnotationKey       string = "node-proxy.openelb.kubesphere.io/config"
+	NameSeparator                      string = "-"
+	IPSeparator                        string = ","
+	EnvArgSplitter                     string = " "
+	NodeProxyWorkloadPrefix            string = "node-proxy-"
+	NodeProxyFinalizerName             string = "node-proxy.openelb.kubesphere.io/finalizer"
 
 	KubernetesMasterLabel string = "node-role.kubernetes.io/master"
 
 	OpenELBEIPAnnotationKey         string = "eip.openelb.kubesphere.io/v1alpha1"
 	OpenELBEIPAnnotationKeyV1Alpha2 string = "eip.openelb.kubesphere.io/v1alpha2"
 	OpenELBEIPAnnotationDefaultPool string = "eip.openelb.kubesphere.io/is-default-eip"
-	OpenELBProtocolAnnotationKey    string = "protocol.openelb.kubesphere.io/v1alpha1"
+	// The policy of the services allocated out of the range of a shrunk Eip, Keep or Reassign
+	OpenELBEIPAnnotationShrinkPolicy string = "eip.openelb.kubesphere.io/shrink-policy"
+	// The Eip the services out of the range of a shrunk Eip are reassigned to
+	OpenELBEIPAnnotationShrinkTarget string = "eip.openelb.kubesphere.io/shrink-target"
+	OpenELBProtocolAnnotationKey     string = "protocol.openelb.kubesphere.io/v1alpha1"
 
 	OpenELBNodeRack string = "openelb.kubesphere.io/rack"
 	// TODO: Disable lable modification using webhook
@@ -43,6 +50,9 @@ const (
 	OpenELBCNICalico      string = "calico"
 	EipRangeSeparator     string = "-"
 
+	OpenELBEipShrinkPolicyKeep     string = "Keep"
+	OpenELBEipShrinkPolicyReassign string = "Reassign"
+
 	OpenELBControllerLocker = "openelb-controller"
 	OpenELBSpeakerName      = "openelb-speaker"
 	OpenELBNamespace        = "openelb-system"
@@ -56,10 +66,44 @@ const (
 	OpenELBImagesConfigMap         = "openelb-images"
 	NodeProxyConfigMapForwardImage = "forward-image"
 	NodeProxyConfigMapProxyImage   = "proxy-image"
-	NodeProxyDefaultForwardImage   = "kubesphere/openelb-forward:master"
-	NodeProxyDefaultProxyImage     = "kubesphere/openelb-proxy:master"
+	NodeProxyConfigMapConfig       = "node-proxy-config"
+	NodeProxyDefaultForwardImage   = "kubesphere/openelb-speaker:master"
+	NodeProxyDefaultProxyImage     = "kubesphere/openelb-speaker:master"
+	NodeProxyRulesEnv              = "PROXY_ARGS"
+	NodeProxyCommand               = "openelb-speaker"
+	NodeProxySubCommand            = "node-proxy"
+
+	// service conditions of node-proxy
+	NodeProxyConditionReady          = "NodeProxyReady"
+	NodeProxyConditionDegraded       = "NodeProxyDegraded"
+	NodeProxyReasonProxyPodsReady    = "ProxyPodsReady"
+	NodeProxyReasonNoReadyProxyPod   = "NoReadyProxyPod"
+	NodeProxyReasonNodesDropped      = "NodesDropped"
+	NodeProxyReasonAllNodesAvailable = "AllNodesAvailable"
 
 	Layer2MemberlistDefaultSecret = "openelb-speakers"
 	Layer2ReloadEIPName           = "reload"
 	Layer2ReloadEIPNamespace      = "openelb-layer2-eip-reload"
+	BgpReloadEIPName              = "reload"
+	BgpReloadEIPNamespace         = "openelb-bgp-eip-reload"
+	// the name of the reload eip of a plugin speaker is its protocol
+	PluginReloadEIPNamespace = "openelb-plugin-eip-reload"
+
+	// layer2 ownership backends
+	Layer2OwnershipMemberlist      = "memberlist"
+	Layer2OwnershipLease           = "lease"
+	Layer2LeasePrefix              = "openelb-layer2-"
+	OpenELBLayer2LeaseIPAnnotation = "layer2.openelb.kubesphere.io/ip"
+
+	// vip backends
+	VipBackendKeepalived = "keepalived"
+	VipBackendVrrp       = "vrrp"
+
+	// bgp backends
+	BgpBackendGoBgp = "gobgp"
+	BgpBackendFrr   = "frr"
+
+	// VRRP settings of a node in vip mode, set as node label or annotation
+	OpenELBVipPriority = "vip.openelb.kubesphere.io/priority"
+	OpenELBVipPreempt  = "vip.openelb.kubesphere.io/preempt"
 )
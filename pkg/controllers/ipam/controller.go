@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"net"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -27,6 +29,8 @@ import (
 type EIPController struct {
 	client.Client
 	record.EventRecorder
+
+	reported shrinkReports
 }
 
 const name = "EIPController"
@@ -106,17 +110,56 @@ func (i *EIPController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 }
 
 func (i *EIPController) updateEip(ctx context.Context, e *networkv1alpha2.Eip) error {
-	if e.Status.FirstIP == "" {
-		base, size, _ := e.GetSize()
+	// the range is updated after the eip is grown or shrunk
+	base, size, err := e.GetSize()
+	if err != nil {
+		return err
+	}
+	if e.Status.FirstIP != base.String() || e.Status.PoolSize != int(size) {
 		e.Status.PoolSize = int(size)
 		e.Status.FirstIP = base.String()
 		e.Status.LastIP = cnet.IncrementIP(cnet.IP{IP: base}, big.NewInt(size-1)).String()
-		if base.To4() != nil {
-			e.Status.V4 = true
+		e.Status.V4 = base.To4() != nil
+	}
+
+	if err := i.syncEip(ctx, e); err != nil {
+		return err
+	}
+
+	i.reportOutOfRange(e)
+	return nil
+}
+
+// reportOutOfRange records an event of the services allocated out of the
+// range of the shrunk eip, they're reassigned by the lb controller if the
+// shrink policy is Reassign.
+func (i *EIPController) reportOutOfRange(e *networkv1alpha2.Eip) {
+	svcs := []string{}
+	for addr, used := range e.Status.Used {
+		if !e.Contains(net.ParseIP(addr)) {
+			svcs = append(svcs, fmt.Sprintf("%s(%s)", used, addr))
 		}
 	}
+	if len(svcs) == 0 {
+		i.reported.forget(e.Name)
+		return
+	}
+	if !i.reported.changed(e.Name, e) {
+		return
+	}
 
-	return i.syncEip(ctx, e)
+	sort.Strings(svcs)
+	policy := e.Annotations[constant.OpenELBEIPAnnotationShrinkPolicy]
+	if policy != constant.OpenELBEipShrinkPolicyReassign {
+		i.Eventf(e, v1.EventTypeWarning, EipShrinkReason, "services out of the range are kept: %s", strings.Join(svcs, ","))
+		return
+	}
+
+	target := e.Name
+	if e.Annotations[constant.OpenELBEIPAnnotationShrinkTarget] != "" {
+		target = e.Annotations[constant.OpenELBEIPAnnotationShrinkTarget]
+	}
+	i.Eventf(e, v1.EventTypeNormal, EipShrinkReason, "services out of the range are reassigned from eip %s: %s", target, strings.Join(svcs, ","))
 }
 
 func (i *EIPController) syncEip(ctx context.Context, e *networkv1alpha2.Eip) error {
@@ -148,7 +191,19 @@ func (i *EIPController) syncEip(ctx context.Context, e *networkv1alpha2.Eip) err
 	}
 	e.Status.Used = used
 	e.Status.Usage = len(used)
-	e.Status.Occupied = e.Status.Usage >= e.Status.PoolSize
+	e.Status.Occupied = InRangeUsage(e) >= e.Status.PoolSize
+
+	// the status of the deleted nodes is dropped
+	for node := range e.Status.NodesStatus {
+		err := i.Get(ctx, client.ObjectKey{Name: node}, &v1.Node{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				delete(e.Status.NodesStatus, node)
+				continue
+			}
+			return err
+		}
+	}
 
 	return nil
 }
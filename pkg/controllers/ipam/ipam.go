@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 
 	networkv1alpha2 "github.com/openelb/openelb/api/v1alpha2"
 	"github.com/openelb/openelb/pkg/constant"
@@ -29,11 +30,46 @@ const (
 	EipDeleteReason      = "delete eip"
 	EipAddOrUpdateReason = "add/update eip"
 	EipNotContainIP      = "no available eip was found containing the ip"
+	EipShrinkReason      = "shrink eip"
 )
 
 type Manager struct {
 	client.Client
 	record.EventRecorder
+
+	reported shrinkReports
+}
+
+// shrinkReports remembers the shrink of the eips reported on every object, so
+// the events are recorded once per change instead of on every reconcile.
+type shrinkReports struct {
+	lock    sync.Mutex
+	reports map[string]string
+}
+
+// changed returns true if the shrink of the eip isn't reported on the object
+// yet, the shrink changes with the generation and the shrink annotations.
+func (s *shrinkReports) changed(key string, eip *networkv1alpha2.Eip) bool {
+	report := fmt.Sprintf("%d/%s/%s", eip.Generation, eip.Annotations[constant.OpenELBEIPAnnotationShrinkPolicy],
+		eip.Annotations[constant.OpenELBEIPAnnotationShrinkTarget])
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.reports == nil {
+		s.reports = map[string]string{}
+	}
+	if s.reports[key] == report {
+		return false
+	}
+	s.reports[key] = report
+	return true
+}
+
+// forget forgets the reports on the object.
+func (s *shrinkReports) forget(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.reports, key)
 }
 
 type svcRecord struct {
@@ -58,6 +94,9 @@ type Request struct {
 
 	// The Release records specifying release
 	Release *svcRecord
+
+	// Reassign is true if the ip is out of the range of the shrunk eip
+	Reassign bool
 }
 
 func NewManager(client client.Client) *Manager {
@@ -97,8 +136,14 @@ func (i *Manager) assignIPFromEip(allocate *svcRecord, eip *networkv1alpha2.Eip)
 		}
 	}
 
-	for ; offset < eip.Status.PoolSize; offset++ {
-		addr := cnet.IncrementIP(*cnet.ParseIP(eip.Status.FirstIP), big.NewInt(int64(offset))).String()
+	// the range is taken from the spec, the status may not be updated yet
+	// after the eip is resized
+	base, size, err := eip.GetSize()
+	if err != nil {
+		return "", err
+	}
+	for ; offset < int(size); offset++ {
+		addr := cnet.IncrementIP(cnet.IP{IP: base}, big.NewInt(int64(offset))).String()
 		tmp, ok := eip.Status.Used[addr]
 		if !ok {
 			if eip.Status.Used == nil {
@@ -106,7 +151,7 @@ func (i *Manager) assignIPFromEip(allocate *svcRecord, eip *networkv1alpha2.Eip)
 			}
 			eip.Status.Used[addr] = allocate.Key
 			eip.Status.Usage = len(eip.Status.Used)
-			if eip.Status.Usage == eip.Status.PoolSize {
+			if InRangeUsage(eip) >= int(size) {
 				eip.Status.Occupied = true
 			}
 			return addr, nil
@@ -121,13 +166,34 @@ func (i *Manager) assignIPFromEip(allocate *svcRecord, eip *networkv1alpha2.Eip)
 	return "", fmt.Errorf("no suitable ip to allocate")
 }
 
+// InRangeUsage returns the number of the used addresses in the range of the
+// eip, the addresses out of the range are still used after it's shrunk.
+func InRangeUsage(eip *networkv1alpha2.Eip) int {
+	usage := 0
+	for addr := range eip.Status.Used {
+		if eip.Contains(net.ParseIP(addr)) {
+			usage++
+		}
+	}
+	return usage
+}
+
 // look up by key in IPAMRequest
 func (i *Manager) releaseIPFromEip(svcInfo string, eip *networkv1alpha2.Eip) {
+	i.releaseAddrFromEip(svcInfo, "", eip)
+}
+
+// releaseAddrFromEip releases the ip of the service, or all of its ips if ip
+// is empty.
+func (i *Manager) releaseAddrFromEip(svcInfo, ip string, eip *networkv1alpha2.Eip) {
 	if !eip.DeletionTimestamp.IsZero() {
 		return
 	}
 
 	for addr, svcs := range eip.Status.Used {
+		if ip != "" && addr != ip {
+			continue
+		}
 		tmp := strings.Split(svcs, ";")
 		for _, svc := range tmp {
 			if svc != svcInfo {
@@ -137,7 +203,7 @@ func (i *Manager) releaseIPFromEip(svcInfo string, eip *networkv1alpha2.Eip) {
 			if len(tmp) == 1 {
 				delete(eip.Status.Used, addr)
 				eip.Status.Usage = len(eip.Status.Used)
-				if eip.Status.Usage != eip.Status.PoolSize {
+				if InRangeUsage(eip) < eip.Status.PoolSize {
 					eip.Status.Occupied = false
 				}
 			} else {
@@ -233,6 +299,14 @@ func (i *Manager) ConstructRequest(ctx context.Context, svc *v1.Service) (req Re
 	}
 
 	info.svcSpecifyEIP = svc.Annotations[constant.OpenELBEIPAnnotationKeyV1Alpha2]
+	shrunk, err := i.getShrunkEIP(ctx, svc, info)
+	if err != nil {
+		return Request{}, err
+	}
+	if shrunk != nil {
+		return i.constructReassign(svc, info, shrunk, req), nil
+	}
+
 	if info.svcSpecifyEIP == "" {
 		eip, err := i.getEIP(context.Background(), svc.Namespace, info.svcSpecifyLBIP, info.svcSpecifyEIP)
 		if err != nil {
@@ -257,6 +331,77 @@ func (i *Manager) ConstructRequest(ctx context.Context, svc *v1.Service) (req Re
 	return req, nil
 }
 
+// getShrunkEIP returns the eip of the service if its ip is out of the range
+// after the eip is shrunk, and the service still asks for the eip and the ip.
+func (i *Manager) getShrunkEIP(ctx context.Context, svc *v1.Service, info info) (*networkv1alpha2.Eip, error) {
+	if info.allocatedEip == "" || info.svcStatusLBIP != info.allocatedIP {
+		return nil, nil
+	}
+
+	if info.svcSpecifyEIP != "" && info.svcSpecifyEIP != info.allocatedEip {
+		return nil, nil
+	}
+
+	if info.svcSpecifyLBIP != "" && info.svcSpecifyLBIP != info.allocatedIP {
+		return nil, nil
+	}
+
+	if _, exist := svc.Labels[constant.OpenELBEIPAnnotationKeyV1Alpha2]; !exist {
+		return nil, nil
+	}
+
+	eip := &networkv1alpha2.Eip{}
+	if err := i.Get(ctx, types.NamespacedName{Name: info.allocatedEip}, eip); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !eip.DeletionTimestamp.IsZero() || eip.Contains(net.ParseIP(info.allocatedIP)) {
+		return nil, nil
+	}
+
+	return eip, nil
+}
+
+// constructReassign constructs the request of the service out of the range
+// of the shrunk eip. The ip is kept unless the shrink policy of the eip is
+// Reassign, then it's released and a new ip is allocated from the eip or the
+// shrink target. The services specifying the ip are always kept.
+func (i *Manager) constructReassign(svc *v1.Service, info info, eip *networkv1alpha2.Eip, req Request) Request {
+	report := i.reported.changed(info.svcName, eip)
+	if eip.Annotations[constant.OpenELBEIPAnnotationShrinkPolicy] != constant.OpenELBEipShrinkPolicyReassign {
+		if report {
+			i.Eventf(svc, v1.EventTypeWarning, EipShrinkReason, "ip %s is out of the range of eip %s, keep it", info.allocatedIP, eip.Name)
+		}
+		return Request{}
+	}
+
+	if info.svcSpecifyLBIP != "" {
+		if report {
+			i.Eventf(svc, v1.EventTypeWarning, EipShrinkReason, "the specified ip %s is out of the range of eip %s, keep it", info.allocatedIP, eip.Name)
+		}
+		return Request{}
+	}
+
+	target := eip.Name
+	if eip.Annotations[constant.OpenELBEIPAnnotationShrinkTarget] != "" {
+		target = eip.Annotations[constant.OpenELBEIPAnnotationShrinkTarget]
+	}
+
+	// the reassignment is retried until it succeeds, then the service is
+	// reported again if its new ip is out of the range
+	i.reported.forget(info.svcName)
+	i.Eventf(svc, v1.EventTypeNormal, EipShrinkReason, "ip %s is out of the range of eip %s, reassign it from eip %s", info.allocatedIP, eip.Name, target)
+	req.Allocate = &svcRecord{
+		Key: info.svcName,
+		Eip: target,
+	}
+	req.Reassign = true
+	return req
+}
+
 func needRelease(svc *v1.Service) bool {
 	if svc == nil || svc.Annotations == nil {
 		return true
@@ -456,6 +601,19 @@ func (i *Manager) AssignIP(ctx context.Context, ipFamilies []v1.IPFamily, alloca
 }
 
 func (i *Manager) ReleaseIP(ctx context.Context, release *svcRecord) error {
+	return i.releaseIP(ctx, release, "")
+}
+
+// ReleaseAddr releases only the ip of the record, the service may hold
+// another ip of the eip, e.g. while it's reassigned.
+func (i *Manager) ReleaseAddr(ctx context.Context, release *svcRecord) error {
+	if release == nil {
+		return nil
+	}
+	return i.releaseIP(ctx, release, release.IP)
+}
+
+func (i *Manager) releaseIP(ctx context.Context, release *svcRecord, addr string) error {
 	if release == nil {
 		return nil
 	}
@@ -467,7 +625,7 @@ func (i *Manager) ReleaseIP(ctx context.Context, release *svcRecord) error {
 	}
 
 	clone := eip.DeepCopy()
-	i.releaseIPFromEip(release.Key, clone)
+	i.releaseAddrFromEip(release.Key, addr, clone)
 	//i.updateMetrics(clone)
 	if !reflect.DeepEqual(clone, eip) {
 		if err := i.Status().Update(ctx, clone); err != nil {
@@ -1398,3 +1398,210 @@ func TestManager_ReleaseIP(t *testing.T) {
 		})
 	}
 }
+
+func TestManager_ConstructReassign(t *testing.T) {
+	shrunk := func(annotations map[string]string) *networkv1alpha2.Eip {
+		return &networkv1alpha2.Eip{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "eip",
+				Annotations: annotations,
+			},
+			Spec: networkv1alpha2.EipSpec{
+				Address: "192.168.1.0-192.168.1.9",
+			},
+			Status: networkv1alpha2.EipStatus{
+				FirstIP:  "192.168.1.0",
+				LastIP:   "192.168.1.255",
+				PoolSize: 256,
+				Used: map[string]string{
+					"192.168.1.1":   "default/insvc",
+					"192.168.1.100": "default/testsvc",
+				},
+			},
+		}
+	}
+	service := func(name, ip string, annotations map[string]string) *v1.Service {
+		svc := &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Annotations: map[string]string{
+					constant.OpenELBAnnotationKey: constant.OpenELBAnnotationValue,
+				},
+				Labels: map[string]string{
+					constant.OpenELBEIPAnnotationKeyV1Alpha2: "eip",
+				},
+			},
+			Spec: v1.ServiceSpec{
+				Type: v1.ServiceTypeLoadBalancer,
+			},
+			Status: v1.ServiceStatus{
+				LoadBalancer: v1.LoadBalancerStatus{
+					Ingress: []v1.LoadBalancerIngress{{IP: ip}},
+				},
+			},
+		}
+		for k, v := range annotations {
+			svc.Annotations[k] = v
+		}
+		return svc
+	}
+	reassign := map[string]string{constant.OpenELBEIPAnnotationShrinkPolicy: constant.OpenELBEipShrinkPolicyReassign}
+	release := &svcRecord{Key: "default/testsvc", Eip: "eip", IP: "192.168.1.100"}
+
+	tests := []struct {
+		name         string
+		eip          *networkv1alpha2.Eip
+		svc          *v1.Service
+		wantAllocate *svcRecord
+		wantRelease  *svcRecord
+	}{
+		{
+			name: "in range service is untouched",
+			eip:  shrunk(reassign),
+			svc: service("insvc", "192.168.1.1", map[string]string{
+				constant.OpenELBEIPAnnotationKeyV1Alpha2: "eip",
+			}),
+		},
+		{
+			name: "keep by default",
+			eip:  shrunk(nil),
+			svc:  service("testsvc", "192.168.1.100", nil),
+		},
+		{
+			name: "reassign from the eip",
+			eip:  shrunk(reassign),
+			svc: service("testsvc", "192.168.1.100", map[string]string{
+				constant.OpenELBEIPAnnotationKeyV1Alpha2: "eip",
+			}),
+			wantAllocate: &svcRecord{Key: "default/testsvc", Eip: "eip"},
+			wantRelease:  release,
+		},
+		{
+			name: "reassign from the target",
+			eip: shrunk(map[string]string{
+				constant.OpenELBEIPAnnotationShrinkPolicy: constant.OpenELBEipShrinkPolicyReassign,
+				constant.OpenELBEIPAnnotationShrinkTarget: "target",
+			}),
+			svc:          service("testsvc", "192.168.1.100", nil),
+			wantAllocate: &svcRecord{Key: "default/testsvc", Eip: "target"},
+			wantRelease:  release,
+		},
+		{
+			name: "keep the specified ip",
+			eip:  shrunk(reassign),
+			svc: service("testsvc", "192.168.1.100", map[string]string{
+				constant.OpenELBEIPAnnotationKey: "192.168.1.100",
+			}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.eip, tt.svc)
+			m := NewManager(cl.Build())
+			m.EventRecorder = &record.FakeRecorder{}
+			request, err := m.ConstructRequest(context.Background(), tt.svc)
+			if err != nil {
+				t.Errorf("Manager.ConstructRequest() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(tt.wantAllocate, request.Allocate) {
+				t.Errorf("Manager.ConstructRequest() wantAllocate = %v, Allocate %v", tt.wantAllocate, request.Allocate)
+			}
+
+			if !reflect.DeepEqual(tt.wantRelease, request.Release) {
+				t.Errorf("Manager.ConstructRequest() wantRelease = %v, Release %v", tt.wantRelease, request.Release)
+			}
+
+			if request.Reassign != (tt.wantAllocate != nil) {
+				t.Errorf("Manager.ConstructRequest() Reassign = %v", request.Reassign)
+			}
+		})
+	}
+}
+
+func TestManager_AssignIPFromShrunkEip(t *testing.T) {
+	// the status isn't updated yet after the eip is shrunk
+	eip := &networkv1alpha2.Eip{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "eip",
+		},
+		Spec: networkv1alpha2.EipSpec{
+			Address: "192.168.1.0-192.168.1.1",
+		},
+		Status: networkv1alpha2.EipStatus{
+			FirstIP:  "192.168.1.0",
+			LastIP:   "192.168.1.255",
+			PoolSize: 256,
+			Used: map[string]string{
+				"192.168.1.0":   "default/svc1",
+				"192.168.1.100": "default/svc2",
+			},
+			Usage: 2,
+		},
+	}
+
+	m := NewManager(fake.NewClientBuilder().WithScheme(scheme).Build())
+	addr, err := m.assignIPFromEip(&svcRecord{Key: "default/svc3", Eip: "eip"}, eip)
+	if err != nil || addr != "192.168.1.1" {
+		t.Errorf("Manager.assignIPFromEip() addr = %s, error = %v", addr, err)
+	}
+	if !eip.Status.Occupied {
+		t.Errorf("Manager.assignIPFromEip() eip.Status %v", eip.Status)
+	}
+
+	if _, err := m.assignIPFromEip(&svcRecord{Key: "default/svc4", Eip: "eip"}, eip); err == nil {
+		t.Errorf("Manager.assignIPFromEip() assigned an ip out of the range")
+	}
+}
+
+func TestManager_ShrinkEventsOnce(t *testing.T) {
+	eip := &networkv1alpha2.Eip{
+		ObjectMeta: metav1.ObjectMeta{Name: "eip", Generation: 1},
+		Spec:       networkv1alpha2.EipSpec{Address: "192.168.1.0-192.168.1.9"},
+		Status: networkv1alpha2.EipStatus{
+			PoolSize: 10,
+			Used:     map[string]string{"192.168.1.100": "default/testsvc"},
+		},
+	}
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "testsvc",
+			Namespace:   "default",
+			Annotations: map[string]string{constant.OpenELBAnnotationKey: constant.OpenELBAnnotationValue},
+			Labels:      map[string]string{constant.OpenELBEIPAnnotationKeyV1Alpha2: "eip"},
+		},
+		Spec: v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer},
+		Status: v1.ServiceStatus{LoadBalancer: v1.LoadBalancerStatus{
+			Ingress: []v1.LoadBalancerIngress{{IP: "192.168.1.100"}},
+		}},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(eip, svc).Build()
+	recorder := record.NewFakeRecorder(10)
+	m := NewManager(cl)
+	m.EventRecorder = recorder
+	c := &EIPController{Client: cl, EventRecorder: recorder}
+
+	// the kept services are reported once per shrink
+	for n := 0; n < 2; n++ {
+		if _, err := m.ConstructRequest(context.Background(), svc); err != nil {
+			t.Errorf("Manager.ConstructRequest() error = %v", err)
+		}
+		c.reportOutOfRange(eip)
+	}
+	if len(recorder.Events) != 2 {
+		t.Errorf("the shrink is reported %d times", len(recorder.Events))
+	}
+
+	eip.Generation = 2
+	if err := cl.Update(context.Background(), eip); err != nil {
+		t.Errorf("update eip error = %v", err)
+	}
+	if _, err := m.ConstructRequest(context.Background(), svc); err != nil {
+		t.Errorf("Manager.ConstructRequest() error = %v", err)
+	}
+	c.reportOutOfRange(eip)
+	if len(recorder.Events) != 4 {
+		t.Errorf("the changed shrink is reported %d times", len(recorder.Events))
+	}
+}
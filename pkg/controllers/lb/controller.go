@@ -96,6 +96,13 @@ func (r *ServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			newEip := e.ObjectNew.(*v1alpha2.Eip)
 			emptyStatus := v1alpha2.EipStatus{}
 
+			// the services out of the range of a shrunk eip are reassigned
+			if oldEip.Spec.Address != newEip.Spec.Address ||
+				oldEip.Annotations[constant.OpenELBEIPAnnotationShrinkPolicy] != newEip.Annotations[constant.OpenELBEIPAnnotationShrinkPolicy] ||
+				oldEip.Annotations[constant.OpenELBEIPAnnotationShrinkTarget] != newEip.Annotations[constant.OpenELBEIPAnnotationShrinkTarget] {
+				return true
+			}
+
 			return reflect.DeepEqual(oldEip.Status, emptyStatus) && !reflect.DeepEqual(newEip.Status, emptyStatus)
 		},
 		CreateFunc: func(e event.CreateEvent) bool {
@@ -154,10 +161,29 @@ func (r *ServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if err != nil {
 		return err
 	}
-	return mgr.GetFieldIndexer().IndexField(context.TODO(), &corev1.Pod{}, "status.phase", func(rawObj client.Object) []string {
-		pod := rawObj.(*corev1.Pod)
-		return []string{string(pod.Status.Phase)}
+
+	// The ingress of OpenELB NodeProxy Services only lists the nodes running a ready proxy pod
+	podp := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isProxyResc(e.ObjectNew) && proxyPodChanged(e.ObjectOld.(*corev1.Pod), e.ObjectNew.(*corev1.Pod))
+		},
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isProxyResc(e.Object)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isProxyResc(e.Object)
+		},
+	}
+	err = ctl.Watch(source.Kind(mgr.GetCache(), &corev1.Pod{}), &EnqueueRequestForDeAndDs{Client: r.Client}, podp)
+	if err != nil {
+		return err
+	}
+
+	// The proxy pods of all OpenELB NodeProxy Services are regenerated when the cluster-level config changes
+	cmp := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetNamespace() == util.EnvNamespace() && obj.GetName() == constant.OpenELBImagesConfigMap
 	})
+	return ctl.Watch(source.Kind(mgr.GetCache(), &corev1.ConfigMap{}), &EnqueueRequestForDeAndDs{Client: r.Client}, cmp)
 }
 
 func (r *ServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -191,6 +217,10 @@ func (r *ServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, nil
 	}
 
+	if request.Reassign {
+		return r.reassignIP(ctx, svc, request)
+	}
+
 	clone := svc.DeepCopy()
 	statusIPs := svc.Status.LoadBalancer.Ingress
 	if request.Release != nil {
@@ -238,10 +268,51 @@ func (r *ServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	return ctrl.Result{}, r.updateReconcileResult(ctx, svc, clone)
 }
 
+// reassignIP moves the service out of the range of the shrunk eip. The new ip
+// is allocated before the old one is released, so the service keeps its ip if
+// the target is full or invalid.
+func (r *ServiceReconciler) reassignIP(ctx context.Context, svc *corev1.Service, request ipam.Request) (ctrl.Result, error) {
+	klog.V(4).Infof("Reassign service loadbalanceip %s", request.Allocate.String())
+	if err := r.ipmanager.AssignIP(ctx, svc.Spec.IPFamilies, request.Allocate); err != nil {
+		klog.Errorf("%s reassign ip form eip[%s] error :%s", request.Allocate.Key, request.Allocate.Eip, err.Error())
+		r.Event(svc, corev1.EventTypeWarning, "AssignIPFailed", err.Error())
+		return ctrl.Result{}, err
+	}
+
+	if err := r.ipmanager.ReleaseAddr(ctx, request.Release); err != nil {
+		klog.Errorf("%s release ip[%s] form eip[%s] error :%s", request.Release.Key, request.Release.IP, request.Release.Eip, err.Error())
+		r.Event(svc, corev1.EventTypeWarning, "ReleaseIPFailed", err.Error())
+		// the new ip is given back so the service holds a single ip
+		if err := r.ipmanager.ReleaseAddr(ctx, request.Allocate); err != nil {
+			klog.Errorf("%s release ip[%s] form eip[%s] error :%s", request.Allocate.Key, request.Allocate.IP, request.Allocate.Eip, err.Error())
+		}
+		return ctrl.Result{}, err
+	}
+	r.Eventf(svc, corev1.EventTypeNormal, "ReleaseIP", "success to release ip: %s", request.Release.IP)
+
+	clone := svc.DeepCopy()
+	if clone.Labels == nil {
+		clone.Labels = make(map[string]string)
+	}
+	clone.Labels[constant.OpenELBEIPAnnotationKeyV1Alpha2] = request.Allocate.Eip
+	// the service is moved to the shrink target, so it isn't allocated from the shrunk eip again
+	if request.Release.Eip != request.Allocate.Eip {
+		if clone.Annotations == nil {
+			clone.Annotations = make(map[string]string)
+		}
+		clone.Annotations[constant.OpenELBEIPAnnotationKeyV1Alpha2] = request.Allocate.Eip
+	}
+	clone.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: request.Allocate.IP}}
+	r.Eventf(svc, corev1.EventTypeNormal, "AssignIP", "success to assign ip: %s", request.Allocate.IP)
+	klog.Infof("reassign ip[%s] from eip[%s] to ip[%s] from eip[%s] for service %s successfully",
+		request.Release.IP, request.Release.Eip, request.Allocate.IP, request.Allocate.Eip, request.Allocate.Key)
+	return ctrl.Result{}, r.updateReconcileResult(ctx, svc, clone)
+}
+
 // updateReconcileResult update service resource and status
 func (r *ServiceReconciler) updateReconcileResult(ctx context.Context, svc, resultSvc *corev1.Service) error {
 	clone := resultSvc.DeepCopy()
-	if !reflect.DeepEqual(svc.Labels, resultSvc.Labels) {
+	if !reflect.DeepEqual(svc.Labels, resultSvc.Labels) || !reflect.DeepEqual(svc.Annotations, resultSvc.Annotations) {
 		if err := r.Update(ctx, clone); err != nil {
 			klog.Errorf("update update labels error:%s", err.Error())
 			return err
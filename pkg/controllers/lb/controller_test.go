@@ -0,0 +1,94 @@
+package lb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/controllers/ipam"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReassignIP(t *testing.T) {
+	shrunk := &v1alpha2.Eip{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "eip",
+			Annotations: map[string]string{
+				constant.OpenELBEIPAnnotationShrinkPolicy: constant.OpenELBEipShrinkPolicyReassign,
+				constant.OpenELBEIPAnnotationShrinkTarget: "target",
+			},
+		},
+		Spec:   v1alpha2.EipSpec{Address: "192.168.1.0-192.168.1.9"},
+		Status: v1alpha2.EipStatus{PoolSize: 10, Used: map[string]string{"192.168.1.100": "default/svc"}, Usage: 1},
+	}
+	// the target is full
+	target := &v1alpha2.Eip{
+		ObjectMeta: metav1.ObjectMeta{Name: "target"},
+		Spec:       v1alpha2.EipSpec{Address: "192.168.2.0"},
+		Status:     v1alpha2.EipStatus{PoolSize: 1, Used: map[string]string{"192.168.2.0": "default/other"}, Usage: 1, Occupied: true},
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "svc",
+			Annotations: map[string]string{constant.OpenELBAnnotationKey: constant.OpenELBAnnotationValue},
+			Labels:      map[string]string{constant.OpenELBEIPAnnotationKeyV1Alpha2: "eip"},
+			Finalizers:  []string{constant.FinalizerName},
+		},
+		Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, IPFamilies: []corev1.IPFamily{corev1.IPv4Protocol}},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+			Ingress: []corev1.LoadBalancerIngress{{IP: "192.168.1.100"}},
+		}},
+	}
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = v1alpha2.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(shrunk, target, svc).
+		WithStatusSubresource(shrunk, target, svc).Build()
+	recorder := record.NewFakeRecorder(100)
+	r := &ServiceReconciler{Client: c, ipmanager: ipam.NewManager(c), EventRecorder: recorder}
+	r.ipmanager.EventRecorder = recorder
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "svc"}}
+
+	// the service keeps its ip if it can't be allocated from the target
+	if _, err := r.Reconcile(ctx, req); err == nil {
+		t.Errorf("Reconcile() expects an error with a full target")
+	}
+	latest := &corev1.Service{}
+	_ = c.Get(ctx, req.NamespacedName, latest)
+	if len(latest.Status.LoadBalancer.Ingress) != 1 || latest.Status.LoadBalancer.Ingress[0].IP != "192.168.1.100" {
+		t.Errorf("the service lost its ip: %v", latest.Status.LoadBalancer.Ingress)
+	}
+	eip := &v1alpha2.Eip{}
+	_ = c.Get(ctx, types.NamespacedName{Name: "eip"}, eip)
+	if eip.Status.Used["192.168.1.100"] != "default/svc" {
+		t.Errorf("the ip of the service is released: %v", eip.Status.Used)
+	}
+
+	// and moves to the target once it has room
+	_ = c.Get(ctx, types.NamespacedName{Name: "target"}, target)
+	target.Spec.Address = "192.168.2.0-192.168.2.1"
+	_ = c.Update(ctx, target)
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Errorf("Reconcile() error = %v", err)
+	}
+	_ = c.Get(ctx, req.NamespacedName, latest)
+	if len(latest.Status.LoadBalancer.Ingress) != 1 || latest.Status.LoadBalancer.Ingress[0].IP != "192.168.2.1" {
+		t.Errorf("the service isn't reassigned: %v", latest.Status.LoadBalancer.Ingress)
+	}
+	if latest.Annotations[constant.OpenELBEIPAnnotationKeyV1Alpha2] != "target" || latest.Labels[constant.OpenELBEIPAnnotationKeyV1Alpha2] != "target" {
+		t.Errorf("the service isn't moved to the target: %v %v", latest.Annotations, latest.Labels)
+	}
+	_ = c.Get(ctx, types.NamespacedName{Name: "eip"}, eip)
+	if len(eip.Status.Used) != 0 {
+		t.Errorf("the old ip isn't released: %v", eip.Status.Used)
+	}
+}
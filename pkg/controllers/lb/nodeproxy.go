@@ -2,7 +2,8 @@ package lb
 
 import (
 	"context"
-	"sort"
+	stderrors "errors"
+	"reflect"
 	"strconv"
 	"strings"
 
@@ -51,49 +52,52 @@ func (r *ServiceReconciler) shouldReconcileDeDs(e metav1.Object) bool {
 	return IsOpenELBNPService(svc)
 }
 
-func (r *ServiceReconciler) newProxyResc(svc *corev1.Service) *client.Object {
+func (r *ServiceReconciler) newProxyResc(svc *corev1.Service, ports []proxyPort, cfg *nodeProxyConfig) *client.Object {
 	var proxyResc client.Object
 	switch svc.Annotations[constant.NodeProxyTypeAnnotationKey] {
 	case constant.NodeProxyTypeDeployment:
-		proxyResc = r.newProxyDe(svc)
+		proxyResc = r.newProxyDe(svc, ports, cfg)
 	case constant.NodeProxyTypeDaemonSet:
-		proxyResc = r.newProxyDs(svc)
+		proxyResc = r.newProxyDs(svc, ports, cfg)
 	}
 	return &proxyResc
 }
 
-func (r *ServiceReconciler) newProxyDe(svc *corev1.Service) *appsv1.Deployment {
+func (r *ServiceReconciler) newProxyDe(svc *corev1.Service, ports []proxyPort, cfg *nodeProxyConfig) *appsv1.Deployment {
+	tmpl := r.newProxyPoTepl(svc, ports, cfg)
 	return &appsv1.Deployment{
-		ObjectMeta: *r.newProxyRescOM(svc),
+		ObjectMeta: *r.newProxyRescOM(svc, tmpl),
 		Spec: appsv1.DeploymentSpec{
 			Selector: r.newProxyRescSel(svc),
-			Template: *r.newProxyPoTepl(svc),
+			Template: *tmpl,
 		},
 	}
 }
 
-func (r *ServiceReconciler) newProxyDs(svc *corev1.Service) *appsv1.DaemonSet {
+func (r *ServiceReconciler) newProxyDs(svc *corev1.Service, ports []proxyPort, cfg *nodeProxyConfig) *appsv1.DaemonSet {
+	tmpl := r.newProxyPoTepl(svc, ports, cfg)
 	return &appsv1.DaemonSet{
-		ObjectMeta: *r.newProxyRescOM(svc),
+		ObjectMeta: *r.newProxyRescOM(svc, tmpl),
 		Spec: appsv1.DaemonSetSpec{
 			Selector: r.newProxyRescSel(svc),
-			Template: *r.newProxyPoTepl(svc),
+			Template: *tmpl,
 		},
 	}
 }
 
-func (r *ServiceReconciler) newProxyRescAnno(svc *corev1.Service) *map[string]string {
+func (r *ServiceReconciler) newProxyRescAnno(svc *corev1.Service, tmpl *corev1.PodTemplateSpec) *map[string]string {
 	return &map[string]string{
-		constant.OpenELBAnnotationKey:       constant.OpenELBAnnotationValue,
-		constant.NodeProxyTypeAnnotationKey: svc.Namespace,
+		constant.OpenELBAnnotationKey:               constant.OpenELBAnnotationValue,
+		constant.NodeProxyTypeAnnotationKey:         svc.Namespace,
+		constant.NodeProxyTemplateHashAnnotationKey: templateHash(tmpl),
 	}
 }
 
-func (r *ServiceReconciler) newProxyRescOM(svc *corev1.Service) *metav1.ObjectMeta {
+func (r *ServiceReconciler) newProxyRescOM(svc *corev1.Service, tmpl *corev1.PodTemplateSpec) *metav1.ObjectMeta {
 	return &metav1.ObjectMeta{
 		Name:        proxyRescName(svc.Name, svc.Namespace),
 		Namespace:   util.EnvNamespace(),
-		Annotations: *r.newProxyRescAnno(svc),
+		Annotations: *r.newProxyRescAnno(svc, tmpl),
 	}
 }
 
@@ -105,14 +109,14 @@ func (r *ServiceReconciler) newProxyRescSel(svc *corev1.Service) *metav1.LabelSe
 	}
 }
 
-func (r *ServiceReconciler) newProxyPoTepl(svc *corev1.Service) *corev1.PodTemplateSpec {
+func (r *ServiceReconciler) newProxyPoTepl(svc *corev1.Service, ports []proxyPort, cfg *nodeProxyConfig) *corev1.PodTemplateSpec {
 	res := &corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels: map[string]string{"name": proxyRescName(svc.Name, svc.Namespace)},
 		},
 		Spec: corev1.PodSpec{
-			Containers:     []corev1.Container{*r.newProxyCtn(svc)},
-			InitContainers: []corev1.Container{*r.newForwardCtn(svc.Name)},
+			Containers:     []corev1.Container{*r.newProxyCtn(svc, ports)},
+			InitContainers: []corev1.Container{*r.newForwardCtn(svc, ports)},
 			Affinity: &corev1.Affinity{
 				NodeAffinity: &corev1.NodeAffinity{
 					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
@@ -144,6 +148,7 @@ func (r *ServiceReconciler) newProxyPoTepl(svc *corev1.Service) *corev1.PodTempl
 			}},
 		},
 	}
+	cfg.apply(res, proxyClusterIPs(svc))
 	return res
 }
 
@@ -186,43 +191,57 @@ func (r *ServiceReconciler) getProxyImage() string {
 	return image
 }
 
-// The only env variable is `PROXY_ARGS`
+// The only env variable is `PROXY_ARGS`, read by the node-proxy agent of the speaker image
 // `PROXY_ARGS` is 4-tuple parameters split by space: <SVC_IP POD_PORT SVC_PORT SVC_PROTO>
-func (r *ServiceReconciler) newProxyCtnEnvArgs(ports *[]corev1.ServicePort, clusterIP string) *[]corev1.EnvVar {
+// A dual-stack service gets the tuples of every cluster ip.
+func (r *ServiceReconciler) newProxyCtnEnvArgs(ports []proxyPort, clusterIPs []string) *[]corev1.EnvVar {
 	var builder strings.Builder
-	for _, port := range *ports {
-		builder.WriteString(clusterIP)
-		builder.WriteString(constant.EnvArgSplitter)
-		builder.WriteString(strconv.Itoa(int(port.Port)))
-		builder.WriteString(constant.EnvArgSplitter)
-		builder.WriteString(strconv.Itoa(int(port.Port)))
-		builder.WriteString(constant.EnvArgSplitter)
-		builder.WriteString(strings.ToLower(string(port.Protocol)))
-		builder.WriteString(constant.EnvArgSplitter)
+	for _, clusterIP := range clusterIPs {
+		for _, port := range ports {
+			builder.WriteString(clusterIP)
+			builder.WriteString(constant.EnvArgSplitter)
+			builder.WriteString(strconv.Itoa(int(port.HostPort)))
+			builder.WriteString(constant.EnvArgSplitter)
+			builder.WriteString(strconv.Itoa(int(port.ServicePort)))
+			builder.WriteString(constant.EnvArgSplitter)
+			builder.WriteString(strings.ToLower(string(port.Protocol)))
+			builder.WriteString(constant.EnvArgSplitter)
+		}
 	}
 	return &[]corev1.EnvVar{{
-		Name:  "PROXY_ARGS",
+		Name:  constant.NodeProxyRulesEnv,
 		Value: builder.String(),
 	}}
 }
 
-func (r *ServiceReconciler) newProxyCtnPorts(ports *[]corev1.ServicePort) *[]corev1.ContainerPort {
-	res := make([]corev1.ContainerPort, len(*ports))
-	for i, port := range *ports {
-		res[i].ContainerPort = port.Port
-		res[i].HostPort = port.Port
-		res[i].Name = port.Name
+// The container ports are not named after the service ports, renaming a
+// service port would otherwise roll the proxy pods.
+func (r *ServiceReconciler) newProxyCtnPorts(ports []proxyPort) *[]corev1.ContainerPort {
+	res := make([]corev1.ContainerPort, len(ports))
+	for i, port := range ports {
+		res[i].ContainerPort = port.HostPort
+		res[i].HostPort = port.HostPort
 		res[i].Protocol = port.Protocol
 	}
 	return &res
 }
 
-func (r *ServiceReconciler) newProxyCtn(svc *corev1.Service) *corev1.Container {
+func (r *ServiceReconciler) newProxyCtn(svc *corev1.Service, ports []proxyPort) *corev1.Container {
 	return &corev1.Container{
-		Name:  proxyRescName(svc.Name, svc.Namespace),
-		Image: r.getProxyImage(),
-		Ports: *r.newProxyCtnPorts(&svc.Spec.Ports),
-		Env:   *r.newProxyCtnEnvArgs(&svc.Spec.Ports, svc.Spec.ClusterIP),
+		Name:    proxyRescName(svc.Name, svc.Namespace),
+		Image:   r.getProxyImage(),
+		Command: []string{constant.NodeProxyCommand, constant.NodeProxySubCommand},
+		Ports:   *r.newProxyCtnPorts(ports),
+		Env:     *r.newProxyCtnEnvArgs(ports, proxyClusterIPs(svc)),
+		// The pod is ready once ip forwarding is enabled and the rules are installed
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				Exec: &corev1.ExecAction{
+					Command: []string{constant.NodeProxyCommand, constant.NodeProxySubCommand, "--check"},
+				},
+			},
+			PeriodSeconds: 10,
+		},
 		// NET_ADMIN capability is required for iptables running in a container
 		SecurityContext: &corev1.SecurityContext{
 			Capabilities: &corev1.Capabilities{
@@ -232,11 +251,15 @@ func (r *ServiceReconciler) newProxyCtn(svc *corev1.Service) *corev1.Container {
 	}
 }
 
-func (r *ServiceReconciler) newForwardCtn(name string) *corev1.Container {
+// The forward container enables ip forwarding in the pod network namespace,
+// which requires a privileged container, before the proxy container starts.
+func (r *ServiceReconciler) newForwardCtn(svc *corev1.Service, ports []proxyPort) *corev1.Container {
 	privileged := true
 	return &corev1.Container{
-		Name:  name,
-		Image: r.getForwardImage(),
+		Name:    svc.Name,
+		Image:   r.getForwardImage(),
+		Command: []string{constant.NodeProxyCommand, constant.NodeProxySubCommand, "--setup-only"},
+		Env:     *r.newProxyCtnEnvArgs(ports, proxyClusterIPs(svc)),
 		SecurityContext: &corev1.SecurityContext{
 			Privileged: &privileged,
 		},
@@ -256,6 +279,24 @@ func (r *ServiceReconciler) reconcileNPNormal(svc *corev1.Service) (ctrl.Result,
 		}
 	}
 
+	ports, err := newProxyPorts(svc)
+	if err != nil {
+		klog.Errorf("invalid node-proxy ports: %v", err)
+		r.Event(svc, corev1.EventTypeWarning, "InvalidNodeProxyPorts", err.Error())
+		return ctrl.Result{}, nil
+	}
+	cfg, err := r.getNodeProxyConfig(svc)
+	if err != nil {
+		invalid := &invalidNodeProxyConfigError{}
+		if !stderrors.As(err, &invalid) {
+			klog.Errorf("can't get node-proxy config: %v", err)
+			return ctrl.Result{}, err
+		}
+		klog.Errorf("invalid node-proxy config: %v", err)
+		r.Event(svc, corev1.EventTypeWarning, "InvalidNodeProxyConfig", err.Error())
+		return ctrl.Result{}, nil
+	}
+
 	// Check if all nodes are labeled for having external-ip
 	// Labeled nodes are prefered for OpenELB to deploy to
 	nodeList := &corev1.NodeList{}
@@ -263,13 +304,9 @@ func (r *ServiceReconciler) reconcileNPNormal(svc *corev1.Service) (ctrl.Result,
 		klog.Errorf("can't get node information: %v", err)
 		return ctrl.Result{}, err
 	}
-	// use map for efficiency
-	nodeWithExternalIP := map[string]*corev1.Node{}
-	nodeWithInternalIP := map[string]*corev1.Node{}
 	for _, node := range nodeList.Items {
 		for _, nodeAddr := range node.Status.Addresses {
 			if nodeAddr.Type == corev1.NodeExternalIP {
-				nodeWithExternalIP[nodeAddr.Address] = &node
 				if _, ok := node.Labels[constant.LabelNodeProxyExternalIPPreffered]; !ok {
 					node.Labels[constant.LabelNodeProxyExternalIPPreffered] = ""
 					if err = r.Update(context.Background(), &node); err != nil {
@@ -278,9 +315,6 @@ func (r *ServiceReconciler) reconcileNPNormal(svc *corev1.Service) (ctrl.Result,
 					}
 				}
 			}
-			if nodeAddr.Type == corev1.NodeInternalIP {
-				nodeWithInternalIP[nodeAddr.Address] = &node
-			}
 		}
 	}
 
@@ -319,72 +353,83 @@ func (r *ServiceReconciler) reconcileNPNormal(svc *corev1.Service) (ctrl.Result,
 	}
 	if err = r.Get(context.TODO(), dpDsNamespacedName, proxyResc); err == nil {
 		// If exists
-		// Update Service pod template by svc
-		proxyResc = *r.newProxyResc(svc)
-		if err = r.Update(context.Background(), proxyResc); err != nil {
-			klog.Errorf("can't patch proxy resc: %v", err)
-			return ctrl.Result{}, err
-		}
-		// External-ip updating procedure
-		podList := &corev1.PodList{}
-		opts := []client.ListOption{
-			client.InNamespace(util.EnvNamespace()),
-			client.MatchingLabels{"name": proxyRescName(svc.Name, svc.Namespace)},
-			client.MatchingFields{"status.phase": "Running"},
-		}
-		if err = r.List(context.TODO(), podList, opts...); err != nil {
-			if !errors.IsNotFound(err) {
-				klog.Errorf("can't list proxy pod: %v", err)
+		// Update Service pod template by svc, only when the template changed
+		// so that unrelated service updates don't roll the proxy pods
+		desired := *r.newProxyResc(svc, ports, cfg)
+		hash := desired.GetAnnotations()[constant.NodeProxyTemplateHashAnnotationKey]
+		if proxyResc.GetAnnotations()[constant.NodeProxyTemplateHashAnnotationKey] != hash {
+			desired.SetResourceVersion(proxyResc.GetResourceVersion())
+			if err = r.Update(context.Background(), desired); err != nil {
+				klog.Errorf("can't patch proxy resc: %v", err)
 				return ctrl.Result{}, err
 			}
 		}
-		if len(podList.Items) == 0 {
-			klog.Info("no proxy pod available")
-			return ctrl.Result{}, err
-		}
-		// Find suitable proxy pods which in nodes with external-ip or internal-ip
-		podInExternalIPNode := []string{}
-		podInInternalIPNode := []string{}
-		for _, pod := range podList.Items {
-			if _, ok := nodeWithExternalIP[pod.Status.HostIP]; ok {
-				podInExternalIPNode = append(podInExternalIPNode, pod.Status.HostIP)
-			}
-			if _, ok := nodeWithInternalIP[pod.Status.HostIP]; ok {
-				podInInternalIPNode = append(podInInternalIPNode, pod.Status.HostIP)
-			}
-		}
-		// If there's some proxy pods in nodes with external-ip, use these nodes' external-ips as svc external-ip
-		// Otherwize svc external-ip was specified by node internal-ips which there's a proxy pod in
-		if len(podInExternalIPNode) != 0 {
-			sort.Strings(podInExternalIPNode) // Use sorting to guarantee update idempotency
-			svc.ObjectMeta.Annotations[constant.NodeProxyExternalIPAnnotationKey] = strings.Join(podInExternalIPNode, constant.IPSeparator)
-		} else {
-			delete(svc.ObjectMeta.Annotations, constant.NodeProxyExternalIPAnnotationKey)
-		}
-		if len(podInInternalIPNode) != 0 {
-			sort.Strings(podInInternalIPNode) // Use sorting to guarantee update idempotency
-			svc.ObjectMeta.Annotations[constant.NodeProxyInternalIPAnnotationKey] = strings.Join(podInInternalIPNode, constant.IPSeparator)
-		} else {
-			delete(svc.ObjectMeta.Annotations, constant.NodeProxyInternalIPAnnotationKey)
-		}
-
-		if err = r.Update(context.Background(), svc); err != nil {
-			klog.Errorf("can't update svc exposed ips annotations: %v", err)
-			return ctrl.Result{}, err
-		}
 	} else {
 		if !errors.IsNotFound(err) {
 			klog.Errorf("can't get proxy resource: %v", err)
 			return ctrl.Result{}, err
 		}
 		// If not exists, create Proxy resource
-		if err = r.Create(context.TODO(), *r.newProxyResc(svc)); err != nil {
+		if err = r.Create(context.TODO(), *r.newProxyResc(svc, ports, cfg)); err != nil {
 			klog.Errorf("can't create proxy resource: %v", err)
 			return ctrl.Result{}, err
 		}
 	}
 
-	return ctrl.Result{}, err
+	// External-ip updating procedure
+	// Only the nodes running a ready proxy pod are exposed
+	podList := &corev1.PodList{}
+	opts := []client.ListOption{
+		client.InNamespace(util.EnvNamespace()),
+		client.MatchingLabels{"name": proxyRescName(svc.Name, svc.Namespace)},
+	}
+	if err = r.List(context.TODO(), podList, opts...); err != nil {
+		klog.Errorf("can't list proxy pod: %v", err)
+		return ctrl.Result{}, err
+	}
+	status := newProxyStatus(nodeList.Items, podList.Items)
+	if len(status.Nodes) == 0 {
+		klog.Info("no proxy pod available")
+	}
+
+	return ctrl.Result{}, r.updateNPStatus(svc, status)
+}
+
+// updateNPStatus exposes the serving nodes by the annotations and the ingress
+// of the service, and explains the dropped nodes by the conditions.
+func (r *ServiceReconciler) updateNPStatus(svc *corev1.Service, status *proxyStatus) error {
+	clone := svc.DeepCopy()
+	if clone.Annotations == nil {
+		clone.Annotations = map[string]string{}
+	}
+	if len(status.ExternalIPs) != 0 {
+		clone.Annotations[constant.NodeProxyExternalIPAnnotationKey] = strings.Join(status.ExternalIPs, constant.IPSeparator)
+	} else {
+		delete(clone.Annotations, constant.NodeProxyExternalIPAnnotationKey)
+	}
+	if len(status.InternalIPs) != 0 {
+		clone.Annotations[constant.NodeProxyInternalIPAnnotationKey] = strings.Join(status.InternalIPs, constant.IPSeparator)
+	} else {
+		delete(clone.Annotations, constant.NodeProxyInternalIPAnnotationKey)
+	}
+	if !reflect.DeepEqual(clone.Annotations, svc.Annotations) {
+		if err := r.Update(context.Background(), clone); err != nil {
+			klog.Errorf("can't update svc exposed ips annotations: %v", err)
+			return err
+		}
+	}
+
+	oldStatus := clone.Status.DeepCopy()
+	clone.Status.LoadBalancer.Ingress = status.Ingress()
+	status.setConditions(clone)
+	if !reflect.DeepEqual(*oldStatus, clone.Status) {
+		if err := r.Status().Update(context.Background(), clone); err != nil {
+			klog.Errorf("can't update svc status: %v", err)
+			r.Event(svc, corev1.EventTypeWarning, "UpdateServiceStatus", err.Error())
+			return err
+		}
+	}
+	return nil
 }
 
 // Called when OpenELB NodeProxy Service was deleted
@@ -0,0 +1,285 @@
+package lb
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+
+	"github.com/openelb/openelb/pkg/constant"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// The ways to enable ip forwarding in the network namespace of the proxy pods.
+// The NET_ADMIN capability of the proxy container isn't allowed by the
+// baseline PodSecurity profile, so the namespace of the proxy pods needs the
+// privileged profile whatever the way.
+const (
+	// ipForwardInitContainer runs the forward init container, which must be a
+	// privileged root container.
+	ipForwardInitContainer = "initContainer"
+	// ipForwardSysctls sets the forwarding sysctls of the pod, which are
+	// unsafe sysctls to be allowed by the kubelet flag --allowed-unsafe-sysctls.
+	ipForwardSysctls = "sysctls"
+	// ipForwardNone leaves it to the CNI, e.g. allow_ip_forwarding of calico.
+	ipForwardNone = "none"
+
+	ipv4ForwardSysctl = "net.ipv4.ip_forward"
+	ipv6ForwardSysctl = "net.ipv6.conf.all.forwarding"
+)
+
+// nodeProxyConfig customizes the generated proxy pods. The cluster-level config
+// is the `node-proxy-config` key of the openelb-images ConfigMap, a Service
+// overrides it by the `node-proxy.openelb.kubesphere.io/config` annotation.
+// Both are YAML or JSON, e.g.
+//
+//	priorityClassName: system-node-critical
+//	resources:
+//	  requests:
+//	    cpu: 10m
+//	    memory: 32Mi
+//	tolerations:
+//	- operator: Exists
+type nodeProxyConfig struct {
+	// Resources of the proxy and forward containers
+	Resources         corev1.ResourceRequirements   `json:"resources,omitempty"`
+	Tolerations       []corev1.Toleration           `json:"tolerations,omitempty"`
+	NodeSelector      map[string]string             `json:"nodeSelector,omitempty"`
+	PriorityClassName string                        `json:"priorityClassName,omitempty"`
+	ImagePullSecrets  []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// PodSecurityContext is the security context of the proxy pods
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+	// SecurityContext is the security context of the proxy container, the
+	// NET_ADMIN capability required by iptables is always added
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+	// IPForward is how ip forwarding is enabled in the proxy pods, one of
+	// initContainer (the default), sysctls and none
+	IPForward string `json:"ipForward,omitempty"`
+}
+
+func parseNodeProxyConfig(data string) (*nodeProxyConfig, error) {
+	cfg := &nodeProxyConfig{}
+	if data == "" {
+		return cfg, nil
+	}
+	if err := yaml.UnmarshalStrict([]byte(data), cfg); err != nil {
+		return nil, fmt.Errorf("invalid node-proxy config: %v", err)
+	}
+	return cfg, nil
+}
+
+func mergeResourceList(dst, src corev1.ResourceList) corev1.ResourceList {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = corev1.ResourceList{}
+	}
+	for name, quantity := range src {
+		dst[name] = quantity.DeepCopy()
+	}
+	return dst
+}
+
+// merge overrides the config by o: the resources and the node selector are
+// merged by key, the tolerations and the pull secrets are added to the ones
+// of the config and the other fields are replaced if set.
+func (c *nodeProxyConfig) merge(o *nodeProxyConfig) {
+	c.Resources.Requests = mergeResourceList(c.Resources.Requests, o.Resources.Requests)
+	c.Resources.Limits = mergeResourceList(c.Resources.Limits, o.Resources.Limits)
+
+	for _, toleration := range o.Tolerations {
+		exist := false
+		for _, t := range c.Tolerations {
+			if reflect.DeepEqual(t, toleration) {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			c.Tolerations = append(c.Tolerations, toleration)
+		}
+	}
+
+	for k, v := range o.NodeSelector {
+		if c.NodeSelector == nil {
+			c.NodeSelector = map[string]string{}
+		}
+		c.NodeSelector[k] = v
+	}
+
+	if o.PriorityClassName != "" {
+		c.PriorityClassName = o.PriorityClassName
+	}
+
+	for _, secret := range o.ImagePullSecrets {
+		exist := false
+		for _, s := range c.ImagePullSecrets {
+			if s.Name == secret.Name {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			c.ImagePullSecrets = append(c.ImagePullSecrets, secret)
+		}
+	}
+
+	if o.PodSecurityContext != nil {
+		c.PodSecurityContext = o.PodSecurityContext.DeepCopy()
+	}
+	if o.SecurityContext != nil {
+		c.SecurityContext = o.SecurityContext.DeepCopy()
+	}
+	if o.IPForward != "" {
+		c.IPForward = o.IPForward
+	}
+}
+
+// validate checks the merged config. The forward init container can't run as
+// the non-root user of the pod, it's rejected rather than run as root anyway.
+func (c *nodeProxyConfig) validate() error {
+	switch c.IPForward {
+	case "", ipForwardInitContainer:
+		sc := c.PodSecurityContext
+		if sc != nil && ((sc.RunAsNonRoot != nil && *sc.RunAsNonRoot) || (sc.RunAsUser != nil && *sc.RunAsUser != 0)) {
+			return fmt.Errorf("the forward init container must run as root, set ipForward to %s or %s with a non-root podSecurityContext",
+				ipForwardSysctls, ipForwardNone)
+		}
+	case ipForwardSysctls, ipForwardNone:
+	default:
+		return fmt.Errorf("unknown ipForward %q", c.IPForward)
+	}
+	return nil
+}
+
+// apply merges the config into the generated pod template, the forwarding
+// sysctls are set for the families of the cluster ips.
+func (c *nodeProxyConfig) apply(tmpl *corev1.PodTemplateSpec, clusterIPs []string) {
+	spec := &tmpl.Spec
+	spec.Tolerations = append(spec.Tolerations, c.Tolerations...)
+	if len(c.NodeSelector) != 0 {
+		spec.NodeSelector = c.NodeSelector
+	}
+	spec.PriorityClassName = c.PriorityClassName
+	spec.ImagePullSecrets = c.ImagePullSecrets
+	spec.SecurityContext = c.PodSecurityContext.DeepCopy()
+
+	switch c.IPForward {
+	case ipForwardSysctls:
+		spec.InitContainers = nil
+		if spec.SecurityContext == nil {
+			spec.SecurityContext = &corev1.PodSecurityContext{}
+		}
+		for _, sysctl := range forwardSysctls(clusterIPs) {
+			exist := false
+			for _, s := range spec.SecurityContext.Sysctls {
+				if s.Name == sysctl {
+					exist = true
+					break
+				}
+			}
+			if !exist {
+				spec.SecurityContext.Sysctls = append(spec.SecurityContext.Sysctls, corev1.Sysctl{Name: sysctl, Value: "1"})
+			}
+		}
+	case ipForwardNone:
+		spec.InitContainers = nil
+	}
+
+	for i := range spec.InitContainers {
+		spec.InitContainers[i].Resources = *c.Resources.DeepCopy()
+	}
+
+	for i := range spec.Containers {
+		ctn := &spec.Containers[i]
+		ctn.Resources = *c.Resources.DeepCopy()
+		if c.SecurityContext == nil {
+			continue
+		}
+
+		sc := c.SecurityContext.DeepCopy()
+		if sc.Capabilities == nil {
+			sc.Capabilities = &corev1.Capabilities{}
+		}
+		if ctn.SecurityContext != nil && ctn.SecurityContext.Capabilities != nil {
+			for _, capability := range ctn.SecurityContext.Capabilities.Add {
+				if !hasCapability(sc.Capabilities.Add, capability) {
+					sc.Capabilities.Add = append(sc.Capabilities.Add, capability)
+				}
+			}
+		}
+		ctn.SecurityContext = sc
+	}
+}
+
+func forwardSysctls(clusterIPs []string) []string {
+	ipv4, ipv6 := false, false
+	for _, clusterIP := range clusterIPs {
+		ip := net.ParseIP(clusterIP)
+		if ip == nil {
+			continue
+		}
+		if ip.To4() != nil {
+			ipv4 = true
+		} else {
+			ipv6 = true
+		}
+	}
+
+	sysctls := []string{}
+	if ipv4 {
+		sysctls = append(sysctls, ipv4ForwardSysctl)
+	}
+	if ipv6 {
+		sysctls = append(sysctls, ipv6ForwardSysctl)
+	}
+	return sysctls
+}
+
+func hasCapability(caps []corev1.Capability, capability corev1.Capability) bool {
+	for _, c := range caps {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidNodeProxyConfigError is an invalid config, which is reported on the
+// service rather than retried.
+type invalidNodeProxyConfigError struct {
+	err error
+}
+
+func (e *invalidNodeProxyConfigError) Error() string {
+	return e.err.Error()
+}
+
+// getNodeProxyConfig returns the cluster-level config overridden by the one
+// of the service. The defaults are only used if the ConfigMap doesn't exist,
+// the other errors are returned so the proxy pods aren't rolled back to them.
+func (r *ServiceReconciler) getNodeProxyConfig(svc *corev1.Service) (*nodeProxyConfig, error) {
+	data := ""
+	cm, err := r.getNPConfig()
+	if err == nil {
+		data = cm.Data[constant.NodeProxyConfigMapConfig]
+	} else if !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("get configmap %s: %w", constant.OpenELBImagesConfigMap, err)
+	}
+	cfg, err := parseNodeProxyConfig(data)
+	if err != nil {
+		return nil, &invalidNodeProxyConfigError{fmt.Errorf("configmap %s: %v", constant.OpenELBImagesConfigMap, err)}
+	}
+
+	override, err := parseNodeProxyConfig(svc.Annotations[constant.NodeProxyConfigAnnotationKey])
+	if err != nil {
+		return nil, &invalidNodeProxyConfigError{fmt.Errorf("annotation %s: %v", constant.NodeProxyConfigAnnotationKey, err)}
+	}
+	cfg.merge(override)
+	if err := cfg.validate(); err != nil {
+		return nil, &invalidNodeProxyConfigError{err}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,171 @@
+package lb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestParseNodeProxyConfig(t *testing.T) {
+	cfg, err := parseNodeProxyConfig("")
+	if err != nil || !reflect.DeepEqual(cfg, &nodeProxyConfig{}) {
+		t.Errorf("parseNodeProxyConfig() = %v, %v, want empty config", cfg, err)
+	}
+
+	for _, data := range []string{
+		"priorityClass: high",
+		"resources: 10m",
+		"{",
+	} {
+		if _, err := parseNodeProxyConfig(data); err == nil {
+			t.Errorf("parseNodeProxyConfig(%q) expects an error", data)
+		}
+	}
+}
+
+func TestNodeProxyConfig(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: constant.OpenELBImagesConfigMap, Namespace: util.EnvNamespace()},
+		Data: map[string]string{constant.NodeProxyConfigMapConfig: `
+priorityClassName: low
+resources:
+  requests:
+    cpu: 10m
+    memory: 32Mi
+tolerations:
+- key: dedicated
+  operator: Exists
+imagePullSecrets:
+- name: registry
+podSecurityContext:
+  runAsNonRoot: true
+  runAsUser: 1000
+securityContext:
+  allowPrivilegeEscalation: false
+  capabilities:
+    drop: ["ALL"]
+ipForward: sysctls
+`},
+	}
+	r := &ServiceReconciler{Client: fake.NewClientBuilder().WithObjects(cm).Build()}
+	svc := newNodeProxyService("", corev1.ServicePort{Name: "http", Port: 80})
+	svc.Annotations[constant.NodeProxyConfigAnnotationKey] = `{"priorityClassName": "high", "resources": {"requests": {"cpu": "50m"}}, "imagePullSecrets": [{"name": "registry"}, {"name": "mirror"}]}`
+
+	cfg, err := r.getNodeProxyConfig(svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PriorityClassName != "high" {
+		t.Errorf("priorityClassName = %s, want high", cfg.PriorityClassName)
+	}
+	if want := (corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("50m"),
+		corev1.ResourceMemory: resource.MustParse("32Mi"),
+	}); !reflect.DeepEqual(cfg.Resources.Requests, want) {
+		t.Errorf("requests = %v, want %v", cfg.Resources.Requests, want)
+	}
+	if want := []corev1.LocalObjectReference{{Name: "registry"}, {Name: "mirror"}}; !reflect.DeepEqual(cfg.ImagePullSecrets, want) {
+		t.Errorf("imagePullSecrets = %v, want %v", cfg.ImagePullSecrets, want)
+	}
+
+	ports, _ := newProxyPorts(svc)
+	tmpl := r.newProxyPoTepl(svc, ports, cfg)
+	if tmpl.Spec.PriorityClassName != "high" || len(tmpl.Spec.ImagePullSecrets) != 2 {
+		t.Errorf("pod spec = %v", tmpl.Spec)
+	}
+	if len(tmpl.Spec.Tolerations) != 2 {
+		t.Errorf("tolerations = %v, want the master toleration and the configured one", tmpl.Spec.Tolerations)
+	}
+	if sc := tmpl.Spec.SecurityContext; sc == nil || !*sc.RunAsNonRoot {
+		t.Errorf("pod security context = %v", tmpl.Spec.SecurityContext)
+	}
+
+	proxy := tmpl.Spec.Containers[0]
+	if !reflect.DeepEqual(proxy.Resources, cfg.Resources) {
+		t.Errorf("proxy resources = %v, want %v", proxy.Resources, cfg.Resources)
+	}
+	if !hasCapability(proxy.SecurityContext.Capabilities.Add, "NET_ADMIN") {
+		t.Errorf("proxy container lost the NET_ADMIN capability: %v", proxy.SecurityContext)
+	}
+	if *proxy.SecurityContext.AllowPrivilegeEscalation {
+		t.Errorf("proxy security context = %v", proxy.SecurityContext)
+	}
+
+	// ip forwarding is enabled by the sysctls instead of the forward container
+	if len(tmpl.Spec.InitContainers) != 0 {
+		t.Errorf("init containers = %v, want none", tmpl.Spec.InitContainers)
+	}
+	if want := []corev1.Sysctl{{Name: ipv4ForwardSysctl, Value: "1"}, {Name: ipv6ForwardSysctl, Value: "1"}}; !reflect.DeepEqual(tmpl.Spec.SecurityContext.Sysctls, want) {
+		t.Errorf("sysctls = %v, want %v", tmpl.Spec.SecurityContext.Sysctls, want)
+	}
+	if cfg.PodSecurityContext.Sysctls != nil {
+		t.Errorf("the config is modified: %v", cfg.PodSecurityContext)
+	}
+
+	// the forward container can't run as the non-root user of the pod
+	svc.Annotations[constant.NodeProxyConfigAnnotationKey] = `{"ipForward": "initContainer"}`
+	if _, err := r.getNodeProxyConfig(svc); err == nil {
+		t.Errorf("getNodeProxyConfig() expects an error")
+	}
+	svc.Annotations[constant.NodeProxyConfigAnnotationKey] = `{"ipForward": "initContainer", "podSecurityContext": {"seccompProfile": {"type": "RuntimeDefault"}}}`
+	cfg, err = r.getNodeProxyConfig(svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl = r.newProxyPoTepl(svc, ports, cfg)
+	if forward := tmpl.Spec.InitContainers[0]; !*forward.SecurityContext.Privileged || forward.SecurityContext.RunAsUser != nil {
+		t.Errorf("forward container security context = %v", forward.SecurityContext)
+	}
+
+	svc.Annotations[constant.NodeProxyConfigAnnotationKey] = `{"ipForward": "none"}`
+	cfg, err = r.getNodeProxyConfig(svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl = r.newProxyPoTepl(svc, ports, cfg)
+	if len(tmpl.Spec.InitContainers) != 0 || len(tmpl.Spec.SecurityContext.Sysctls) != 0 {
+		t.Errorf("pod spec = %v, want neither the forward container nor the sysctls", tmpl.Spec)
+	}
+
+	svc.Annotations[constant.NodeProxyConfigAnnotationKey] = `{"ipForward": "host"}`
+	if _, err := r.getNodeProxyConfig(svc); err == nil {
+		t.Errorf("getNodeProxyConfig() expects an error")
+	}
+
+	// an invalid override is reported
+	svc.Annotations[constant.NodeProxyConfigAnnotationKey] = "priority: high"
+	if _, err := r.getNodeProxyConfig(svc); err == nil {
+		t.Errorf("getNodeProxyConfig() expects an error")
+	}
+}
+
+func TestNodeProxyConfigMapError(t *testing.T) {
+	svc := newNodeProxyService("", corev1.ServicePort{Name: "http", Port: 80})
+
+	// the defaults are used without the ConfigMap
+	r := &ServiceReconciler{Client: fake.NewClientBuilder().Build()}
+	cfg, err := r.getNodeProxyConfig(svc)
+	if err != nil || !reflect.DeepEqual(cfg, &nodeProxyConfig{}) {
+		t.Errorf("getNodeProxyConfig() = %v, %v, want empty config", cfg, err)
+	}
+
+	// but not if the ConfigMap can't be read
+	r.Client = fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, client client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			return fmt.Errorf("connection refused")
+		},
+	}).Build()
+	if _, err := r.getNodeProxyConfig(svc); err == nil {
+		t.Errorf("getNodeProxyConfig() expects an error")
+	}
+}
@@ -0,0 +1,164 @@
+package lb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openelb/openelb/pkg/constant"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// proxyPort is how a node-proxy pod exposes one service port: traffic received
+// on HostPort is forwarded to ServicePort of the ClusterIP, kube-proxy then
+// forwards it to the targetPort of the endpoints.
+type proxyPort struct {
+	Protocol    corev1.Protocol
+	HostPort    int32
+	ServicePort int32
+}
+
+// portMapping is parsed from the port-mapping annotation, a comma separated
+// list of `<service port>:<host port>`. The service port is a port name, a
+// port number or a range of port numbers mapped to a host port range of the
+// same size, e.g. `http:8080,5000-5010:15000-15010`.
+type portMapping struct {
+	byName map[string]int32
+	byPort map[int32]int32
+}
+
+func parsePortRange(s string) (int32, int32, error) {
+	first, last, isRange := strings.Cut(s, constant.EipRangeSeparator)
+	start, err := strconv.ParseUint(first, 10, 16)
+	if err != nil || start == 0 {
+		return 0, 0, fmt.Errorf("invalid port %q", first)
+	}
+	if !isRange {
+		return int32(start), int32(start), nil
+	}
+
+	end, err := strconv.ParseUint(last, 10, 16)
+	if err != nil || end < start {
+		return 0, 0, fmt.Errorf("invalid port range %q", s)
+	}
+	return int32(start), int32(end), nil
+}
+
+func parsePortMapping(value string) (*portMapping, error) {
+	m := &portMapping{byName: map[string]int32{}, byPort: map[int32]int32{}}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		svcPort, hostPort, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid port mapping %q, expect <service port>:<host port>", entry)
+		}
+		hostStart, hostEnd, err := parsePortRange(hostPort)
+		if err != nil {
+			return nil, err
+		}
+
+		if svcPort == "" {
+			return nil, fmt.Errorf("invalid port mapping %q, missing the service port", entry)
+		}
+		if svcPort[0] < '0' || svcPort[0] > '9' {
+			// a port name
+			if hostStart != hostEnd {
+				return nil, fmt.Errorf("invalid port mapping %q, a port name maps to a single host port", entry)
+			}
+			m.byName[svcPort] = hostStart
+			continue
+		}
+
+		svcStart, svcEnd, err := parsePortRange(svcPort)
+		if err != nil {
+			return nil, err
+		}
+		if svcEnd-svcStart != hostEnd-hostStart {
+			return nil, fmt.Errorf("invalid port mapping %q, the ranges have different sizes", entry)
+		}
+		for offset := int32(0); svcStart+offset <= svcEnd; offset++ {
+			m.byPort[svcStart+offset] = hostStart + offset
+		}
+	}
+	return m, nil
+}
+
+// hostPort returns the host port of a service port, the service port itself if
+// it is not mapped.
+func (m *portMapping) hostPort(port corev1.ServicePort) int32 {
+	if port.Name != "" {
+		if hostPort, ok := m.byName[port.Name]; ok {
+			return hostPort
+		}
+	}
+	if hostPort, ok := m.byPort[port.Port]; ok {
+		return hostPort
+	}
+	return port.Port
+}
+
+// newProxyPorts returns the ports of the node-proxy, sorted so that renaming or
+// reordering the service ports does not change the generated workload.
+func newProxyPorts(svc *corev1.Service) ([]proxyPort, error) {
+	mapping, err := parsePortMapping(svc.Annotations[constant.NodeProxyPortMappingAnnotationKey])
+	if err != nil {
+		return nil, err
+	}
+
+	ports := make([]proxyPort, 0, len(svc.Spec.Ports))
+	exposed := map[string]string{}
+	for _, port := range svc.Spec.Ports {
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
+
+		p := proxyPort{Protocol: protocol, HostPort: mapping.hostPort(port), ServicePort: port.Port}
+		key := fmt.Sprintf("%d/%s", p.HostPort, p.Protocol)
+		if other, exist := exposed[key]; exist {
+			return nil, fmt.Errorf("service ports %s and %d are both exposed on host port %s", other, port.Port, key)
+		}
+		exposed[key] = strconv.Itoa(int(port.Port))
+		ports = append(ports, p)
+	}
+
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].HostPort != ports[j].HostPort {
+			return ports[i].HostPort < ports[j].HostPort
+		}
+		return ports[i].Protocol < ports[j].Protocol
+	})
+	return ports, nil
+}
+
+// proxyClusterIPs returns every cluster ip of a dual-stack service.
+func proxyClusterIPs(svc *corev1.Service) []string {
+	ips := svc.Spec.ClusterIPs
+	if len(ips) == 0 {
+		ips = []string{svc.Spec.ClusterIP}
+	}
+
+	res := []string{}
+	for _, ip := range ips {
+		if ip != "" && ip != corev1.ClusterIPNone {
+			res = append(res, ip)
+		}
+	}
+	return res
+}
+
+// templateHash identifies the generated pod template, the workload is only
+// updated, and its pods rolled, when the hash changes.
+func templateHash(tmpl *corev1.PodTemplateSpec) string {
+	data, _ := json.Marshal(tmpl)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
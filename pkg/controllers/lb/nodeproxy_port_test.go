@@ -0,0 +1,127 @@
+package lb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openelb/openelb/pkg/constant"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newNodeProxyService(mapping string, ports ...corev1.ServicePort) *corev1.Service {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Annotations: map[string]string{}},
+		Spec: corev1.ServiceSpec{
+			ClusterIP:  "10.96.0.10",
+			ClusterIPs: []string{"10.96.0.10", "fd00::10"},
+			Ports:      ports,
+		},
+	}
+	if mapping != "" {
+		svc.Annotations[constant.NodeProxyPortMappingAnnotationKey] = mapping
+	}
+	return svc
+}
+
+func TestNewProxyPorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		svc     *corev1.Service
+		want    []proxyPort
+		wantErr bool
+	}{
+		{
+			name: "default protocol and same port",
+			svc:  newNodeProxyService("", corev1.ServicePort{Name: "http", Port: 80}),
+			want: []proxyPort{{Protocol: corev1.ProtocolTCP, HostPort: 80, ServicePort: 80}},
+		},
+		{
+			name: "mapped by name, number and range",
+			svc: newNodeProxyService("http:8080, 53:5353, 5000-5001:15000-15001",
+				corev1.ServicePort{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP},
+				corev1.ServicePort{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP},
+				corev1.ServicePort{Name: "a", Port: 5001, Protocol: corev1.ProtocolSCTP},
+				corev1.ServicePort{Name: "b", Port: 5000, Protocol: corev1.ProtocolTCP},
+			),
+			want: []proxyPort{
+				{Protocol: corev1.ProtocolUDP, HostPort: 5353, ServicePort: 53},
+				{Protocol: corev1.ProtocolTCP, HostPort: 8080, ServicePort: 80},
+				{Protocol: corev1.ProtocolTCP, HostPort: 15000, ServicePort: 5000},
+				{Protocol: corev1.ProtocolSCTP, HostPort: 15001, ServicePort: 5001},
+			},
+		},
+		{
+			name: "same port with different protocols",
+			svc: newNodeProxyService("",
+				corev1.ServicePort{Name: "dns-udp", Port: 53, Protocol: corev1.ProtocolUDP},
+				corev1.ServicePort{Name: "dns-tcp", Port: 53, Protocol: corev1.ProtocolTCP},
+			),
+			want: []proxyPort{
+				{Protocol: corev1.ProtocolTCP, HostPort: 53, ServicePort: 53},
+				{Protocol: corev1.ProtocolUDP, HostPort: 53, ServicePort: 53},
+			},
+		},
+		{
+			name: "conflicting host ports",
+			svc: newNodeProxyService("http:443",
+				corev1.ServicePort{Name: "http", Port: 80},
+				corev1.ServicePort{Name: "https", Port: 443},
+			),
+			wantErr: true,
+		},
+		{
+			name:    "ranges with different sizes",
+			svc:     newNodeProxyService("5000-5010:15000-15001", corev1.ServicePort{Port: 5000}),
+			wantErr: true,
+		},
+		{
+			name:    "named port mapped to a range",
+			svc:     newNodeProxyService("http:8080-8081", corev1.ServicePort{Name: "http", Port: 80}),
+			wantErr: true,
+		},
+		{
+			name:    "missing host port",
+			svc:     newNodeProxyService("http", corev1.ServicePort{Name: "http", Port: 80}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newProxyPorts(tt.svc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newProxyPorts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newProxyPorts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyTemplateHash(t *testing.T) {
+	r := &ServiceReconciler{Client: fake.NewClientBuilder().Build()}
+	port := corev1.ServicePort{Name: "http", Port: 80}
+	svc := newNodeProxyService("", port)
+	ports, err := newProxyPorts(svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := templateHash(r.newProxyPoTepl(svc, ports, &nodeProxyConfig{}))
+
+	// renaming the service port doesn't change the template
+	port.Name = "web"
+	renamed := newNodeProxyService("", port)
+	ports, _ = newProxyPorts(renamed)
+	if got := templateHash(r.newProxyPoTepl(renamed, ports, &nodeProxyConfig{})); got != hash {
+		t.Errorf("template changed after renaming the port")
+	}
+
+	// mapping the port does
+	mapped := newNodeProxyService("web:8080", port)
+	ports, _ = newProxyPorts(mapped)
+	if got := templateHash(r.newProxyPoTepl(mapped, ports, &nodeProxyConfig{})); got == hash {
+		t.Errorf("template didn't change after mapping the port")
+	}
+}
@@ -0,0 +1,173 @@
+package lb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// proxyStatus is computed from the proxy pods, a node serves the service only
+// if it runs a proxy pod passing the readiness probe.
+type proxyStatus struct {
+	Nodes       []string
+	ExternalIPs []string
+	InternalIPs []string
+	// Dropped are the nodes running a proxy pod that doesn't serve the
+	// service, with the reason
+	Dropped map[string]string
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, con := range pod.Status.Conditions {
+		if con.Type == corev1.PodReady {
+			return con.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// proxyPodNotReadyReason returns why a proxy pod doesn't serve the service, an
+// empty string if it does.
+func proxyPodNotReadyReason(pod *corev1.Pod) string {
+	switch {
+	case pod.DeletionTimestamp != nil:
+		return fmt.Sprintf("proxy pod %s is terminating", pod.Name)
+	case pod.Status.Phase != corev1.PodRunning:
+		return fmt.Sprintf("proxy pod %s is %s", pod.Name, pod.Status.Phase)
+	case !podReady(pod):
+		return fmt.Sprintf("proxy pod %s is not ready", pod.Name)
+	}
+	return ""
+}
+
+func newProxyStatus(nodes []corev1.Node, pods []corev1.Pod) *proxyStatus {
+	nodeByName := map[string]*corev1.Node{}
+	for i := range nodes {
+		nodeByName[nodes[i].Name] = &nodes[i]
+	}
+
+	status := &proxyStatus{Dropped: map[string]string{}}
+	serving := map[string]*corev1.Node{}
+	for i := range pods {
+		pod := &pods[i]
+		// pods not scheduled yet are not bound to any node
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		if _, ok := serving[pod.Spec.NodeName]; ok {
+			continue
+		}
+
+		node, ok := nodeByName[pod.Spec.NodeName]
+		var reason string
+		switch {
+		case !ok:
+			reason = fmt.Sprintf("node of proxy pod %s is not found", pod.Name)
+		case !util.NodeReady(node):
+			reason = "node is not ready"
+		default:
+			reason = proxyPodNotReadyReason(pod)
+		}
+		if reason != "" {
+			status.Dropped[pod.Spec.NodeName] = reason
+			continue
+		}
+
+		externalIP, internalIP := nodeInternalAndExternalIP(node)
+		if externalIP == "" && internalIP == "" {
+			status.Dropped[node.Name] = "node has neither an external nor an internal ip"
+			continue
+		}
+		// another pod of the node may be terminating during a rollout
+		delete(status.Dropped, node.Name)
+		serving[node.Name] = node
+		status.Nodes = append(status.Nodes, node.Name)
+		if externalIP != "" {
+			status.ExternalIPs = append(status.ExternalIPs, externalIP)
+		}
+		if internalIP != "" {
+			status.InternalIPs = append(status.InternalIPs, internalIP)
+		}
+	}
+
+	// Use sorting to guarantee update idempotency
+	sort.Strings(status.Nodes)
+	sort.Strings(status.ExternalIPs)
+	sort.Strings(status.InternalIPs)
+	return status
+}
+
+// Ingress prefers the external ips, the internal ips are used only when no
+// serving node has an external ip.
+func (s *proxyStatus) Ingress() []corev1.LoadBalancerIngress {
+	ips := s.ExternalIPs
+	if len(ips) == 0 {
+		ips = s.InternalIPs
+	}
+
+	res := []corev1.LoadBalancerIngress{}
+	for _, ip := range ips {
+		res = append(res, corev1.LoadBalancerIngress{IP: ip})
+	}
+	return res
+}
+
+func (s *proxyStatus) droppedMessage() string {
+	nodes := make([]string, 0, len(s.Dropped))
+	for node := range s.Dropped {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	msgs := make([]string, len(nodes))
+	for i, node := range nodes {
+		msgs[i] = node + ": " + s.Dropped[node]
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// setConditions explains on the service which nodes are serving it and why
+// the others were dropped.
+func (s *proxyStatus) setConditions(svc *corev1.Service) {
+	ready := metav1.Condition{
+		Type:               constant.NodeProxyConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: svc.Generation,
+		Reason:             constant.NodeProxyReasonProxyPodsReady,
+		Message:            "nodes serving the service: " + strings.Join(s.Nodes, ", "),
+	}
+	if len(s.Nodes) == 0 {
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = constant.NodeProxyReasonNoReadyProxyPod
+		ready.Message = "no node runs a ready proxy pod"
+	}
+	meta.SetStatusCondition(&svc.Status.Conditions, ready)
+
+	degraded := metav1.Condition{
+		Type:               constant.NodeProxyConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: svc.Generation,
+		Reason:             constant.NodeProxyReasonAllNodesAvailable,
+		Message:            "every node running a proxy pod is serving the service",
+	}
+	if len(s.Dropped) != 0 {
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = constant.NodeProxyReasonNodesDropped
+		degraded.Message = s.droppedMessage()
+	}
+	meta.SetStatusCondition(&svc.Status.Conditions, degraded)
+}
+
+// proxyPodChanged filters the pod updates that can change the serving nodes.
+func proxyPodChanged(oldPod, newPod *corev1.Pod) bool {
+	return oldPod.Spec.NodeName != newPod.Spec.NodeName ||
+		oldPod.Status.Phase != newPod.Status.Phase ||
+		podReady(oldPod) != podReady(newPod) ||
+		(oldPod.DeletionTimestamp == nil) != (newPod.DeletionTimestamp == nil)
+}
@@ -0,0 +1,116 @@
+package lb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openelb/openelb/pkg/constant"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newProxyNode(name, externalIP, internalIP string, ready bool) corev1.Node {
+	node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if externalIP != "" {
+		node.Status.Addresses = append(node.Status.Addresses, corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: externalIP})
+	}
+	if internalIP != "" {
+		node.Status.Addresses = append(node.Status.Addresses, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: internalIP})
+	}
+	status := corev1.ConditionTrue
+	if !ready {
+		status = corev1.ConditionFalse
+	}
+	node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}}
+	return node
+}
+
+func newProxyPod(name, node string, phase corev1.PodPhase, ready bool) corev1.Pod {
+	status := corev1.ConditionTrue
+	if !ready {
+		status = corev1.ConditionFalse
+	}
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{
+			Phase:      phase,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func TestNewProxyStatus(t *testing.T) {
+	nodes := []corev1.Node{
+		newProxyNode("node1", "", "192.168.0.1", true),
+		newProxyNode("node2", "", "192.168.0.2", true),
+		newProxyNode("node3", "", "192.168.0.3", false),
+		newProxyNode("node4", "", "192.168.0.4", true),
+		newProxyNode("node5", "", "", true),
+		newProxyNode("node6", "", "192.168.0.6", true),
+	}
+	terminating := newProxyPod("pod6-old", "node6", corev1.PodRunning, true)
+	terminating.DeletionTimestamp = &metav1.Time{}
+	pods := []corev1.Pod{
+		newProxyPod("pod1", "node1", corev1.PodRunning, true),
+		newProxyPod("pod2", "node2", corev1.PodRunning, false),
+		newProxyPod("pod3", "node3", corev1.PodRunning, true),
+		newProxyPod("pod4", "node4", corev1.PodPending, false),
+		newProxyPod("pod5", "node5", corev1.PodRunning, true),
+		terminating,
+		newProxyPod("pod6", "node6", corev1.PodRunning, true),
+		newProxyPod("unscheduled", "", corev1.PodPending, false),
+	}
+
+	status := newProxyStatus(nodes, pods)
+	if want := []string{"node1", "node6"}; !reflect.DeepEqual(status.Nodes, want) {
+		t.Errorf("serving nodes = %v, want %v", status.Nodes, want)
+	}
+	if want := []string{"192.168.0.1", "192.168.0.6"}; !reflect.DeepEqual(status.InternalIPs, want) {
+		t.Errorf("internal ips = %v, want %v", status.InternalIPs, want)
+	}
+	if want := []corev1.LoadBalancerIngress{{IP: "192.168.0.1"}, {IP: "192.168.0.6"}}; !reflect.DeepEqual(status.Ingress(), want) {
+		t.Errorf("ingress = %v, want %v", status.Ingress(), want)
+	}
+	want := map[string]string{
+		"node2": "proxy pod pod2 is not ready",
+		"node3": "node is not ready",
+		"node4": "proxy pod pod4 is Pending",
+		"node5": "node has neither an external nor an internal ip",
+	}
+	if !reflect.DeepEqual(status.Dropped, want) {
+		t.Errorf("dropped = %v, want %v", status.Dropped, want)
+	}
+
+	svc := &corev1.Service{}
+	status.setConditions(svc)
+	ready := meta.FindStatusCondition(svc.Status.Conditions, constant.NodeProxyConditionReady)
+	if ready == nil || ready.Status != metav1.ConditionTrue {
+		t.Errorf("ready condition = %v", ready)
+	}
+	degraded := meta.FindStatusCondition(svc.Status.Conditions, constant.NodeProxyConditionDegraded)
+	if degraded == nil || degraded.Status != metav1.ConditionTrue || degraded.Reason != constant.NodeProxyReasonNodesDropped {
+		t.Errorf("degraded condition = %v", degraded)
+	}
+	if want := "node2: proxy pod pod2 is not ready; node3: node is not ready; node4: proxy pod pod4 is Pending; node5: node has neither an external nor an internal ip"; degraded.Message != want {
+		t.Errorf("degraded message = %q, want %q", degraded.Message, want)
+	}
+
+	// external ips are preferred
+	nodes[0] = newProxyNode("node1", "1.1.1.1", "192.168.0.1", true)
+	if want := []corev1.LoadBalancerIngress{{IP: "1.1.1.1"}}; !reflect.DeepEqual(newProxyStatus(nodes, pods).Ingress(), want) {
+		t.Errorf("ingress = %v, want %v", newProxyStatus(nodes, pods).Ingress(), want)
+	}
+
+	// no ready proxy pod at all
+	status = newProxyStatus(nodes, nil)
+	status.setConditions(svc)
+	ready = meta.FindStatusCondition(svc.Status.Conditions, constant.NodeProxyConditionReady)
+	if ready.Status != metav1.ConditionFalse || ready.Reason != constant.NodeProxyReasonNoReadyProxyPod {
+		t.Errorf("ready condition = %v", ready)
+	}
+	if len(status.Ingress()) != 0 {
+		t.Errorf("ingress = %v, want empty", status.Ingress())
+	}
+}
@@ -57,6 +57,27 @@ var (
 		[]string{
 			"ip",
 		})
+	conflictsDetected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "conflicts_detected",
+			Help: "The number of arp/ndp packets claiming an announced ip from a foreign mac.",
+		},
+		[]string{
+			"ip",
+			"mac",
+		})
+
+	// VRRP
+	vrrpState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vrrp_state",
+			Help: "The state of VRRP instances, 0 for init, 1 for backup and 2 for master.",
+		},
+		[]string{
+			"instance",
+			"vrid",
+			"family",
+		})
 
 	// BGP
 	sessionUp = prometheus.NewGaugeVec(
@@ -107,6 +128,10 @@ func init() {
 	metrics.Registry.MustRegister(requestsReceived)
 	metrics.Registry.MustRegister(responsesSent)
 	metrics.Registry.MustRegister(gratuitousSent)
+	metrics.Registry.MustRegister(conflictsDetected)
+
+	// VRRP
+	metrics.Registry.MustRegister(vrrpState)
 
 	// BGP
 	metrics.Registry.MustRegister(sessionUp)
@@ -144,10 +169,23 @@ func UpdateRequestsReceivedMetrics(ip string) {
 	requestsReceived.WithLabelValues(ip).Inc()
 }
 
+func UpdateConflictsDetectedMetrics(ip, mac string) {
+	conflictsDetected.WithLabelValues(ip, mac).Inc()
+}
+
 func DeleteLayer2Metrics(ip string) {
 	gratuitousSent.DeleteLabelValues(ip)
 	responsesSent.DeleteLabelValues(ip)
 	requestsReceived.DeleteLabelValues(ip)
+	conflictsDetected.DeletePartialMatch(prometheus.Labels{"ip": ip})
+}
+
+func UpdateVrrpStateMetrics(instance, vrid, family string, state float64) {
+	vrrpState.WithLabelValues(instance, vrid, family).Set(state)
+}
+
+func DeleteVrrpStateMetrics(instance, vrid, family string) {
+	vrrpState.DeleteLabelValues(instance, vrid, family)
 }
 
 func InitBGPPeerMetrics(peerIP, node string) {
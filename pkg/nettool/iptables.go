@@ -2,6 +2,9 @@ package nettool
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/openelb/openelb/pkg/nettool/iptables"
 )
 
@@ -34,3 +37,139 @@ func DeletePortForwardOfBGP(iptableExec iptables.IptablesIface, routerIP, localI
 }
 
 const BgpNatChain = "PREROUTING-OPENELB"
+
+// EnsureChainOfBGP creates the BgpNatChain and inserts its jump at the top of PREROUTING,
+// so the bgp connections are forwarded before the rules of the cni.
+func EnsureChainOfBGP(iptableExec iptables.IptablesIface) error {
+	return EnsureJumpChain(iptableExec, "nat", "PREROUTING", BgpNatChain)
+}
+
+// DeleteChainOfBGP deletes the jump to the BgpNatChain and the chain with its rules.
+func DeleteChainOfBGP(iptableExec iptables.IptablesIface) error {
+	return DeleteJumpChain(iptableExec, "nat", "PREROUTING", BgpNatChain)
+}
+
+func hasChain(iptableExec iptables.IptablesIface, table, chain string) (bool, error) {
+	chains, err := iptableExec.ListChains(table)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range chains {
+		if c == chain {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// EnsureJumpChain creates the chain and inserts its jump at the top of the builtin chain.
+func EnsureJumpChain(iptableExec iptables.IptablesIface, table, builtin, chain string) error {
+	found, err := hasChain(iptableExec, table, chain)
+	if err != nil {
+		return err
+	}
+	if !found {
+		if err := iptableExec.NewChain(table, chain); err != nil {
+			return err
+		}
+	}
+
+	ok, err := iptableExec.Exists(table, builtin, "-j", chain)
+	if err != nil || ok {
+		return err
+	}
+	return iptableExec.Insert(table, builtin, 1, "-j", chain)
+}
+
+// DeleteJumpChain deletes the jump from the builtin chain and the chain with its rules.
+func DeleteJumpChain(iptableExec iptables.IptablesIface, table, builtin, chain string) error {
+	ok, err := iptableExec.Exists(table, builtin, "-j", chain)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if err := iptableExec.Delete(table, builtin, "-j", chain); err != nil {
+			return err
+		}
+	}
+
+	found, err := hasChain(iptableExec, table, chain)
+	if err != nil || !found {
+		return err
+	}
+	if err := iptableExec.ClearChain(table, chain); err != nil {
+		return err
+	}
+	return iptableExec.DeleteChain(table, chain)
+}
+
+// jumps returns true if the builtin chain jumps to the chain.
+func jumps(iptableExec iptables.IptablesIface, table, builtin, chain string) (bool, error) {
+	found, err := hasChain(iptableExec, table, chain)
+	if err != nil || !found {
+		return false, err
+	}
+	return iptableExec.Exists(table, builtin, "-j", chain)
+}
+
+// SwapJumpChain replaces the rules of the chains by filling the one the builtin chain doesn't jump to
+// and moving the jump to it, so the traffic never goes through a partially filled chain. The jump to
+// the new chain is inserted before the old one is deleted, the traffic goes through both in between.
+// It returns the chain jumped to.
+func SwapJumpChain(iptableExec iptables.IptablesIface, table, builtin string, chains [2]string, rules [][]string) (string, error) {
+	current, next := chains[1], chains[0]
+	ok, err := jumps(iptableExec, table, builtin, next)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		current, next = next, current
+	}
+
+	found, err := hasChain(iptableExec, table, next)
+	if err != nil {
+		return "", err
+	}
+	if found {
+		err = iptableExec.ClearChain(table, next)
+	} else {
+		err = iptableExec.NewChain(table, next)
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, rule := range rules {
+		if err := iptableExec.Append(table, next, rule...); err != nil {
+			return "", err
+		}
+	}
+
+	// the jump is left by a previous swap interrupted in between
+	ok, err = iptableExec.Exists(table, builtin, "-j", next)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		if err := iptableExec.Insert(table, builtin, 1, "-j", next); err != nil {
+			return "", err
+		}
+	}
+	return next, DeleteJumpChain(iptableExec, table, builtin, current)
+}
+
+const SourceRangesChain = "OPENELB-SOURCE-RANGES"
+
+// SourceRangesChains are the chains the source ranges rules are swapped between.
+var SourceRangesChains = [2]string{SourceRangesChain, SourceRangesChain + "-1"}
+
+// GenerateSourceRangesRules returns the rules letting the traffic to the port of ip from the ranges
+// through and dropping the traffic from the other sources.
+// Example: iptables -t mangle -A OPENELB-SOURCE-RANGES -d 172.22.0.10 -p tcp --dport 80 -s 10.0.0.0/8 -j RETURN
+func GenerateSourceRangesRules(ip, protocol string, port int32, ranges []string) [][]string {
+	match := []string{"-d", ip, "-p", strings.ToLower(protocol), "--dport", strconv.Itoa(int(port))}
+	rules := make([][]string, 0, len(ranges)+1)
+	for _, r := range ranges {
+		rules = append(rules, append(append([]string{}, match...), "-s", r, "-j", "RETURN"))
+	}
+	return append(rules, append(append([]string{}, match...), "-j", "DROP"))
+}
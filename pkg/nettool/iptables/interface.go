@@ -38,3 +38,11 @@ func NewIPTables() IptablesIface {
 	}
 	return ipt
 }
+
+func NewIP6Tables() IptablesIface {
+	ipt, err := coreosiptables.NewWithProtocol(coreosiptables.ProtocolIPv6)
+	if err != nil {
+		panic(err)
+	}
+	return ipt
+}
@@ -0,0 +1,33 @@
+package nettool
+
+import (
+	"fmt"
+
+	coreosiptables "github.com/coreos/go-iptables/iptables"
+	"github.com/openelb/openelb/pkg/nettool/iptables"
+	"github.com/openelb/openelb/pkg/nettool/nftables"
+	"k8s.io/klog/v2"
+)
+
+// NewIPTables returns the iptables of the family, nftables is used on the
+// nodes without a working iptables, e.g. the ones missing iptables-legacy.
+func NewIPTables(ipv6 bool) (iptables.IptablesIface, error) {
+	proto, family := coreosiptables.ProtocolIPv4, nftables.FamilyIPv4
+	if ipv6 {
+		proto, family = coreosiptables.ProtocolIPv6, nftables.FamilyIPv6
+	}
+
+	ipt, iptErr := coreosiptables.NewWithProtocol(proto)
+	if iptErr == nil {
+		if _, iptErr = ipt.ListChains("nat"); iptErr == nil {
+			return ipt, nil
+		}
+	}
+
+	nft, nftErr := nftables.New(family)
+	if nftErr != nil {
+		return nil, fmt.Errorf("neither iptables nor nftables works, iptables: %v, nftables: %v", iptErr, nftErr)
+	}
+	klog.Infof("iptables doesn't work: %v, using nftables of family %s", iptErr, family)
+	return nft, nil
+}
@@ -4,10 +4,75 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/openelb/openelb/pkg/nettool"
+	"github.com/openelb/openelb/pkg/nettool/iptables"
+	"github.com/openelb/openelb/pkg/nettool/nftables"
 )
 
 var _ = Describe("Nettool", func() {
 	It("Should generate right iptables rule", func() {
 		Expect(GenerateCretiriaAndAction("10.10.12.1", "10.10.12.2", 17900)).To(ConsistOf("-s", "10.10.12.1", "-p", "tcp", "--dport", "179", "-j", "DNAT", "--to-destination", "10.10.12.2:17900"))
 	})
+
+	It("Should generate right source ranges rules", func() {
+		Expect(GenerateSourceRangesRules("172.22.0.10", "TCP", 80, []string{"10.0.0.0/8"})).To(Equal([][]string{
+			{"-d", "172.22.0.10", "-p", "tcp", "--dport", "80", "-s", "10.0.0.0/8", "-j", "RETURN"},
+			{"-d", "172.22.0.10", "-p", "tcp", "--dport", "80", "-j", "DROP"},
+		}))
+	})
+	It("Should create and delete the chain of bgp", func() {
+		ipt := iptables.NewFakeIPTables()
+		Expect(EnsureChainOfBGP(ipt)).To(Succeed())
+		Expect(EnsureChainOfBGP(ipt)).To(Succeed())
+		Expect(ipt.ListChains("nat")).To(ContainElement(BgpNatChain))
+		Expect(ipt.Data["nat"]["PREROUTING"]).To(HaveLen(1))
+		Expect(ipt.Exists("nat", "PREROUTING", "-j", BgpNatChain)).To(BeTrue())
+
+		Expect(AddPortForwardOfBGP(ipt, "10.10.12.1", "10.10.12.2", 17900)).To(Succeed())
+		Expect(ipt.Data["nat"][BgpNatChain]).To(HaveLen(1))
+
+		Expect(DeleteChainOfBGP(ipt)).To(Succeed())
+		Expect(ipt.ListChains("nat")).NotTo(ContainElement(BgpNatChain))
+		Expect(ipt.Data["nat"]["PREROUTING"]).To(BeEmpty())
+		Expect(DeleteChainOfBGP(ipt)).To(Succeed())
+	})
+
+	It("Should swap the chains jumped to", func() {
+		ipt := iptables.NewFakeIPTables()
+		chains := [2]string{"A", "B"}
+		Expect(SwapJumpChain(ipt, "mangle", "PREROUTING", chains, [][]string{{"-j", "DROP"}})).To(Equal("A"))
+		Expect(ipt.Data["mangle"]["A"]).To(HaveLen(1))
+		Expect(ipt.Data["mangle"]).NotTo(HaveKey("B"))
+
+		Expect(SwapJumpChain(ipt, "mangle", "PREROUTING", chains, [][]string{{"-j", "RETURN"}, {"-j", "DROP"}})).To(Equal("B"))
+		Expect(ipt.Data["mangle"]["B"]).To(HaveLen(2))
+		Expect(ipt.Data["mangle"]).NotTo(HaveKey("A"))
+		Expect(ipt.Data["mangle"]["PREROUTING"]).To(HaveLen(1))
+		Expect(ipt.Exists("mangle", "PREROUTING", "-j", "B")).To(BeTrue())
+
+		// a swap interrupted after the jump to the new chain is inserted
+		Expect(ipt.NewChain("mangle", "A")).To(Succeed())
+		Expect(ipt.Insert("mangle", "PREROUTING", 1, "-j", "A")).To(Succeed())
+		Expect(SwapJumpChain(ipt, "mangle", "PREROUTING", chains, [][]string{{"-j", "DROP"}})).To(Equal("B"))
+		Expect(ipt.Data["mangle"]["PREROUTING"]).To(HaveLen(1))
+		Expect(ipt.Data["mangle"]["B"]).To(HaveLen(1))
+		Expect(ipt.Data["mangle"]).NotTo(HaveKey("A"))
+	})
+
+	It("Should create and delete the chain of bgp with nftables", func() {
+		nft, fake := nftables.NewFakeNFTables(nftables.FamilyIPv4)
+		Expect(EnsureChainOfBGP(nft)).To(Succeed())
+		Expect(EnsureChainOfBGP(nft)).To(Succeed())
+		Expect(fake.Data["nat"]["PREROUTING"].Rules).To(HaveLen(1))
+
+		Expect(AddPortForwardOfBGP(nft, "10.10.12.1", "10.10.12.2", 17900)).To(Succeed())
+		Expect(AddPortForwardOfBGP(nft, "10.10.12.1", "10.10.12.2", 17900)).To(Succeed())
+		Expect(fake.Data["nat"][BgpNatChain].Rules).To(HaveLen(1))
+		Expect(fake.Data["nat"][BgpNatChain].Rules[0].Expr).To(Equal("ip saddr 10.10.12.1 meta l4proto tcp tcp dport 179 dnat to 10.10.12.2:17900"))
+		Expect(DeletePortForwardOfBGP(nft, "10.10.12.1", "10.10.12.2", 17900)).To(Succeed())
+		Expect(fake.Data["nat"][BgpNatChain].Rules).To(BeEmpty())
+
+		Expect(DeleteChainOfBGP(nft)).To(Succeed())
+		Expect(fake.Data["nat"]).NotTo(HaveKey(BgpNatChain))
+		Expect(fake.Data["nat"]["PREROUTING"].Rules).To(BeEmpty())
+	})
 })
@@ -0,0 +1,157 @@
+package nftables
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var _ Interface = &execNft{}
+
+// execNft runs the nft command, the commands are passed by stdin so the
+// names are quoted the same as in a ruleset file.
+type execNft struct {
+	path   string
+	family string
+}
+
+func newExecNft(family string) (*execNft, error) {
+	path, err := exec.LookPath("nft")
+	if err != nil {
+		return nil, err
+	}
+
+	n := &execNft{path: path, family: family}
+	// fails if nf_tables isn't supported by the kernel
+	if _, err := n.run(false, "list tables "+family); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *execNft) run(json bool, script string) ([]byte, error) {
+	args := []string{"-f", "-"}
+	if json {
+		args = append([]string{"-j"}, args...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(n.path, args...)
+	cmd.Stdin = strings.NewReader(script)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running %q: %v, %s", strings.TrimSpace(script), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func (n *execNft) table(table string) string {
+	return fmt.Sprintf("%s %s", n.family, quote(tablePrefix+table))
+}
+
+func (n *execNft) chain(table, chain string) string {
+	return fmt.Sprintf("%s %s", n.table(table), quote(chain))
+}
+
+func (n *execNft) EnsureTable(table string) error {
+	_, err := n.run(false, "add table "+n.table(table))
+	return err
+}
+
+// listed is the output of `nft -j list`.
+type listed struct {
+	Nftables []struct {
+		Chain *struct {
+			Name string `json:"name"`
+		} `json:"chain"`
+		Rule *struct {
+			Handle  int    `json:"handle"`
+			Comment string `json:"comment"`
+		} `json:"rule"`
+	} `json:"nftables"`
+}
+
+func (n *execNft) list(what string) (*listed, error) {
+	out, err := n.run(true, "list "+what)
+	if err != nil {
+		return nil, err
+	}
+	result := &listed{}
+	if err := json.Unmarshal(out, result); err != nil {
+		return nil, fmt.Errorf("parse nft output: %v", err)
+	}
+	return result, nil
+}
+
+func (n *execNft) ListChains(table string) ([]string, error) {
+	result, err := n.list("table " + n.table(table))
+	if err != nil {
+		return nil, err
+	}
+	chains := []string{}
+	for _, object := range result.Nftables {
+		if object.Chain != nil {
+			chains = append(chains, object.Chain.Name)
+		}
+	}
+	return chains, nil
+}
+
+func (n *execNft) AddChain(table, chain string, hook *Hook) error {
+	script := "add chain " + n.chain(table, chain)
+	if hook != nil {
+		script += fmt.Sprintf(" { type %s hook %s priority %d; }", hook.Type, hook.Hook, hook.Priority)
+	}
+	_, err := n.run(false, script)
+	return err
+}
+
+func (n *execNft) FlushChain(table, chain string) error {
+	_, err := n.run(false, "flush chain "+n.chain(table, chain))
+	return err
+}
+
+func (n *execNft) DeleteChain(table, chain string) error {
+	_, err := n.run(false, "delete chain "+n.chain(table, chain))
+	return err
+}
+
+func (n *execNft) ListRules(table, chain string) ([]Rule, error) {
+	result, err := n.list("chain " + n.chain(table, chain))
+	if err != nil {
+		return nil, err
+	}
+	rules := []Rule{}
+	for _, object := range result.Nftables {
+		if object.Rule != nil {
+			rules = append(rules, Rule{Handle: object.Rule.Handle, Comment: object.Rule.Comment})
+		}
+	}
+	return rules, nil
+}
+
+func (n *execNft) InsertRule(table, chain string, before int, expr, comment string) error {
+	script := "insert rule " + n.chain(table, chain)
+	if before > 0 {
+		script += fmt.Sprintf(" position %d", before)
+	}
+	_, err := n.run(false, fmt.Sprintf("%s %s comment %s", script, expr, quote(comment)))
+	return err
+}
+
+func (n *execNft) AppendRule(table, chain string, expr, comment string) error {
+	_, err := n.run(false, fmt.Sprintf("add rule %s %s comment %s", n.chain(table, chain), expr, quote(comment)))
+	return err
+}
+
+func (n *execNft) DeleteRule(table, chain string, handle int) error {
+	_, err := n.run(false, fmt.Sprintf("delete rule %s handle %d", n.chain(table, chain), handle))
+	return err
+}
+
+func quote(s string) string {
+	return `"` + s + `"`
+}
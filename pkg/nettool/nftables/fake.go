@@ -0,0 +1,159 @@
+package nftables
+
+import "fmt"
+
+var _ Interface = &FakeNft{}
+
+// FakeRule is a rule of the FakeNft with the expression translated.
+type FakeRule struct {
+	Rule
+	Expr string
+}
+
+type FakeChain struct {
+	Hook  *Hook
+	Rules []FakeRule
+}
+
+// FakeNft keeps the tables of a family in memory.
+type FakeNft struct {
+	Data   map[string]map[string]*FakeChain
+	handle int
+}
+
+func NewFakeNft() *FakeNft {
+	return &FakeNft{Data: make(map[string]map[string]*FakeChain)}
+}
+
+// NewFakeNFTables returns the NFTables backed by a FakeNft.
+func NewFakeNFTables(family string) (*NFTables, *FakeNft) {
+	fake := NewFakeNft()
+	return NewWithInterface(family, fake), fake
+}
+
+func (f *FakeNft) getChain(table, chain string) (*FakeChain, error) {
+	chains, ok := f.Data[table]
+	if !ok {
+		return nil, fmt.Errorf("table %s doesn't exist", table)
+	}
+	c, ok := chains[chain]
+	if !ok {
+		return nil, fmt.Errorf("chain %s doesn't exist", chain)
+	}
+	return c, nil
+}
+
+func (f *FakeNft) EnsureTable(table string) error {
+	if _, ok := f.Data[table]; !ok {
+		f.Data[table] = make(map[string]*FakeChain)
+	}
+	return nil
+}
+
+func (f *FakeNft) ListChains(table string) ([]string, error) {
+	chains, ok := f.Data[table]
+	if !ok {
+		return nil, fmt.Errorf("table %s doesn't exist", table)
+	}
+	result := make([]string, 0)
+	for name := range chains {
+		result = append(result, name)
+	}
+	return result, nil
+}
+
+func (f *FakeNft) AddChain(table, chain string, hook *Hook) error {
+	chains, ok := f.Data[table]
+	if !ok {
+		return fmt.Errorf("table %s doesn't exist", table)
+	}
+	if _, ok := chains[chain]; !ok {
+		chains[chain] = &FakeChain{Hook: hook}
+	}
+	return nil
+}
+
+func (f *FakeNft) FlushChain(table, chain string) error {
+	c, err := f.getChain(table, chain)
+	if err != nil {
+		return err
+	}
+	c.Rules = nil
+	return nil
+}
+
+func (f *FakeNft) DeleteChain(table, chain string) error {
+	c, err := f.getChain(table, chain)
+	if err != nil {
+		return err
+	}
+	if len(c.Rules) != 0 {
+		return fmt.Errorf("chain %s isn't empty", chain)
+	}
+	delete(f.Data[table], chain)
+	return nil
+}
+
+func (f *FakeNft) ListRules(table, chain string) ([]Rule, error) {
+	c, err := f.getChain(table, chain)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]Rule, 0, len(c.Rules))
+	for _, rule := range c.Rules {
+		rules = append(rules, rule.Rule)
+	}
+	return rules, nil
+}
+
+func (f *FakeNft) newRule(expr, comment string) FakeRule {
+	f.handle++
+	return FakeRule{Rule: Rule{Handle: f.handle, Comment: comment}, Expr: expr}
+}
+
+func (f *FakeNft) InsertRule(table, chain string, before int, expr, comment string) error {
+	c, err := f.getChain(table, chain)
+	if err != nil {
+		return err
+	}
+	index := 0
+	if before > 0 {
+		index = -1
+		for i, rule := range c.Rules {
+			if rule.Handle == before {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return fmt.Errorf("rule of handle %d doesn't exist", before)
+		}
+	}
+	rules := append([]FakeRule{}, c.Rules[:index]...)
+	rules = append(rules, f.newRule(expr, comment))
+	c.Rules = append(rules, c.Rules[index:]...)
+	return nil
+}
+
+func (f *FakeNft) AppendRule(table, chain string, expr, comment string) error {
+	c, err := f.getChain(table, chain)
+	if err != nil {
+		return err
+	}
+	c.Rules = append(c.Rules, f.newRule(expr, comment))
+	return nil
+}
+
+func (f *FakeNft) DeleteRule(table, chain string, handle int) error {
+	c, err := f.getChain(table, chain)
+	if err != nil {
+		return err
+	}
+	for i, rule := range c.Rules {
+		if rule.Handle == handle {
+			c.Rules = append(c.Rules[:i], c.Rules[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("rule of handle %d doesn't exist", handle)
+}
@@ -0,0 +1,38 @@
+package nftables
+
+const (
+	FamilyIPv4 = "ip"
+	FamilyIPv6 = "ip6"
+
+	// the tables of nftables are prefixed to not collide with the ones of iptables-nft
+	tablePrefix = "openelb_"
+)
+
+// Rule is a rule of a chain, the comment keeps the iptables rulespec it's
+// translated from, so the rule is found again without parsing nft.
+type Rule struct {
+	Handle  int
+	Comment string
+}
+
+// Hook makes a chain a base chain of the netfilter hook.
+type Hook struct {
+	Type     string
+	Hook     string
+	Priority int
+}
+
+// Interface runs the nftables operations of a family, it's implemented by
+// the nft command and faked in tests.
+type Interface interface {
+	EnsureTable(table string) error
+	ListChains(table string) ([]string, error)
+	AddChain(table, chain string, hook *Hook) error
+	FlushChain(table, chain string) error
+	DeleteChain(table, chain string) error
+	ListRules(table, chain string) ([]Rule, error)
+	// InsertRule inserts the rule before the rule of the handle, at the top of the chain if it's 0.
+	InsertRule(table, chain string, before int, expr, comment string) error
+	AppendRule(table, chain string, expr, comment string) error
+	DeleteRule(table, chain string, handle int) error
+}
@@ -0,0 +1,243 @@
+package nftables
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openelb/openelb/pkg/nettool/iptables"
+)
+
+var _ iptables.IptablesIface = &NFTables{}
+
+// the builtin chains of the iptables tables, created as base chains on demand
+var builtinChains = map[string]map[string]Hook{
+	"nat": {
+		"PREROUTING":  {Type: "nat", Hook: "prerouting", Priority: -100},
+		"INPUT":       {Type: "nat", Hook: "input", Priority: 100},
+		"OUTPUT":      {Type: "nat", Hook: "output", Priority: -100},
+		"POSTROUTING": {Type: "nat", Hook: "postrouting", Priority: 100},
+	},
+	"filter": {
+		"INPUT":   {Type: "filter", Hook: "input", Priority: 0},
+		"FORWARD": {Type: "filter", Hook: "forward", Priority: 0},
+		"OUTPUT":  {Type: "filter", Hook: "output", Priority: 0},
+	},
+	"mangle": {
+		"PREROUTING":  {Type: "filter", Hook: "prerouting", Priority: -150},
+		"INPUT":       {Type: "filter", Hook: "input", Priority: -150},
+		"FORWARD":     {Type: "filter", Hook: "forward", Priority: -150},
+		"OUTPUT":      {Type: "route", Hook: "output", Priority: -150},
+		"POSTROUTING": {Type: "filter", Hook: "postrouting", Priority: -150},
+	},
+}
+
+// NFTables implements the iptables operations with nftables, the rulespecs
+// are translated to nft rules of the tables owned by openelb.
+type NFTables struct {
+	family string
+	nft    Interface
+}
+
+// New returns the nftables of the family, it fails if nft is missing or
+// nftables isn't supported by the kernel.
+func New(family string) (*NFTables, error) {
+	nft, err := newExecNft(family)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithInterface(family, nft), nil
+}
+
+func NewWithInterface(family string, nft Interface) *NFTables {
+	return &NFTables{family: family, nft: nft}
+}
+
+// prepare creates the table and the chain if it's builtin.
+func (n *NFTables) prepare(table, chain string) error {
+	chains, ok := builtinChains[table]
+	if !ok {
+		return fmt.Errorf("unsupported table %s", table)
+	}
+	if err := n.nft.EnsureTable(table); err != nil {
+		return err
+	}
+	if hook, ok := chains[chain]; ok {
+		return n.nft.AddChain(table, chain, &hook)
+	}
+	return nil
+}
+
+func (n *NFTables) hasChain(table, chain string) (bool, error) {
+	chains, err := n.ListChains(table)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range chains {
+		if c == chain {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (n *NFTables) find(table, chain string, rulespec []string) (*Rule, error) {
+	found, err := n.hasChain(table, chain)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	rules, err := n.nft.ListRules(table, chain)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.Join(rulespec, " ")
+	for _, rule := range rules {
+		if rule.Comment == key {
+			return &rule, nil
+		}
+	}
+	return nil, nil
+}
+
+func (n *NFTables) Exists(table, chain string, rulespec ...string) (bool, error) {
+	rule, err := n.find(table, chain, rulespec)
+	return rule != nil, err
+}
+
+func (n *NFTables) Insert(table, chain string, pos int, rulespec ...string) error {
+	expr, c, err := n.translate(rulespec)
+	if err != nil {
+		return err
+	}
+	if err := n.prepare(table, chain); err != nil {
+		return err
+	}
+
+	rules, err := n.nft.ListRules(table, chain)
+	if err != nil {
+		return err
+	}
+	if pos < 1 || pos > len(rules)+1 {
+		return fmt.Errorf("index of insertion %d too big", pos)
+	}
+	if pos == len(rules)+1 {
+		return n.nft.AppendRule(table, chain, expr, c)
+	}
+	before := 0
+	if pos > 1 {
+		before = rules[pos-1].Handle
+	}
+	return n.nft.InsertRule(table, chain, before, expr, c)
+}
+
+func (n *NFTables) Append(table, chain string, rulespec ...string) error {
+	expr, c, err := n.translate(rulespec)
+	if err != nil {
+		return err
+	}
+	if err := n.prepare(table, chain); err != nil {
+		return err
+	}
+	return n.nft.AppendRule(table, chain, expr, c)
+}
+
+func (n *NFTables) Delete(table, chain string, rulespec ...string) error {
+	rule, err := n.find(table, chain, rulespec)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		return fmt.Errorf("rule %q doesn't exist in chain %s", strings.Join(rulespec, " "), chain)
+	}
+	return n.nft.DeleteRule(table, chain, rule.Handle)
+}
+
+// List returns the rules of the chain in the format of `iptables -S`.
+func (n *NFTables) List(table, chain string) ([]string, error) {
+	found, err := n.hasChain(table, chain)
+	if err != nil {
+		return nil, err
+	}
+	_, builtin := builtinChains[table][chain]
+	if !found && !builtin {
+		return nil, fmt.Errorf("chain %s doesn't exist", chain)
+	}
+
+	result := []string{"-N " + chain}
+	if builtin {
+		result = []string{"-P " + chain + " ACCEPT"}
+	}
+	if !found {
+		return result, nil
+	}
+
+	rules, err := n.nft.ListRules(table, chain)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		result = append(result, "-A "+chain+" "+rule.Comment)
+	}
+	return result, nil
+}
+
+func (n *NFTables) NewChain(table, chain string) error {
+	found, err := n.hasChain(table, chain)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("chain %s already exists", chain)
+	}
+	return n.nft.AddChain(table, chain, nil)
+}
+
+// ClearChain flushes the chain, it's created if it doesn't exist.
+func (n *NFTables) ClearChain(table, chain string) error {
+	found, err := n.hasChain(table, chain)
+	if err != nil {
+		return err
+	}
+	if found {
+		return n.nft.FlushChain(table, chain)
+	}
+	if err := n.prepare(table, chain); err != nil {
+		return err
+	}
+	if _, builtin := builtinChains[table][chain]; builtin {
+		return nil
+	}
+	return n.nft.AddChain(table, chain, nil)
+}
+
+func (n *NFTables) DeleteChain(table, chain string) error {
+	return n.nft.DeleteChain(table, chain)
+}
+
+// ListChains returns the chains of the table, the builtin chains are only
+// listed once they're used.
+func (n *NFTables) ListChains(table string) ([]string, error) {
+	if _, ok := builtinChains[table]; !ok {
+		return nil, fmt.Errorf("unsupported table %s", table)
+	}
+	if err := n.nft.EnsureTable(table); err != nil {
+		return nil, err
+	}
+	return n.nft.ListChains(table)
+}
+
+func (n *NFTables) HasRandomFully() bool {
+	return true
+}
+
+func (n *NFTables) translate(rulespec []string) (string, string, error) {
+	expr, err := translate(n.family, rulespec)
+	if err != nil {
+		return "", "", err
+	}
+	c, err := comment(rulespec)
+	if err != nil {
+		return "", "", err
+	}
+	return expr, c, nil
+}
@@ -0,0 +1,124 @@
+package nftables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		family   string
+		rulespec []string
+		expr     string
+	}{
+		{
+			family:   FamilyIPv4,
+			rulespec: []string{"-s", "10.10.12.1", "-p", "tcp", "--dport", "179", "-j", "DNAT", "--to-destination", "10.10.12.2:17900"},
+			expr:     "ip saddr 10.10.12.1 meta l4proto tcp tcp dport 179 dnat to 10.10.12.2:17900",
+		},
+		{
+			family:   FamilyIPv6,
+			rulespec: []string{"!", "-s", "fd00::10/128", "-p", "udp", "--dport", "53", "-j", "DNAT", "--to-destination", "[fd00::10]:53"},
+			expr:     "ip6 saddr != fd00::10/128 meta l4proto udp udp dport 53 dnat to [fd00::10]:53",
+		},
+		{
+			family:   FamilyIPv4,
+			rulespec: []string{"-d", "10.96.0.10/32", "-p", "tcp", "-j", "MASQUERADE", "--random-fully"},
+			expr:     "ip daddr 10.96.0.10/32 meta l4proto tcp masquerade fully-random",
+		},
+		{
+			family:   FamilyIPv4,
+			rulespec: []string{"-i", "eth0", "-p", "tcp", "-m", "tcp", "--sport", "1000:2000", "-m", "comment", "--comment", "ports", "-j", "ACCEPT"},
+			expr:     `iifname "eth0" meta l4proto tcp tcp sport 1000-2000 accept`,
+		},
+		{
+			family:   FamilyIPv4,
+			rulespec: []string{"-j", "PREROUTING-OPENELB"},
+			expr:     `jump "PREROUTING-OPENELB"`,
+		},
+		{
+			family:   FamilyIPv4,
+			rulespec: []string{"-o", "eth0"},
+			expr:     `oifname "eth0" counter`,
+		},
+	}
+	for _, tt := range tests {
+		expr, err := translate(tt.family, tt.rulespec)
+		assert.NoError(t, err, tt.rulespec)
+		assert.Equal(t, tt.expr, expr)
+	}
+
+	for _, rulespec := range [][]string{
+		{"-s"},
+		{"--dport", "179", "-j", "ACCEPT"},
+		{"-m", "conntrack", "--ctstate", "NEW"},
+		{"-p", "tcp", "-j", "DNAT"},
+		{"-j", "ACCEPT", "--to-destination", "10.0.0.1"},
+		{"!", "-j", "ACCEPT"},
+		{"-j", "ACCEPT", "--random-fully"},
+	} {
+		_, err := translate(FamilyIPv4, rulespec)
+		assert.Error(t, err, rulespec)
+	}
+}
+
+func TestNFTables(t *testing.T) {
+	nft, fake := NewFakeNFTables(FamilyIPv4)
+	jump := []string{"-j", "OPENELB"}
+	rule1 := []string{"-s", "10.0.0.1", "-p", "tcp", "--dport", "179", "-j", "DNAT", "--to-destination", "10.0.0.2:17900"}
+	rule2 := []string{"-s", "10.0.0.3", "-p", "tcp", "--dport", "179", "-j", "DNAT", "--to-destination", "10.0.0.2:17900"}
+	rule3 := []string{"-s", "10.0.0.4", "-j", "RETURN"}
+
+	assert.Error(t, nft.Append("raw", "PREROUTING", jump...), "unsupported table")
+	assert.Error(t, nft.Append("nat", "PREROUTING", "--unknown"), "unsupported option")
+
+	chains, err := nft.ListChains("nat")
+	assert.NoError(t, err)
+	assert.Empty(t, chains)
+	assert.NoError(t, nft.NewChain("nat", "OPENELB"))
+	assert.Error(t, nft.NewChain("nat", "OPENELB"), "chain exists")
+
+	// the builtin chain is created as a base chain
+	assert.NoError(t, nft.Insert("nat", "PREROUTING", 1, jump...))
+	assert.Equal(t, &Hook{Type: "nat", Hook: "prerouting", Priority: -100}, fake.Data["nat"]["PREROUTING"].Hook)
+	assert.Nil(t, fake.Data["nat"]["OPENELB"].Hook)
+	assert.Equal(t, `jump "OPENELB"`, fake.Data["nat"]["PREROUTING"].Rules[0].Expr)
+
+	exist, err := nft.Exists("nat", "PREROUTING", jump...)
+	assert.NoError(t, err)
+	assert.True(t, exist)
+	exist, err = nft.Exists("nat", "POSTROUTING", jump...)
+	assert.NoError(t, err)
+	assert.False(t, exist)
+
+	assert.NoError(t, nft.Append("nat", "OPENELB", rule1...))
+	assert.NoError(t, nft.Insert("nat", "OPENELB", 1, rule2...))
+	assert.NoError(t, nft.Insert("nat", "OPENELB", 2, rule3...))
+	assert.Error(t, nft.Insert("nat", "OPENELB", 5, rule3...))
+	rules, err := nft.List("nat", "OPENELB")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"-N OPENELB",
+		"-A OPENELB -s 10.0.0.3 -p tcp --dport 179 -j DNAT --to-destination 10.0.0.2:17900",
+		"-A OPENELB -s 10.0.0.4 -j RETURN",
+		"-A OPENELB -s 10.0.0.1 -p tcp --dport 179 -j DNAT --to-destination 10.0.0.2:17900",
+	}, rules)
+	rules, err = nft.List("nat", "POSTROUTING")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-P POSTROUTING ACCEPT"}, rules)
+
+	assert.NoError(t, nft.Delete("nat", "OPENELB", rule3...))
+	assert.Error(t, nft.Delete("nat", "OPENELB", rule3...))
+	assert.Len(t, fake.Data["nat"]["OPENELB"].Rules, 2)
+
+	assert.Error(t, nft.DeleteChain("nat", "OPENELB"), "chain isn't empty")
+	assert.NoError(t, nft.ClearChain("nat", "OPENELB"))
+	assert.Empty(t, fake.Data["nat"]["OPENELB"].Rules)
+	assert.NoError(t, nft.DeleteChain("nat", "OPENELB"))
+	assert.NotContains(t, fake.Data["nat"], "OPENELB")
+
+	// the chain is created if it doesn't exist
+	assert.NoError(t, nft.ClearChain("nat", "OPENELB"))
+	assert.Contains(t, fake.Data["nat"], "OPENELB")
+}
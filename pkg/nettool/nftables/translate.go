@@ -0,0 +1,128 @@
+package nftables
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxCommentLen is the limit of the comments of the rules in nftables.
+const maxCommentLen = 128
+
+// translate converts an iptables rulespec to the expression of a nft rule,
+// only the matches and targets used by openelb are supported.
+func translate(family string, rulespec []string) (string, error) {
+	var matches []string
+	protocol := ""
+	target := ""
+	negate := false
+
+	next := func(i int) (string, error) {
+		if i+1 >= len(rulespec) {
+			return "", fmt.Errorf("option %s requires a value", rulespec[i])
+		}
+		return rulespec[i+1], nil
+	}
+	op := func() string {
+		if negate {
+			negate = false
+			return "!= "
+		}
+		return ""
+	}
+
+	for i := 0; i < len(rulespec); i++ {
+		option := rulespec[i]
+		if option == "!" {
+			negate = true
+			continue
+		}
+
+		value, err := next(i)
+		if err != nil && option != "--random-fully" {
+			return "", err
+		}
+		i++
+
+		switch option {
+		case "-s", "--source":
+			matches = append(matches, fmt.Sprintf("%s saddr %s%s", family, op(), value))
+		case "-d", "--destination":
+			matches = append(matches, fmt.Sprintf("%s daddr %s%s", family, op(), value))
+		case "-i", "--in-interface":
+			matches = append(matches, fmt.Sprintf("iifname %s%s", op(), quote(value)))
+		case "-o", "--out-interface":
+			matches = append(matches, fmt.Sprintf("oifname %s%s", op(), quote(value)))
+		case "-p", "--protocol":
+			protocol = strings.ToLower(value)
+			matches = append(matches, fmt.Sprintf("meta l4proto %s%s", op(), protocol))
+		case "--dport", "--destination-port", "--sport", "--source-port":
+			if protocol == "" {
+				return "", fmt.Errorf("option %s requires a protocol", option)
+			}
+			field := "dport"
+			if option == "--sport" || option == "--source-port" {
+				field = "sport"
+			}
+			matches = append(matches, fmt.Sprintf("%s %s %s%s", protocol, field, op(), strings.Replace(value, ":", "-", 1)))
+		case "-m", "--match":
+			switch value {
+			case "tcp", "udp", "sctp", "comment":
+			default:
+				return "", fmt.Errorf("unsupported match %s", value)
+			}
+		case "--comment":
+			// the rulespec is kept as the comment of the rule
+		case "-j", "--jump", "-g", "--goto":
+			switch value {
+			case "ACCEPT", "DROP", "RETURN", "MASQUERADE":
+				target = strings.ToLower(value)
+			case "DNAT", "SNAT":
+				// completed by --to-destination and --to-source
+				target = value
+			default:
+				verdict := "jump"
+				if option == "-g" || option == "--goto" {
+					verdict = "goto"
+				}
+				target = fmt.Sprintf("%s %s", verdict, quote(value))
+			}
+		case "--to-destination", "--to-source":
+			if (option == "--to-destination" && target != "DNAT") || (option == "--to-source" && target != "SNAT") {
+				return "", fmt.Errorf("option %s doesn't match the target %s", option, target)
+			}
+			target = fmt.Sprintf("%s to %s", strings.ToLower(target), value)
+		case "--random-fully":
+			i--
+			if target != "masquerade" {
+				return "", fmt.Errorf("option %s requires the target MASQUERADE", option)
+			}
+			target = "masquerade fully-random"
+		default:
+			return "", fmt.Errorf("unsupported option %s", option)
+		}
+		if negate {
+			return "", fmt.Errorf("option %s can't be negated", option)
+		}
+	}
+
+	switch target {
+	case "DNAT", "SNAT":
+		return "", fmt.Errorf("target %s requires an address", target)
+	case "":
+		// a rule without target only counts the packets in iptables
+		target = "counter"
+	}
+	return strings.Join(append(matches, target), " "), nil
+}
+
+// comment returns the comment of the rule of the rulespec.
+func comment(rulespec []string) (string, error) {
+	c := strings.Join(rulespec, " ")
+	if len(c) > maxCommentLen {
+		return "", fmt.Errorf("rule %q is longer than %d", c, maxCommentLen)
+	}
+	if strings.Contains(c, `"`) {
+		return "", fmt.Errorf("rule %q contains quotes", c)
+	}
+	return c, nil
+}
@@ -0,0 +1,32 @@
+package nodeproxy
+
+import (
+	"os"
+	"time"
+
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	Rules        string
+	ResyncPeriod time.Duration
+	SetupOnly    bool
+	Check        bool
+}
+
+func NewOptions() *Options {
+	return &Options{
+		Rules:        os.Getenv(constant.NodeProxyRulesEnv),
+		ResyncPeriod: 30 * time.Second,
+		SetupOnly:    false,
+		Check:        false,
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Rules, "rules", o.Rules, "specify the forwarding rules, 4-tuples <SVC_IP POD_PORT SVC_PORT SVC_PROTO> split by space, default to $"+constant.NodeProxyRulesEnv)
+	fs.DurationVar(&o.ResyncPeriod, "resync-period", o.ResyncPeriod, "specify the interval to restore the rules removed by other programs")
+	fs.BoolVar(&o.SetupOnly, "setup-only", o.SetupOnly, "only enable ip forwarding in the pod network namespace and exit, requires privileged")
+	fs.BoolVar(&o.Check, "check", o.Check, "check that ip forwarding is enabled and the rules are installed and exit, used as the readiness probe")
+}
@@ -0,0 +1,303 @@
+package nodeproxy
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openelb/openelb/pkg/nettool/iptables"
+	"k8s.io/klog/v2"
+)
+
+const (
+	natTable = "nat"
+
+	// The chains owned by the node-proxy, jumped to from the builtin chains
+	// so that the rules can be replaced without touching other rules.
+	PreroutingChain  = "OPENELB-NODE-PROXY"
+	PostroutingChain = "OPENELB-NODE-PROXY-MASQ"
+
+	ipv4ForwardSysctl = "/proc/sys/net/ipv4/ip_forward"
+	ipv6ForwardSysctl = "/proc/sys/net/ipv6/conf/all/forwarding"
+)
+
+// Rule forwards the traffic of Protocol received on Port to Destination:DestinationPort.
+type Rule struct {
+	Destination     string
+	Port            int32
+	DestinationPort int32
+	Protocol        string
+}
+
+func (r Rule) ipv6() bool {
+	return net.ParseIP(r.Destination).To4() == nil
+}
+
+func (r Rule) hostCIDR() string {
+	if r.ipv6() {
+		return r.Destination + "/128"
+	}
+	return r.Destination + "/32"
+}
+
+func (r Rule) dnat() []string {
+	return []string{"!", "-s", r.hostCIDR(), "-p", r.Protocol, "--dport", strconv.Itoa(int(r.Port)),
+		"-j", "DNAT", "--to-destination", net.JoinHostPort(r.Destination, strconv.Itoa(int(r.DestinationPort)))}
+}
+
+func (r Rule) masquerade() []string {
+	return []string{"-d", r.hostCIDR(), "-p", r.Protocol, "-j", "MASQUERADE"}
+}
+
+// ParseRules parses the `PROXY_ARGS` format, 4-tuples split by space:
+// <SVC_IP POD_PORT SVC_PORT SVC_PROTO>
+func ParseRules(args string) ([]Rule, error) {
+	fields := strings.Fields(args)
+	if len(fields)%4 != 0 {
+		return nil, fmt.Errorf("invalid proxy args %q, expect 4-tuples", args)
+	}
+
+	rules := make([]Rule, 0, len(fields)/4)
+	for i := 0; i < len(fields); i += 4 {
+		if net.ParseIP(fields[i]) == nil {
+			return nil, fmt.Errorf("invalid destination %q", fields[i])
+		}
+		port, err := strconv.ParseUint(fields[i+1], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", fields[i+1])
+		}
+		dport, err := strconv.ParseUint(fields[i+2], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid destination port %q", fields[i+2])
+		}
+		protocol := strings.ToLower(fields[i+3])
+		switch protocol {
+		case "tcp", "udp", "sctp":
+		default:
+			return nil, fmt.Errorf("unsupported protocol %q", fields[i+3])
+		}
+
+		rules = append(rules, Rule{
+			Destination:     fields[i],
+			Port:            int32(port),
+			DestinationPort: int32(dport),
+			Protocol:        protocol,
+		})
+	}
+	return rules, nil
+}
+
+// Proxy programs the DNAT and masquerade rules of a node-proxy pod.
+type Proxy struct {
+	rules []Rule
+	ipt   iptables.IptablesIface
+	ip6t  iptables.IptablesIface
+}
+
+// NewProxy creates a proxy, ip6t is only used for rules with an IPv6 destination.
+func NewProxy(ipt, ip6t iptables.IptablesIface, rules []Rule) *Proxy {
+	return &Proxy{rules: rules, ipt: ipt, ip6t: ip6t}
+}
+
+func (p *Proxy) families() map[iptables.IptablesIface][]Rule {
+	families := map[iptables.IptablesIface][]Rule{}
+	if p.ipt != nil {
+		families[p.ipt] = nil
+	}
+	if p.ip6t != nil {
+		families[p.ip6t] = nil
+	}
+	for _, rule := range p.rules {
+		ipt := p.ipt
+		if rule.ipv6() {
+			ipt = p.ip6t
+		}
+		families[ipt] = append(families[ipt], rule)
+	}
+	return families
+}
+
+// Setup flushes the chains owned by the proxy and installs every rule.
+func (p *Proxy) Setup() error {
+	for ipt, rules := range p.families() {
+		if ipt == nil {
+			return fmt.Errorf("no iptables for rules %v", rules)
+		}
+		for _, chain := range []string{PreroutingChain, PostroutingChain} {
+			if err := ensureChain(ipt, chain); err != nil {
+				return err
+			}
+			if err := ipt.ClearChain(natTable, chain); err != nil {
+				return err
+			}
+		}
+	}
+	return p.Sync()
+}
+
+// Sync restores the jumps and rules that are missing, e.g. after the chains
+// were flushed by another program.
+func (p *Proxy) Sync() error {
+	for ipt, rules := range p.families() {
+		if ipt == nil {
+			return fmt.Errorf("no iptables for rules %v", rules)
+		}
+		if err := ensureChain(ipt, PreroutingChain); err != nil {
+			return err
+		}
+		if err := ensureChain(ipt, PostroutingChain); err != nil {
+			return err
+		}
+		if err := ensureRule(ipt, "PREROUTING", true, "-j", PreroutingChain); err != nil {
+			return err
+		}
+		if err := ensureRule(ipt, "POSTROUTING", true, "-j", PostroutingChain); err != nil {
+			return err
+		}
+
+		for _, rule := range rules {
+			if err := ensureRule(ipt, PreroutingChain, false, rule.dnat()...); err != nil {
+				return err
+			}
+			if err := ensureRule(ipt, PostroutingChain, false, rule.masquerade()...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Check returns an error if a jump or a rule is missing, it backs the
+// readiness probe of the node-proxy pods.
+func (p *Proxy) Check() error {
+	for ipt, rules := range p.families() {
+		if ipt == nil {
+			return fmt.Errorf("no iptables for rules %v", rules)
+		}
+		if err := checkRule(ipt, "PREROUTING", "-j", PreroutingChain); err != nil {
+			return err
+		}
+		if err := checkRule(ipt, "POSTROUTING", "-j", PostroutingChain); err != nil {
+			return err
+		}
+		for _, rule := range rules {
+			if err := checkRule(ipt, PreroutingChain, rule.dnat()...); err != nil {
+				return err
+			}
+			if err := checkRule(ipt, PostroutingChain, rule.masquerade()...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Cleanup removes the jumps and the chains owned by the proxy.
+func (p *Proxy) Cleanup() error {
+	for ipt := range p.families() {
+		if ipt == nil {
+			continue
+		}
+		for builtin, chain := range map[string]string{"PREROUTING": PreroutingChain, "POSTROUTING": PostroutingChain} {
+			exist, err := ipt.Exists(natTable, builtin, "-j", chain)
+			if err != nil {
+				return err
+			}
+			if exist {
+				if err := ipt.Delete(natTable, builtin, "-j", chain); err != nil {
+					return err
+				}
+			}
+			if err := ipt.ClearChain(natTable, chain); err != nil {
+				return err
+			}
+			if err := ipt.DeleteChain(natTable, chain); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Run installs the rules, keeps them in sync every resyncPeriod and removes
+// them once stopCh is closed.
+func (p *Proxy) Run(resyncPeriod time.Duration, stopCh <-chan struct{}) error {
+	if err := p.Setup(); err != nil {
+		return err
+	}
+	klog.Infof("node-proxy forwarding %d rules", len(p.rules))
+
+	ticker := time.NewTicker(resyncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return p.Cleanup()
+		case <-ticker.C:
+			if err := p.Sync(); err != nil {
+				klog.Errorf("node-proxy sync rules error: %v", err)
+			}
+		}
+	}
+}
+
+func ensureChain(ipt iptables.IptablesIface, chain string) error {
+	chains, err := ipt.ListChains(natTable)
+	if err != nil {
+		return err
+	}
+	for _, c := range chains {
+		if c == chain {
+			return nil
+		}
+	}
+	return ipt.NewChain(natTable, chain)
+}
+
+func ensureRule(ipt iptables.IptablesIface, chain string, first bool, rulespec ...string) error {
+	exist, err := ipt.Exists(natTable, chain, rulespec...)
+	if err != nil || exist {
+		return err
+	}
+	if first {
+		return ipt.Insert(natTable, chain, 1, rulespec...)
+	}
+	return ipt.Append(natTable, chain, rulespec...)
+}
+
+func checkRule(ipt iptables.IptablesIface, chain string, rulespec ...string) error {
+	exist, err := ipt.Exists(natTable, chain, rulespec...)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return fmt.Errorf("rule %q is missing in chain %s", strings.Join(rulespec, " "), chain)
+	}
+	return nil
+}
+
+// EnableIPForward turns on forwarding in the network namespace of the pod,
+// it requires a privileged container.
+func EnableIPForward(ipv6 bool) error {
+	sysctl := ipv4ForwardSysctl
+	if ipv6 {
+		sysctl = ipv6ForwardSysctl
+	}
+	return os.WriteFile(sysctl, []byte("1"), 0640)
+}
+
+// IPForwardEnabled reports whether forwarding is on in the network namespace of the pod.
+func IPForwardEnabled(ipv6 bool) (bool, error) {
+	sysctl := ipv4ForwardSysctl
+	if ipv6 {
+		sysctl = ipv6ForwardSysctl
+	}
+	data, err := os.ReadFile(sysctl)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(data)) == "1", nil
+}
@@ -0,0 +1,103 @@
+package nodeproxy
+
+import (
+	"testing"
+
+	"github.com/openelb/openelb/pkg/nettool/iptables"
+	"github.com/openelb/openelb/pkg/nettool/nftables"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("10.96.0.10 80 8080 tcp fd00::10 53 53 UDP ")
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Destination: "10.96.0.10", Port: 80, DestinationPort: 8080, Protocol: "tcp"},
+		{Destination: "fd00::10", Port: 53, DestinationPort: 53, Protocol: "udp"},
+	}, rules)
+
+	rules, err = ParseRules("")
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+
+	for _, args := range []string{
+		"10.96.0.10 80 8080",
+		"svc 80 8080 tcp",
+		"10.96.0.10 http 8080 tcp",
+		"10.96.0.10 80 70000 tcp",
+		"10.96.0.10 80 8080 icmp",
+	} {
+		_, err := ParseRules(args)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	ipt := iptables.NewFakeIPTables()
+	ip6t := iptables.NewFakeIPTables()
+	rules, err := ParseRules("10.96.0.10 80 8080 tcp fd00::10 53 53 udp")
+	assert.NoError(t, err)
+
+	p := NewProxy(ipt, ip6t, rules)
+	assert.NoError(t, p.Setup())
+
+	exist, _ := ipt.Exists(natTable, "PREROUTING", "-j", PreroutingChain)
+	assert.True(t, exist)
+	exist, _ = ipt.Exists(natTable, "POSTROUTING", "-j", PostroutingChain)
+	assert.True(t, exist)
+	exist, _ = ipt.Exists(natTable, PreroutingChain, "!", "-s", "10.96.0.10/32", "-p", "tcp", "--dport", "80", "-j", "DNAT", "--to-destination", "10.96.0.10:8080")
+	assert.True(t, exist)
+	exist, _ = ipt.Exists(natTable, PostroutingChain, "-d", "10.96.0.10/32", "-p", "tcp", "-j", "MASQUERADE")
+	assert.True(t, exist)
+	exist, _ = ip6t.Exists(natTable, PreroutingChain, "!", "-s", "fd00::10/128", "-p", "udp", "--dport", "53", "-j", "DNAT", "--to-destination", "[fd00::10]:53")
+	assert.True(t, exist)
+	assert.Len(t, ipt.Data[natTable][PreroutingChain], 1, "ipv6 rules are not installed by iptables")
+
+	// sync is idempotent and restores flushed rules
+	assert.NoError(t, p.Sync())
+	assert.Len(t, ipt.Data[natTable][PreroutingChain], 1)
+	assert.Len(t, ipt.Data[natTable]["PREROUTING"], 1)
+	assert.NoError(t, p.Check())
+	assert.NoError(t, ipt.ClearChain(natTable, PostroutingChain))
+	assert.Error(t, p.Check(), "masquerade rule is missing")
+	assert.NoError(t, p.Sync())
+	assert.Len(t, ipt.Data[natTable][PostroutingChain], 1)
+	assert.NoError(t, p.Check())
+
+	assert.NoError(t, p.Cleanup())
+	for _, fake := range []*iptables.FakeIPTables{ipt, ip6t} {
+		assert.NotContains(t, fake.Data[natTable], PreroutingChain)
+		assert.NotContains(t, fake.Data[natTable], PostroutingChain)
+		assert.Empty(t, fake.Data[natTable]["PREROUTING"])
+		assert.Empty(t, fake.Data[natTable]["POSTROUTING"])
+	}
+
+	assert.Error(t, NewProxy(ipt, nil, rules).Setup(), "no ip6tables for ipv6 rules")
+}
+
+func TestProxyNFTables(t *testing.T) {
+	nft, fake := nftables.NewFakeNFTables(nftables.FamilyIPv4)
+	nft6, fake6 := nftables.NewFakeNFTables(nftables.FamilyIPv6)
+	rules, err := ParseRules("10.96.0.10 80 8080 tcp fd00::10 53 53 udp")
+	assert.NoError(t, err)
+
+	p := NewProxy(nft, nft6, rules)
+	assert.NoError(t, p.Setup())
+	assert.NoError(t, p.Check())
+	assert.Equal(t, "ip saddr != 10.96.0.10/32 meta l4proto tcp tcp dport 80 dnat to 10.96.0.10:8080", fake.Data[natTable][PreroutingChain].Rules[0].Expr)
+	assert.Equal(t, "ip daddr 10.96.0.10/32 meta l4proto tcp masquerade", fake.Data[natTable][PostroutingChain].Rules[0].Expr)
+	assert.Equal(t, "ip6 saddr != fd00::10/128 meta l4proto udp udp dport 53 dnat to [fd00::10]:53", fake6.Data[natTable][PreroutingChain].Rules[0].Expr)
+
+	// setup again flushes the chains
+	assert.NoError(t, p.Setup())
+	assert.Len(t, fake.Data[natTable][PreroutingChain].Rules, 1)
+	assert.Len(t, fake.Data[natTable]["PREROUTING"].Rules, 1)
+
+	assert.NoError(t, p.Cleanup())
+	for _, f := range []*nftables.FakeNft{fake, fake6} {
+		assert.NotContains(t, f.Data[natTable], PreroutingChain)
+		assert.NotContains(t, f.Data[natTable], PostroutingChain)
+		assert.Empty(t, f.Data[natTable]["PREROUTING"].Rules)
+		assert.Empty(t, f.Data[natTable]["POSTROUTING"].Rules)
+	}
+}
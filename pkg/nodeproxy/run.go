@@ -0,0 +1,70 @@
+package nodeproxy
+
+import (
+	"fmt"
+
+	"github.com/openelb/openelb/pkg/nettool"
+	"github.com/openelb/openelb/pkg/nettool/iptables"
+	"k8s.io/klog/v2"
+)
+
+// Run is the entry of the node-proxy agent.
+func Run(opt *Options, stopCh <-chan struct{}) error {
+	rules, err := ParseRules(opt.Rules)
+	if err != nil {
+		return err
+	}
+
+	ipv4, ipv6 := false, false
+	for _, rule := range rules {
+		if rule.ipv6() {
+			ipv6 = true
+		} else {
+			ipv4 = true
+		}
+	}
+
+	if opt.SetupOnly {
+		for family, needed := range map[bool]bool{false: ipv4, true: ipv6} {
+			if !needed {
+				continue
+			}
+			if err := EnableIPForward(family); err != nil {
+				return err
+			}
+		}
+		klog.Info("node-proxy enabled ip forwarding")
+		return nil
+	}
+
+	var ipt, ip6t iptables.IptablesIface
+	if ipv4 {
+		if ipt, err = nettool.NewIPTables(false); err != nil {
+			return err
+		}
+	}
+	if ipv6 {
+		if ip6t, err = nettool.NewIPTables(true); err != nil {
+			return err
+		}
+	}
+	proxy := NewProxy(ipt, ip6t, rules)
+
+	if opt.Check {
+		for family, needed := range map[bool]bool{false: ipv4, true: ipv6} {
+			if !needed {
+				continue
+			}
+			enabled, err := IPForwardEnabled(family)
+			if err != nil {
+				return err
+			}
+			if !enabled {
+				return fmt.Errorf("ip forwarding is disabled, ipv6: %v", family)
+			}
+		}
+		return proxy.Check()
+	}
+
+	return proxy.Run(opt.ResyncPeriod, stopCh)
+}
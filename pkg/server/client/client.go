@@ -0,0 +1,201 @@
+// Package client is a typed client of the REST API of the OpenELB apiserver,
+// the operations are described by the OpenAPI document served at
+// /openapi.json and named after their operationId.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openelb/openelb/pkg/server/internal/handler"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type (
+	Create         = handler.Create
+	Update         = handler.Update
+	Operation      = handler.Operation
+	ListOptions    = handler.ListOptions
+	Allocation     = handler.Allocation
+	Service        = handler.Service
+	ServiceAddress = handler.ServiceAddress
+	BgpSession     = handler.BgpSession
+	EipImpact      = handler.EipImpact
+	BgpPeerImpact  = handler.BgpPeerImpact
+)
+
+const (
+	OperationCreate = handler.OperationCreate
+	OperationUpdate = handler.OperationUpdate
+	OperationPatch  = handler.OperationPatch
+	OperationDelete = handler.OperationDelete
+)
+
+// List is a page of a read-only view.
+type List[T any] struct {
+	Items []T `json:"items"`
+	// Continue is set if there are more items, pass it to get the next page
+	Continue string `json:"continue,omitempty"`
+}
+
+// DryRunResult is the result of a change validated without being persisted.
+type DryRunResult[T any] struct {
+	// Valid is true if the change would be accepted
+	Valid bool `json:"valid"`
+	// Errors are the reasons the change would be rejected
+	Errors []string `json:"errors,omitempty"`
+	// Impact is the impact of the change on the cluster
+	Impact *T `json:"impact,omitempty"`
+}
+
+// Config is the configuration of a Client.
+type Config struct {
+	// Host is the URL of the apiserver, e.g. http://openelb-apiserver:8080
+	Host string
+	// BearerToken authenticates the requests, e.g. a service account token
+	BearerToken string
+	// HTTPClient sends the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+}
+
+// Client is a typed client of the REST API. The errors returned by the
+// apiserver are *errors.StatusError, so they can be checked with the
+// helpers of k8s.io/apimachinery/pkg/api/errors.
+type Client struct {
+	host       *url.URL
+	token      string
+	httpClient *http.Client
+}
+
+// NewForConfig returns a client of the apiserver of the config.
+func NewForConfig(c *Config) (*Client, error) {
+	host, err := url.Parse(c.Host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid host %s: %v", c.Host, err)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		host:       host,
+		token:      c.BearerToken,
+		httpClient: httpClient,
+	}, nil
+}
+
+// send sends the request and returns the response if it succeeded, the
+// Status of a failed request is returned as an error. The body is
+// marshalled unless it's a []byte.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values,
+	contentType string, body interface{}) (*http.Response, error) {
+	u := *c.host
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		data, ok := body.([]byte)
+		if !ok {
+			var err error
+			if data, err = json.Marshal(body); err != nil {
+				return nil, err
+			}
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < http.StatusBadRequest {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	status := metav1.Status{}
+	if err := json.Unmarshal(data, &status); err != nil || status.Kind != "Status" {
+		return nil, apierrors.NewGenericServerResponse(resp.StatusCode, method, schema.GroupResource{}, "", string(data), 0, false)
+	}
+	return nil, &apierrors.StatusError{ErrStatus: status}
+}
+
+// do sends the request and unmarshals the response body into out.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values,
+	contentType string, body, out interface{}) error {
+	resp, err := c.send(ctx, method, path, query, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// dryRun sends the change of the object at the path in dry run mode.
+func (c *Client) dryRun(ctx context.Context, op Operation, path string,
+	obj interface{}, patch []byte, out interface{}) error {
+	query := url.Values{"dryRun": {"true"}}
+	switch op {
+	case OperationCreate:
+		return c.do(ctx, http.MethodPost, path, query, "", obj, out)
+	case OperationUpdate:
+		return c.do(ctx, http.MethodPut, path, query, "", obj, out)
+	case OperationPatch:
+		return c.do(ctx, http.MethodPatch, path, query, mergePatch, patch, out)
+	case OperationDelete:
+		return c.do(ctx, http.MethodDelete, path, query, "", nil, out)
+	}
+	return fmt.Errorf("unknown operation %s", op)
+}
+
+const mergePatch = "application/merge-patch+json"
+
+func namedPath(path, name string) string {
+	return path + "/" + url.PathEscape(name)
+}
+
+// listQuery returns the query params of a read-only view.
+func listQuery(opts ListOptions) url.Values {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"namespace": opts.Namespace,
+		"eip":       opts.Eip,
+		"protocol":  opts.Protocol,
+		"node":      opts.Node,
+		"continue":  opts.Continue,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", fmt.Sprint(opts.Limit))
+	}
+	return query
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/server/internal/handler"
+	"github.com/openelb/openelb/pkg/server/internal/lib"
+	"github.com/openelb/openelb/pkg/server/internal/router"
+	"github.com/openelb/openelb/pkg/server/options"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeWatchHandler struct {
+	eips *lib.Broadcaster
+}
+
+func (f *fakeWatchHandler) Eips() *lib.Broadcaster        { return f.eips }
+func (f *fakeWatchHandler) BgpPeers() *lib.Broadcaster    { return f.eips }
+func (f *fakeWatchHandler) BgpConfs() *lib.Broadcaster    { return f.eips }
+func (f *fakeWatchHandler) Allocations() *lib.Broadcaster { return f.eips }
+
+func newTestServer(t *testing.T, eips *lib.Broadcaster) (*httptest.Server, *Client) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = v1alpha2.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	server := lib.NewHTTPServer([]lib.Router{
+		router.NewBgpConfRouter(handler.NewBgpConfHandler(c)),
+		router.NewBgpPeerRouter(handler.NewBgpPeerHandler(c)),
+		router.NewEipRouter(handler.NewEipHandler(c)),
+		router.NewAllocationRouter(handler.NewAllocationHandler(c, nil)),
+		router.NewServiceRouter(handler.NewServiceHandler(c, nil)),
+		router.NewBgpSessionRouter(handler.NewBgpSessionHandler(c)),
+		router.NewWatchRouter(&fakeWatchHandler{eips: eips}),
+	}, options.Options{}, nil)
+	ts := httptest.NewServer(server)
+	client, err := NewForConfig(&Config{Host: ts.URL})
+	assert.NoError(t, err)
+	return ts, client
+}
+
+func TestOpenAPI(t *testing.T) {
+	ts, _ := newTestServer(t, lib.NewBroadcaster(lib.DefaultHistorySize))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/openapi.json")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	doc := &lib.OpenAPI{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(doc))
+
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Contains(t, doc.Components.Schemas, "v1alpha2.Eip")
+	assert.Contains(t, doc.Components.Schemas, "v1.Status")
+	getEip := (*doc.Paths["/apis/v1/eip/{name}"])["get"]
+	assert.Equal(t, "#/components/schemas/v1alpha2.Eip", getEip.Responses["200"].Content["application/json"].Schema.Ref)
+	assert.Equal(t, "name", getEip.Parameters[0].Name)
+
+	// every operation of the document has a method in the client
+	clientType := reflect.TypeOf(&Client{})
+	for path, item := range doc.Paths {
+		for method, op := range *item {
+			name := strings.ToUpper(op.OperationID[:1]) + op.OperationID[1:]
+			_, ok := clientType.MethodByName(name)
+			assert.True(t, ok, "%s %s: missing method %s", method, path, name)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	eips := lib.NewBroadcaster(lib.DefaultHistorySize)
+	ts, c := newTestServer(t, eips)
+	defer ts.Close()
+	ctx := context.Background()
+
+	created, err := c.CreateEip(ctx, &v1alpha2.Eip{
+		ObjectMeta: metav1.ObjectMeta{Name: "eip-1"},
+		Spec:       v1alpha2.EipSpec{Address: "192.168.0.0/24"},
+	})
+	assert.NoError(t, err)
+	assert.True(t, created.Created)
+
+	eip, err := c.GetEip(ctx, "eip-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.0.0/24", eip.Spec.Address)
+
+	_, err = c.GetEip(ctx, "eip-2")
+	assert.True(t, apierrors.IsNotFound(err))
+
+	_, err = c.CreateEip(ctx, &v1alpha2.Eip{ObjectMeta: metav1.ObjectMeta{Name: "eip-1"}})
+	assert.True(t, apierrors.IsAlreadyExists(err))
+
+	updated, err := c.PatchEip(ctx, "eip-1", []byte(`{"spec":{"priority":10}}`))
+	assert.NoError(t, err)
+	assert.True(t, updated.Updated)
+
+	result, err := c.DryRunEip(ctx, OperationPatch, "eip-1", nil, []byte(`{"spec":{"address":"192.168.0.0/25"}}`))
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.NotNil(t, result.Impact)
+
+	list, err := c.ListEips(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, list.Items, 1)
+	assert.Equal(t, 10, list.Items[0].Spec.Priority)
+
+	allocations, err := c.ListAllocations(ctx, ListOptions{Limit: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, allocations.Items)
+
+	_, err = c.ListAllocations(ctx, ListOptions{Continue: "!"})
+	assert.True(t, apierrors.IsBadRequest(err))
+
+	assert.NoError(t, c.DeleteEip(ctx, "eip-1"))
+	_, err = c.GetEip(ctx, "eip-1")
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestClientWatch(t *testing.T) {
+	eips := lib.NewBroadcaster(lib.DefaultHistorySize)
+	eips.Publish(lib.Added, "eip-1", &v1alpha2.Eip{ObjectMeta: metav1.ObjectMeta{Name: "eip-1"}}, "5", true)
+	eips.Synced("5")
+	ts, c := newTestServer(t, eips)
+	defer ts.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := c.WatchEips(ctx, "1")
+	assert.True(t, apierrors.IsResourceExpired(err))
+
+	stream, err := c.WatchEips(ctx, "")
+	assert.NoError(t, err)
+	defer stream.Close()
+
+	event, err := stream.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, Added, event.Type)
+	assert.Equal(t, "eip-1", event.Object.Name)
+	assert.Equal(t, "5", event.ResourceVersion)
+
+	eips.Publish(lib.Deleted, "eip-1", &v1alpha2.Eip{ObjectMeta: metav1.ObjectMeta{Name: "eip-1"}}, "6", false)
+	event, err = stream.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, Deleted, event.Type)
+	assert.Equal(t, "6.0", event.ResourceVersion)
+}
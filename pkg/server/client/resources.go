@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+)
+
+const (
+	eipPath         = "/apis/v1/eip"
+	bgpPeerPath     = "/apis/v1/bgp"
+	bgpConfPath     = "/apis/v1/bgp/conf"
+	allocationsPath = "/apis/v1/allocations"
+	servicesPath    = "/apis/v1/services"
+	bgpSessionsPath = "/apis/v1/bgp/sessions"
+)
+
+// CreateEip creates an Eip.
+func (c *Client) CreateEip(ctx context.Context, eip *v1alpha2.Eip) (*Create, error) {
+	result := &Create{}
+	return result, c.do(ctx, http.MethodPost, eipPath, nil, "", eip, result)
+}
+
+// GetEip returns the Eip.
+func (c *Client) GetEip(ctx context.Context, name string) (*v1alpha2.Eip, error) {
+	result := &v1alpha2.Eip{}
+	return result, c.do(ctx, http.MethodGet, namedPath(eipPath, name), nil, "", nil, result)
+}
+
+// ListEips returns all the Eips.
+func (c *Client) ListEips(ctx context.Context) (*v1alpha2.EipList, error) {
+	result := &v1alpha2.EipList{}
+	return result, c.do(ctx, http.MethodGet, eipPath, nil, "", nil, result)
+}
+
+// PatchEip patches the Eip with a merge patch.
+func (c *Client) PatchEip(ctx context.Context, name string, patch []byte) (*Update, error) {
+	result := &Update{}
+	return result, c.do(ctx, http.MethodPatch, namedPath(eipPath, name), nil, mergePatch, patch, result)
+}
+
+// UpdateEip updates the Eip, the current resourceVersion is used if
+// it's not set.
+func (c *Client) UpdateEip(ctx context.Context, name string, eip *v1alpha2.Eip) (*Update, error) {
+	result := &Update{}
+	return result, c.do(ctx, http.MethodPut, namedPath(eipPath, name), nil, "", eip, result)
+}
+
+// DeleteEip deletes the Eip.
+func (c *Client) DeleteEip(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, namedPath(eipPath, name), nil, "", nil, nil)
+}
+
+// DryRunEip validates the change of the Eip without persisting it and
+// reports its impact. The Eip is ignored for a patch or a deletion, the
+// patch is ignored otherwise.
+func (c *Client) DryRunEip(ctx context.Context, op Operation, name string,
+	eip *v1alpha2.Eip, patch []byte) (*DryRunResult[EipImpact], error) {
+	path := eipPath
+	if op != OperationCreate {
+		path = namedPath(path, name)
+	}
+	result := &DryRunResult[EipImpact]{}
+	return result, c.dryRun(ctx, op, path, eip, patch, result)
+}
+
+// CreateBgpPeer creates a BgpPeer.
+func (c *Client) CreateBgpPeer(ctx context.Context, bgpPeer *v1alpha2.BgpPeer) (*Create, error) {
+	result := &Create{}
+	return result, c.do(ctx, http.MethodPost, bgpPeerPath, nil, "", bgpPeer, result)
+}
+
+// GetBgpPeer returns the BgpPeer.
+func (c *Client) GetBgpPeer(ctx context.Context, name string) (*v1alpha2.BgpPeer, error) {
+	result := &v1alpha2.BgpPeer{}
+	return result, c.do(ctx, http.MethodGet, namedPath(bgpPeerPath, name), nil, "", nil, result)
+}
+
+// ListBgpPeers returns all the BgpPeers.
+func (c *Client) ListBgpPeers(ctx context.Context) (*v1alpha2.BgpPeerList, error) {
+	result := &v1alpha2.BgpPeerList{}
+	return result, c.do(ctx, http.MethodGet, bgpPeerPath, nil, "", nil, result)
+}
+
+// PatchBgpPeer patches the BgpPeer with a merge patch.
+func (c *Client) PatchBgpPeer(ctx context.Context, name string, patch []byte) (*Update, error) {
+	result := &Update{}
+	return result, c.do(ctx, http.MethodPatch, namedPath(bgpPeerPath, name), nil, mergePatch, patch, result)
+}
+
+// UpdateBgpPeer updates the BgpPeer, the current resourceVersion is used if
+// it's not set.
+func (c *Client) UpdateBgpPeer(ctx context.Context, name string, bgpPeer *v1alpha2.BgpPeer) (*Update, error) {
+	result := &Update{}
+	return result, c.do(ctx, http.MethodPut, namedPath(bgpPeerPath, name), nil, "", bgpPeer, result)
+}
+
+// DeleteBgpPeer deletes the BgpPeer.
+func (c *Client) DeleteBgpPeer(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, namedPath(bgpPeerPath, name), nil, "", nil, nil)
+}
+
+// DryRunBgpPeer validates the change of the BgpPeer without persisting it and
+// reports its impact. The BgpPeer is ignored for a patch or a deletion, the
+// patch is ignored otherwise.
+func (c *Client) DryRunBgpPeer(ctx context.Context, op Operation, name string,
+	bgpPeer *v1alpha2.BgpPeer, patch []byte) (*DryRunResult[BgpPeerImpact], error) {
+	path := bgpPeerPath
+	if op != OperationCreate {
+		path = namedPath(path, name)
+	}
+	result := &DryRunResult[BgpPeerImpact]{}
+	return result, c.dryRun(ctx, op, path, bgpPeer, patch, result)
+}
+
+// CreateBgpConf creates the BgpConf.
+func (c *Client) CreateBgpConf(ctx context.Context, bgpConf *v1alpha2.BgpConf) (*Create, error) {
+	result := &Create{}
+	return result, c.do(ctx, http.MethodPost, bgpConfPath, nil, "", bgpConf, result)
+}
+
+// GetBgpConf returns the BgpConf.
+func (c *Client) GetBgpConf(ctx context.Context) (*v1alpha2.BgpConf, error) {
+	result := &v1alpha2.BgpConf{}
+	return result, c.do(ctx, http.MethodGet, bgpConfPath, nil, "", nil, result)
+}
+
+// PatchBgpConf patches the BgpConf with a merge patch.
+func (c *Client) PatchBgpConf(ctx context.Context, patch []byte) (*Update, error) {
+	result := &Update{}
+	return result, c.do(ctx, http.MethodPatch, bgpConfPath, nil, mergePatch, patch, result)
+}
+
+// UpdateBgpConf updates the BgpConf.
+func (c *Client) UpdateBgpConf(ctx context.Context, bgpConf *v1alpha2.BgpConf) (*Update, error) {
+	result := &Update{}
+	return result, c.do(ctx, http.MethodPut, bgpConfPath, nil, "", bgpConf, result)
+}
+
+// DeleteBgpConf deletes the BgpConf.
+func (c *Client) DeleteBgpConf(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, bgpConfPath, nil, "", nil, nil)
+}
+
+// ListAllocations returns the addresses allocated from the Eips filtered by
+// namespace, Eip and protocol.
+func (c *Client) ListAllocations(ctx context.Context, opts ListOptions) (*List[Allocation], error) {
+	result := &List[Allocation]{}
+	return result, c.do(ctx, http.MethodGet, allocationsPath, listQuery(opts), "", nil, result)
+}
+
+// ListServices returns the services exposed by the Eips filtered by
+// namespace, Eip and protocol.
+func (c *Client) ListServices(ctx context.Context, opts ListOptions) (*List[Service], error) {
+	result := &List[Service]{}
+	return result, c.do(ctx, http.MethodGet, servicesPath, listQuery(opts), "", nil, result)
+}
+
+// ListBgpSessions returns the sessions of the BgpPeers filtered by node.
+func (c *Client) ListBgpSessions(ctx context.Context, opts ListOptions) (*List[BgpSession], error) {
+	result := &List[BgpSession]{}
+	return result, c.do(ctx, http.MethodGet, bgpSessionsPath, listQuery(opts), "", nil, result)
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/server/internal/lib"
+)
+
+type EventType = lib.EventType
+
+const (
+	Added    = lib.Added
+	Modified = lib.Modified
+	Deleted  = lib.Deleted
+)
+
+// Event is a change of an object received from a watch.
+type Event[T any] struct {
+	Type   EventType `json:"type"`
+	Object T         `json:"object"`
+	// ResourceVersion is the id of the event, or of the last one with an id
+	// like the EventSource, it resumes the watch after the event
+	ResourceVersion string `json:"-"`
+}
+
+// Stream reads the server-sent events of a watch.
+type Stream[T any] struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	// id is the last event id received
+	id string
+}
+
+// Next returns the next event, it blocks until an event is received. io.EOF
+// is returned when the apiserver closes the stream, the watch has to resume
+// from the resourceVersion of the last event.
+func (s *Stream[T]) Next() (*Event[T], error) {
+	var data string
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if data == "" {
+				continue
+			}
+			event := &Event[T]{ResourceVersion: s.id}
+			return event, json.Unmarshal([]byte(data), event)
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			s.id = value
+		case "data":
+			data += value
+		}
+	}
+}
+
+// Close closes the stream.
+func (s *Stream[T]) Close() error {
+	return s.body.Close()
+}
+
+// watch returns the stream of the events at the path after the
+// resourceVersion, the current objects are sent first if it's empty. An
+// expired resourceVersion returns a Gone error, list again.
+func watch[T any](ctx context.Context, c *Client, path, resourceVersion string) (*Stream[T], error) {
+	query := url.Values{}
+	if resourceVersion != "" {
+		query.Set("resourceVersion", resourceVersion)
+	}
+	resp, err := c.send(ctx, http.MethodGet, path, query, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream[T]{body: resp.Body, reader: bufio.NewReader(resp.Body)}, nil
+}
+
+// WatchEips watches the changes of the Eips.
+func (c *Client) WatchEips(ctx context.Context, resourceVersion string) (*Stream[v1alpha2.Eip], error) {
+	return watch[v1alpha2.Eip](ctx, c, "/apis/v1/watch/eip", resourceVersion)
+}
+
+// WatchBgpPeers watches the changes of the BgpPeers.
+func (c *Client) WatchBgpPeers(ctx context.Context, resourceVersion string) (*Stream[v1alpha2.BgpPeer], error) {
+	return watch[v1alpha2.BgpPeer](ctx, c, "/apis/v1/watch/bgp", resourceVersion)
+}
+
+// WatchBgpConfs watches the changes of the BgpConfs.
+func (c *Client) WatchBgpConfs(ctx context.Context, resourceVersion string) (*Stream[v1alpha2.BgpConf], error) {
+	return watch[v1alpha2.BgpConf](ctx, c, "/apis/v1/watch/bgp/conf", resourceVersion)
+}
+
+// WatchAllocations watches the changes of the addresses allocated from the
+// Eips.
+func (c *Client) WatchAllocations(ctx context.Context, resourceVersion string) (*Stream[Allocation], error) {
+	return watch[Allocation](ctx, c, "/apis/v1/watch/allocations", resourceVersion)
+}
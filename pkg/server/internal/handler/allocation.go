@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Allocation is an IP address of an Eip held by services.
+type Allocation struct {
+	IP  string `json:"ip"`
+	Eip string `json:"eip"`
+	// Protocol is the comma separated protocols of the Eip
+	Protocol string `json:"protocol"`
+	// Services are the namespace/name of the services sharing the address
+	Services []string `json:"services"`
+	// Node announcing the address, only known for the layer2 speaker
+	// with the lease ownership backend
+	Node string `json:"node,omitempty"`
+}
+
+// AllocationHandler is an interface that is used to manage http requests
+// related to the IP addresses allocated from the Eips.
+type AllocationHandler interface {
+	// List returns the allocations filtered by namespace, Eip and protocol.
+	List(ctx context.Context, opts ListOptions) (*List, error)
+}
+
+// allocationHandler is an implementation of the AllocationHandler.
+type allocationHandler struct {
+	client     client.Client
+	kubeClient kubernetes.Interface
+}
+
+// NewAllocationHandler returns a new instance of allocationHandler which
+// implements the AllocationHandler interface. This is used to register the
+// endpoints to the router.
+func NewAllocationHandler(client client.Client, kubeClient kubernetes.Interface) *allocationHandler {
+	return &allocationHandler{
+		client:     client,
+		kubeClient: kubeClient,
+	}
+}
+
+// List returns the allocations filtered by namespace, Eip and protocol.
+func (a *allocationHandler) List(ctx context.Context, opts ListOptions) (*List, error) {
+	eipList := &v1alpha2.EipList{}
+	if err := a.client.List(ctx, eipList); err != nil {
+		return nil, err
+	}
+	nodes := announcingNodes(ctx, a.kubeClient)
+
+	allocations := []Allocation{}
+	for _, eip := range eipList.Items {
+		if !matchEip(eip, opts) {
+			continue
+		}
+		for ip, used := range eip.Status.Used {
+			allocation := newAllocation(&eip, ip, used)
+			if opts.Namespace != "" && !inNamespace(allocation.Services, opts.Namespace) {
+				continue
+			}
+			allocation.Node = nodes[ip]
+			allocations = append(allocations, allocation)
+		}
+	}
+
+	return paginate(allocations, func(a Allocation) string {
+		return a.Eip + "/" + a.IP
+	}, opts)
+}
+
+// newAllocation returns the allocation of the address from the Used status
+// of the Eip.
+func newAllocation(eip *v1alpha2.Eip, ip, used string) Allocation {
+	return Allocation{
+		IP:       ip,
+		Eip:      eip.Name,
+		Protocol: strings.Join(eip.GetProtocols(), ","),
+		Services: strings.Split(used, ";"),
+	}
+}
+
+func matchEip(eip v1alpha2.Eip, opts ListOptions) bool {
+	if opts.Eip != "" && eip.Name != opts.Eip {
+		return false
+	}
+	return opts.Protocol == "" || eip.HasProtocol(opts.Protocol)
+}
+
+func inNamespace(services []string, namespace string) bool {
+	for _, svc := range services {
+		if strings.HasPrefix(svc, namespace+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// announcingNodes returns the nodes holding the layer2 leases by address.
+// The view is best effort, the nodes are unknown if the leases can't be read.
+func announcingNodes(ctx context.Context, kubeClient kubernetes.Interface) map[string]string {
+	nodes := map[string]string{}
+	if kubeClient == nil {
+		return nodes
+	}
+
+	leases, err := kubeClient.CoordinationV1().Leases(util.EnvNamespace()).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Warningf("list layer2 leases error: %v", err)
+		return nodes
+	}
+
+	now := time.Now()
+	for _, lease := range leases.Items {
+		ip, ok := lease.Annotations[constant.OpenELBLayer2LeaseIPAnnotation]
+		if !ok || lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" {
+			continue
+		}
+		if lease.Spec.RenewTime != nil && lease.Spec.LeaseDurationSeconds != nil &&
+			lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds)*time.Second).Before(now) {
+			continue
+		}
+		nodes[ip] = *lease.Spec.HolderIdentity
+	}
+	return nodes
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/util"
+	"github.com/stretchr/testify/assert"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestClient(objs ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = v1alpha2.AddToScheme(scheme)
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func newTestLease(ip, holder string, renew time.Time) *coordinationv1.Lease {
+	seconds := int32(15)
+	renewTime := metav1.NewMicroTime(renew)
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        constant.Layer2LeasePrefix + ip,
+			Namespace:   util.EnvNamespace(),
+			Annotations: map[string]string{constant.OpenELBLayer2LeaseIPAnnotation: ip},
+		},
+		Spec: coordinationv1.LeaseSpec{HolderIdentity: &holder, LeaseDurationSeconds: &seconds, RenewTime: &renewTime},
+	}
+}
+
+func TestAllocationHandler_List(t *testing.T) {
+	c := newTestClient(
+		&v1alpha2.Eip{
+			ObjectMeta: metav1.ObjectMeta{Name: "bgp-eip"},
+			Spec:       v1alpha2.EipSpec{Address: "192.168.0.0/24"},
+			Status: v1alpha2.EipStatus{Used: map[string]string{
+				"192.168.0.1": "default/svc1;test/svc2",
+				"192.168.0.2": "test/svc3",
+			}},
+		},
+		&v1alpha2.Eip{
+			ObjectMeta: metav1.ObjectMeta{Name: "layer2-eip"},
+			Spec:       v1alpha2.EipSpec{Address: "10.0.0.0/24", Protocol: constant.OpenELBProtocolLayer2},
+			Status: v1alpha2.EipStatus{Used: map[string]string{
+				"10.0.0.1": "default/svc4",
+				"10.0.0.2": "default/svc5",
+			}},
+		},
+	)
+	kubeClient := kubefake.NewSimpleClientset(
+		newTestLease("10.0.0.1", "node1", time.Now()),
+		newTestLease("10.0.0.2", "node2", time.Now().Add(-time.Hour)),
+	)
+	h := NewAllocationHandler(c, kubeClient)
+	ctx := context.Background()
+
+	list, err := h.List(ctx, ListOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []Allocation{
+		{IP: "192.168.0.1", Eip: "bgp-eip", Protocol: constant.OpenELBProtocolBGP, Services: []string{"default/svc1", "test/svc2"}},
+		{IP: "192.168.0.2", Eip: "bgp-eip", Protocol: constant.OpenELBProtocolBGP, Services: []string{"test/svc3"}},
+		{IP: "10.0.0.1", Eip: "layer2-eip", Protocol: constant.OpenELBProtocolLayer2, Services: []string{"default/svc4"}, Node: "node1"},
+		{IP: "10.0.0.2", Eip: "layer2-eip", Protocol: constant.OpenELBProtocolLayer2, Services: []string{"default/svc5"}},
+	}, list.Items)
+	assert.Empty(t, list.Continue)
+
+	list, err = h.List(ctx, ListOptions{Namespace: "test"})
+	assert.NoError(t, err)
+	assert.Len(t, list.Items, 2)
+
+	list, err = h.List(ctx, ListOptions{Protocol: constant.OpenELBProtocolLayer2})
+	assert.NoError(t, err)
+	assert.Len(t, list.Items, 2)
+
+	list, err = h.List(ctx, ListOptions{Eip: "bgp-eip", Namespace: "default"})
+	assert.NoError(t, err)
+	assert.Len(t, list.Items, 1)
+
+	// pagination
+	var ips []string
+	opts := ListOptions{Limit: 3}
+	for {
+		list, err := h.List(ctx, opts)
+		assert.NoError(t, err)
+		for _, a := range list.Items.([]Allocation) {
+			ips = append(ips, a.IP)
+		}
+		if list.Continue == "" {
+			break
+		}
+		opts.Continue = list.Continue
+	}
+	assert.Equal(t, []string{"192.168.0.1", "192.168.0.2", "10.0.0.1", "10.0.0.2"}, ips)
+
+	_, err = h.List(ctx, ListOptions{Continue: "!"})
+	assert.Error(t, err)
+}
+
+func TestBgpSessionHandler_List(t *testing.T) {
+	c := newTestClient(&v1alpha2.BgpPeer{
+		ObjectMeta: metav1.ObjectMeta{Name: "peer1"},
+		Status: v1alpha2.BgpPeerStatus{NodesPeerStatus: map[string]v1alpha2.NodePeerStatus{
+			"node1": {PeerState: v1alpha2.PeerState{NeighborAddress: "10.0.0.254", SessionState: "ESTABLISHED", AuthPassword: "secret"}},
+			"node2": {PeerState: v1alpha2.PeerState{NeighborAddress: "10.0.0.254", SessionState: "ACTIVE"}},
+		}},
+	})
+	h := NewBgpSessionHandler(c)
+
+	list, err := h.List(context.Background(), ListOptions{Node: "node1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []BgpSession{{Node: "node1", Peer: "peer1", NeighborAddress: "10.0.0.254", SessionState: "ESTABLISHED"}}, list.Items)
+}
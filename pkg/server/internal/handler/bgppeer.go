@@ -2,8 +2,12 @@ package handler
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/openelb/openelb/api/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -23,6 +27,9 @@ type BgpPeerHandler interface {
 	Update(ctx context.Context, name string, newObj *v1alpha2.BgpPeer) (Update, error)
 	// Delete deletes the BgpPeer object in the kubernetes cluster.
 	Delete(ctx context.Context, name string) (Delete, error)
+	// DryRun validates the change of the BgpPeer without persisting it and
+	// reports the nodes peering with the neighbor.
+	DryRun(ctx context.Context, op Operation, name string, bgpPeer *v1alpha2.BgpPeer, patch []byte) (*DryRun, error)
 }
 
 // bgpPeerHandler is an implementation of the BgpPeerHandler.
@@ -105,3 +112,83 @@ func (b *bgpPeerHandler) Delete(ctx context.Context, name string) (Delete, error
 	}
 	return Delete{Deleted: true}, nil
 }
+
+// BgpPeerImpact is the impact of a change of a BgpPeer.
+type BgpPeerImpact struct {
+	// Nodes are the nodes peering with the neighbor after the change
+	Nodes []string `json:"nodes,omitempty"`
+	// Removed are the nodes whose session is closed by the change
+	Removed []string `json:"removed,omitempty"`
+}
+
+// DryRun validates the change of the BgpPeer without persisting it and
+// reports its impact. The BgpPeer is ignored for a patch or a deletion.
+func (b *bgpPeerHandler) DryRun(ctx context.Context, op Operation, name string,
+	bgpPeer *v1alpha2.BgpPeer, patch []byte) (*DryRun, error) {
+	current := &v1alpha2.BgpPeer{}
+	if op != OperationCreate {
+		var err error
+		if current, err = b.Get(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+	switch op {
+	case OperationPatch:
+		bgpPeer = &v1alpha2.BgpPeer{}
+		if err := applyPatch(current, patch, bgpPeer); err != nil {
+			return nil, err
+		}
+	case OperationDelete:
+		bgpPeer = nil
+	}
+
+	result := &DryRun{}
+	if err := result.reject(dryRunChange(ctx, b.client, op, current, bgpPeer, patch)); err != nil {
+		return nil, err
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := b.client.List(ctx, nodes); err != nil {
+		return nil, err
+	}
+	impact := &BgpPeerImpact{}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		peering := false
+		if bgpPeer != nil {
+			match, err := peerMatchNode(bgpPeer, node)
+			if err != nil {
+				result.Errors = append(result.Errors, err.Error())
+				return result.done(), nil
+			}
+			peering = match
+		}
+		if peering {
+			impact.Nodes = append(impact.Nodes, node.Name)
+		} else if _, ok := current.Status.NodesPeerStatus[node.Name]; ok {
+			impact.Removed = append(impact.Removed, node.Name)
+		}
+	}
+
+	if bgpPeer != nil {
+		// The speakers add the peer to gobgp from the converted spec
+		if _, err := bgpPeer.Spec.ToGoBgpPeer(); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("invalid BgpPeer spec: %v", err))
+		}
+	}
+
+	result.Impact = impact
+	return result.done(), nil
+}
+
+// peerMatchNode returns true if the node is selected by the BgpPeer.
+func peerMatchNode(peer *v1alpha2.BgpPeer, node *corev1.Node) (bool, error) {
+	if peer.Spec.NodeSelector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(peer.Spec.NodeSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid nodeSelector: %v", err)
+	}
+	return selector.Matches(labels.Set(node.Labels)), nil
+}
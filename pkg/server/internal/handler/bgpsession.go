@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// BgpSession is the state of the session of a BgpPeer on a node. The
+// credentials of the peer are never returned.
+type BgpSession struct {
+	Node            string `json:"node"`
+	Peer            string `json:"peer"`
+	NeighborAddress string `json:"neighborAddress,omitempty"`
+	PeerAs          uint32 `json:"peerAs,omitempty"`
+	LocalAs         uint32 `json:"localAs,omitempty"`
+	SessionState    string `json:"sessionState,omitempty"`
+	AdminState      string `json:"adminState,omitempty"`
+	Flops           uint32 `json:"flops,omitempty"`
+	Uptime          string `json:"uptime,omitempty"`
+	Downtime        string `json:"downtime,omitempty"`
+}
+
+// BgpSessionHandler is an interface that is used to manage http requests
+// related to the BGP sessions of the speakers.
+type BgpSessionHandler interface {
+	// List returns the sessions reported in the status of the BgpPeers,
+	// filtered by node.
+	List(ctx context.Context, opts ListOptions) (*List, error)
+}
+
+// bgpSessionHandler is an implementation of the BgpSessionHandler.
+type bgpSessionHandler struct {
+	client client.Client
+}
+
+// NewBgpSessionHandler returns a new instance of bgpSessionHandler which
+// implements the BgpSessionHandler interface. This is used to register the
+// endpoints to the router.
+func NewBgpSessionHandler(client client.Client) *bgpSessionHandler {
+	return &bgpSessionHandler{
+		client: client,
+	}
+}
+
+// List returns the sessions reported in the status of the BgpPeers, filtered
+// by node.
+func (b *bgpSessionHandler) List(ctx context.Context, opts ListOptions) (*List, error) {
+	peerList := &v1alpha2.BgpPeerList{}
+	if err := b.client.List(ctx, peerList); err != nil {
+		return nil, err
+	}
+
+	sessions := []BgpSession{}
+	for _, peer := range peerList.Items {
+		for node, status := range peer.Status.NodesPeerStatus {
+			if opts.Node != "" && node != opts.Node {
+				continue
+			}
+			sessions = append(sessions, BgpSession{
+				Node:            node,
+				Peer:            peer.Name,
+				NeighborAddress: status.PeerState.NeighborAddress,
+				PeerAs:          status.PeerState.PeerAs,
+				LocalAs:         status.PeerState.LocalAs,
+				SessionState:    status.PeerState.SessionState,
+				AdminState:      status.PeerState.AdminState,
+				Flops:           status.PeerState.Flops,
+				Uptime:          status.TimersState.Uptime,
+				Downtime:        status.TimersState.Downtime,
+			})
+		}
+	}
+
+	return paginate(sessions, func(s BgpSession) string {
+		return s.Node + "/" + s.Peer
+	}, opts)
+}
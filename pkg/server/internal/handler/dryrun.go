@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Operation is the operation of a change checked by a dry run.
+type Operation string
+
+const (
+	OperationCreate Operation = "create"
+	OperationUpdate Operation = "update"
+	OperationPatch  Operation = "patch"
+	OperationDelete Operation = "delete"
+)
+
+// DryRun is the result of a change validated without being persisted.
+type DryRun struct {
+	// Valid is true if the change would be accepted
+	Valid bool `json:"valid"`
+	// Errors are the reasons the change would be rejected
+	Errors []string `json:"errors,omitempty"`
+	// Impact is the impact of the change on the cluster
+	Impact interface{} `json:"impact,omitempty"`
+}
+
+// reject records the rejection of the change by the validation, the errors
+// that don't come from the validation are returned.
+func (d *DryRun) reject(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case apierrors.IsInvalid(err) || apierrors.IsForbidden(err) || apierrors.IsBadRequest(err) ||
+		apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err):
+		d.Errors = append(d.Errors, err.Error())
+		return nil
+	default:
+		return err
+	}
+}
+
+func (d *DryRun) done() *DryRun {
+	d.Valid = len(d.Errors) == 0
+	return d
+}
+
+// dryRunChange sends the change to the apiserver in dry run mode, so it goes
+// through the admission webhooks without being persisted. The current object
+// is nil for a creation.
+func dryRunChange(ctx context.Context, c client.Client, op Operation, current, obj client.Object, patch []byte) error {
+	switch op {
+	case OperationCreate:
+		return c.Create(ctx, obj, client.DryRunAll)
+	case OperationUpdate:
+		if obj.GetResourceVersion() == "" {
+			obj.SetResourceVersion(current.GetResourceVersion())
+		}
+		return c.Update(ctx, obj, client.DryRunAll)
+	case OperationPatch:
+		return c.Patch(ctx, current.DeepCopyObject().(client.Object), client.RawPatch(types.MergePatchType, patch), client.DryRunAll)
+	case OperationDelete:
+		return c.Delete(ctx, current, client.DryRunAll)
+	}
+	return apierrors.NewBadRequest(fmt.Sprintf("unknown operation %s", op))
+}
+
+// applyPatch applies the merge patch to the current object into obj, the
+// apiserver doesn't return the object of a rejected change.
+func applyPatch(current client.Object, patch []byte, obj client.Object) error {
+	original, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	patched, err := jsonpatch.MergePatch(original, patch)
+	if err != nil {
+		return apierrors.NewBadRequest(fmt.Sprintf("invalid merge patch: %v", err))
+	}
+	if err := json.Unmarshal(patched, obj); err != nil {
+		return apierrors.NewBadRequest(fmt.Sprintf("invalid merge patch: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEipHandler_DryRun(t *testing.T) {
+	c := newTestClient(
+		&v1alpha2.Eip{
+			ObjectMeta: metav1.ObjectMeta{Name: "eip-1"},
+			Spec:       v1alpha2.EipSpec{Address: "192.168.0.0/24"},
+			Status: v1alpha2.EipStatus{Used: map[string]string{
+				"192.168.0.1":   "default/svc1",
+				"192.168.0.200": "default/svc2",
+			}},
+		},
+		&v1alpha2.Eip{
+			ObjectMeta: metav1.ObjectMeta{Name: "eip-2"},
+			Spec:       v1alpha2.EipSpec{Address: "192.168.1.0/24"},
+		},
+	)
+	h := NewEipHandler(c)
+	ctx := context.Background()
+
+	// the services out of the shrunk range keep their addresses by default
+	result, err := h.DryRun(ctx, OperationPatch, "eip-1", nil, []byte(`{"spec":{"address":"192.168.0.0/25"}}`))
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, &EipImpact{Kept: []Allocation{
+		{IP: "192.168.0.200", Eip: "eip-1", Protocol: "bgp", Services: []string{"default/svc2"}},
+	}}, result.Impact)
+
+	// growing it overlaps eip-2
+	eip, _ := h.Get(ctx, "eip-1")
+	eip.Spec.Address = "192.168.0.0/23"
+	result, err = h.DryRun(ctx, OperationUpdate, "eip-1", eip, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &EipImpact{Overlaps: []string{"eip-2"}}, result.Impact)
+
+	result, err = h.DryRun(ctx, OperationCreate, "", &v1alpha2.Eip{
+		ObjectMeta: metav1.ObjectMeta{Name: "eip-3"},
+		Spec:       v1alpha2.EipSpec{Address: "192.168.0.10-192.168.0.1"},
+	}, nil)
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Len(t, result.Errors, 1)
+
+	result, err = h.DryRun(ctx, OperationDelete, "eip-1", nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, result.Impact.(*EipImpact).Released, 2)
+
+	_, err = h.DryRun(ctx, OperationPatch, "eip-1", nil, []byte(`{`))
+	assert.Error(t, err)
+
+	// nothing is persisted
+	eip, _ = h.Get(ctx, "eip-1")
+	assert.Equal(t, "192.168.0.0/24", eip.Spec.Address)
+}
+
+func TestEipHandler_DryRunShrinkPolicy(t *testing.T) {
+	c := newTestClient(
+		&v1alpha2.Eip{
+			ObjectMeta: metav1.ObjectMeta{Name: "eip-1"},
+			Spec:       v1alpha2.EipSpec{Address: "192.168.0.0/24"},
+			Status: v1alpha2.EipStatus{Used: map[string]string{
+				"192.168.0.1":   "default/svc1",
+				"192.168.0.200": "default/svc2;default/svc3",
+				"192.168.0.201": "default/svc4",
+			}},
+		},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "svc2"}},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "svc3"},
+			Spec:       corev1.ServiceSpec{LoadBalancerIP: "192.168.0.200"},
+		},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "svc4"}},
+	)
+	h := NewEipHandler(c)
+	ctx := context.Background()
+
+	result, err := h.DryRun(ctx, OperationPatch, "eip-1", nil, []byte(`{"metadata":{"annotations":{"eip.openelb.kubesphere.io/shrink-policy":"Keep"}},"spec":{"address":"192.168.0.0/25"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &EipImpact{Kept: []Allocation{
+		{IP: "192.168.0.200", Eip: "eip-1", Protocol: "bgp", Services: []string{"default/svc2", "default/svc3"}},
+		{IP: "192.168.0.201", Eip: "eip-1", Protocol: "bgp", Services: []string{"default/svc4"}},
+	}}, result.Impact)
+
+	// the services specifying the address are kept under the Reassign policy
+	result, err = h.DryRun(ctx, OperationPatch, "eip-1", nil, []byte(`{"metadata":{"annotations":{"eip.openelb.kubesphere.io/shrink-policy":"Reassign"}},"spec":{"address":"192.168.0.0/25"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &EipImpact{
+		Kept: []Allocation{
+			{IP: "192.168.0.200", Eip: "eip-1", Protocol: "bgp", Services: []string{"default/svc3"}},
+		},
+		Reassigned: []Allocation{
+			{IP: "192.168.0.200", Eip: "eip-1", Protocol: "bgp", Services: []string{"default/svc2"}},
+			{IP: "192.168.0.201", Eip: "eip-1", Protocol: "bgp", Services: []string{"default/svc4"}},
+		},
+		ReassignTarget: "eip-1",
+	}, result.Impact)
+}
+
+func TestBgpPeerHandler_DryRun(t *testing.T) {
+	c := newTestClient(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"rack": "a"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"rack": "b"}}},
+		&v1alpha2.BgpPeer{
+			ObjectMeta: metav1.ObjectMeta{Name: "peer1"},
+			Spec:       v1alpha2.BgpPeerSpec{Conf: &v1alpha2.PeerConf{NeighborAddress: "10.0.0.254", PeerAs: 65001}},
+			Status: v1alpha2.BgpPeerStatus{NodesPeerStatus: map[string]v1alpha2.NodePeerStatus{
+				"node1": {}, "node2": {},
+			}},
+		},
+	)
+	h := NewBgpPeerHandler(c)
+	ctx := context.Background()
+
+	result, err := h.DryRun(ctx, OperationPatch, "peer1", nil, []byte(`{"spec":{"nodeSelector":{"matchLabels":{"rack":"a"}}}}`))
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, &BgpPeerImpact{Nodes: []string{"node1"}, Removed: []string{"node2"}}, result.Impact)
+
+	result, err = h.DryRun(ctx, OperationPatch, "peer1", nil, []byte(`{"spec":{"timers":{"config":{"holdTime":"abc"}}}}`))
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Errors[0], "invalid BgpPeer spec")
+
+	result, err = h.DryRun(ctx, OperationDelete, "peer1", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &BgpPeerImpact{Removed: []string{"node1", "node2"}}, result.Impact)
+}
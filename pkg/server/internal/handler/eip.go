@@ -2,8 +2,13 @@ package handler
 
 import (
 	"context"
+	"net"
+	"sort"
+	"strings"
 
 	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/constant"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -23,6 +28,9 @@ type EipHandler interface {
 	Update(ctx context.Context, name string, newObj *v1alpha2.Eip) (Update, error)
 	// Delete deletes the Eip object in the kubernetes cluster.
 	Delete(ctx context.Context, name string) (Delete, error)
+	// DryRun validates the change of the Eip without persisting it and
+	// reports the allocations released and the Eips overlapped.
+	DryRun(ctx context.Context, op Operation, name string, eip *v1alpha2.Eip, patch []byte) (*DryRun, error)
 }
 
 // eipHandler is an implementation of the EipHandler.
@@ -105,3 +113,133 @@ func (e *eipHandler) Delete(ctx context.Context, name string) (Delete, error) {
 	}
 	return Delete{Deleted: true}, nil
 }
+
+// EipImpact is the impact of a change of an Eip.
+type EipImpact struct {
+	// Released are the allocations of the deleted Eip, the services lose
+	// their address
+	Released []Allocation `json:"released,omitempty"`
+	// Kept are the allocations out of the new address range kept by the
+	// services, under the Keep shrink policy or specifying the address
+	Kept []Allocation `json:"kept,omitempty"`
+	// Reassigned are the allocations out of the new address range
+	// reassigned from the ReassignTarget under the Reassign shrink policy
+	Reassigned     []Allocation `json:"reassigned,omitempty"`
+	ReassignTarget string       `json:"reassignTarget,omitempty"`
+	// Overlaps are the Eips overlapping the new address range
+	Overlaps []string `json:"overlaps,omitempty"`
+}
+
+// DryRun validates the change of the Eip without persisting it and reports
+// its impact. The Eip is ignored for a patch or a deletion.
+func (e *eipHandler) DryRun(ctx context.Context, op Operation, name string,
+	eip *v1alpha2.Eip, patch []byte) (*DryRun, error) {
+	current := &v1alpha2.Eip{}
+	if op != OperationCreate {
+		var err error
+		if current, err = e.Get(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+	switch op {
+	case OperationPatch:
+		eip = &v1alpha2.Eip{}
+		if err := applyPatch(current, patch, eip); err != nil {
+			return nil, err
+		}
+	case OperationDelete:
+		eip = nil
+	}
+
+	result := &DryRun{}
+	if err := result.reject(dryRunChange(ctx, e.client, op, current, eip, patch)); err != nil {
+		return nil, err
+	}
+
+	impact := &EipImpact{}
+	for ip, used := range current.Status.Used {
+		if eip == nil {
+			impact.Released = append(impact.Released, newAllocation(current, ip, used))
+			continue
+		}
+		if !eip.Contains(net.ParseIP(ip)) {
+			if err := e.shrinkImpact(ctx, impact, current, eip, ip, used); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, allocations := range [][]Allocation{impact.Released, impact.Kept, impact.Reassigned} {
+		sort.Slice(allocations, func(i, j int) bool {
+			return allocations[i].IP < allocations[j].IP
+		})
+	}
+
+	if eip != nil {
+		if _, _, err := eip.GetSize(); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return result.done(), nil
+		}
+		eips, err := e.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range eips.Items {
+			if other.Name != eip.Name && eip.IsOverlap(other) {
+				impact.Overlaps = append(impact.Overlaps, other.Name)
+			}
+		}
+	}
+
+	result.Impact = impact
+	return result.done(), nil
+}
+
+// shrinkImpact reports the services of ip out of the range of the shrunk Eip
+// as the ipam controller handles them, they're kept unless the shrink policy
+// is Reassign, and the services specifying the address are always kept.
+func (e *eipHandler) shrinkImpact(ctx context.Context, impact *EipImpact,
+	current, eip *v1alpha2.Eip, ip, used string) error {
+	if eip.Annotations[constant.OpenELBEIPAnnotationShrinkPolicy] != constant.OpenELBEipShrinkPolicyReassign {
+		impact.Kept = append(impact.Kept, newAllocation(current, ip, used))
+		return nil
+	}
+
+	impact.ReassignTarget = eip.Name
+	if eip.Annotations[constant.OpenELBEIPAnnotationShrinkTarget] != "" {
+		impact.ReassignTarget = eip.Annotations[constant.OpenELBEIPAnnotationShrinkTarget]
+	}
+	kept, reassigned := []string{}, []string{}
+	for _, name := range strings.Split(used, ";") {
+		specified, err := e.specifyAddress(ctx, name)
+		if err != nil {
+			return err
+		}
+		if specified {
+			kept = append(kept, name)
+		} else {
+			reassigned = append(reassigned, name)
+		}
+	}
+	if len(kept) != 0 {
+		impact.Kept = append(impact.Kept, newAllocation(current, ip, strings.Join(kept, ";")))
+	}
+	if len(reassigned) != 0 {
+		impact.Reassigned = append(impact.Reassigned, newAllocation(current, ip, strings.Join(reassigned, ";")))
+	}
+	return nil
+}
+
+// specifyAddress returns true if the service specifies its address, by the
+// spec or the annotation.
+func (e *eipHandler) specifyAddress(ctx context.Context, name string) (bool, error) {
+	namespace, svcName, found := strings.Cut(name, "/")
+	if !found {
+		return false, nil
+	}
+
+	svc := &corev1.Service{}
+	if err := e.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: svcName}, svc); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return svc.Spec.LoadBalancerIP != "" || svc.Annotations[constant.OpenELBEIPAnnotationKey] != "", nil
+}
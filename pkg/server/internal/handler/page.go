@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"encoding/base64"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// paginate sorts the items by key and returns the page after the continue
+// token. The token is the key of the last item returned, so that the pages
+// stay consistent when items are added or removed between the requests.
+func paginate[T any](items []T, key func(T) string, opts ListOptions) (*List, error) {
+	if items == nil {
+		items = []T{}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return key(items[i]) < key(items[j])
+	})
+
+	if opts.Continue != "" {
+		last, err := base64.RawURLEncoding.DecodeString(opts.Continue)
+		if err != nil {
+			return nil, errors.NewBadRequest("invalid continue token")
+		}
+		start := sort.Search(len(items), func(i int) bool {
+			return key(items[i]) > string(last)
+		})
+		items = items[start:]
+	}
+
+	list := &List{Items: items}
+	if opts.Limit > 0 && len(items) > opts.Limit {
+		items = items[:opts.Limit]
+		list.Items = items
+		list.Continue = base64.RawURLEncoding.EncodeToString([]byte(key(items[len(items)-1])))
+	}
+	return list, nil
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"strings"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/constant"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ServiceAddress is a load balancer address of a service.
+type ServiceAddress struct {
+	IP string `json:"ip"`
+	// Node announcing the address, only known for the layer2 speaker
+	// with the lease ownership backend
+	Node string `json:"node,omitempty"`
+}
+
+// Service is a service exposed by an Eip.
+type Service struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Eip       string `json:"eip"`
+	// Protocol is the comma separated protocols of the Eip
+	Protocol  string           `json:"protocol,omitempty"`
+	Addresses []ServiceAddress `json:"addresses"`
+}
+
+// ServiceHandler is an interface that is used to manage http requests related
+// to the services exposed by OpenELB.
+type ServiceHandler interface {
+	// List returns the services filtered by namespace, Eip and protocol.
+	List(ctx context.Context, opts ListOptions) (*List, error)
+}
+
+// serviceHandler is an implementation of the ServiceHandler.
+type serviceHandler struct {
+	client     client.Client
+	kubeClient kubernetes.Interface
+}
+
+// NewServiceHandler returns a new instance of serviceHandler which implements
+// the ServiceHandler interface. This is used to register the endpoints to the
+// router.
+func NewServiceHandler(client client.Client, kubeClient kubernetes.Interface) *serviceHandler {
+	return &serviceHandler{
+		client:     client,
+		kubeClient: kubeClient,
+	}
+}
+
+// svcEip returns the Eip of the service, the label set by the controller takes
+// precedence over the annotation set by the user.
+func svcEip(svc *corev1.Service) string {
+	if eip := svc.Labels[constant.OpenELBEIPAnnotationKeyV1Alpha2]; eip != "" {
+		return eip
+	}
+	return svc.Annotations[constant.OpenELBEIPAnnotationKeyV1Alpha2]
+}
+
+// List returns the services filtered by namespace, Eip and protocol.
+func (s *serviceHandler) List(ctx context.Context, opts ListOptions) (*List, error) {
+	eipList := &v1alpha2.EipList{}
+	if err := s.client.List(ctx, eipList); err != nil {
+		return nil, err
+	}
+	eips := map[string]v1alpha2.Eip{}
+	for _, eip := range eipList.Items {
+		eips[eip.Name] = eip
+	}
+
+	svcList := &corev1.ServiceList{}
+	if err := s.client.List(ctx, svcList, client.InNamespace(opts.Namespace)); err != nil {
+		return nil, err
+	}
+	nodes := announcingNodes(ctx, s.kubeClient)
+
+	services := []Service{}
+	for i := range svcList.Items {
+		svc := &svcList.Items[i]
+		name := svcEip(svc)
+		if name == "" || len(svc.Status.LoadBalancer.Ingress) == 0 {
+			continue
+		}
+
+		view := Service{Namespace: svc.Namespace, Name: svc.Name, Eip: name, Addresses: []ServiceAddress{}}
+		if eip, ok := eips[name]; ok {
+			if !matchEip(eip, opts) {
+				continue
+			}
+			view.Protocol = strings.Join(eip.GetProtocols(), ",")
+		} else if opts.Eip != "" || opts.Protocol != "" {
+			continue
+		}
+
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			view.Addresses = append(view.Addresses, ServiceAddress{IP: ingress.IP, Node: nodes[ingress.IP]})
+		}
+		services = append(services, view)
+	}
+
+	return paginate(services, func(s Service) string {
+		return s.Namespace + "/" + s.Name
+	}, opts)
+}
@@ -11,3 +11,26 @@ type Update struct {
 type Delete struct {
 	Deleted bool `json:"deleted"`
 }
+
+// ListOptions filters and paginates the read-only views.
+type ListOptions struct {
+	// Namespace of the services
+	Namespace string
+	// Eip is the name of the Eip
+	Eip string
+	// Protocol is the protocol of the Eip
+	Protocol string
+	// Node is the name of the node
+	Node string
+	// Limit is the maximum number of items returned, no limit if 0
+	Limit int
+	// Continue is the token of the next page returned by the previous list
+	Continue string
+}
+
+// List is a page of a read-only view.
+type List struct {
+	Items interface{} `json:"items"`
+	// Continue is set if there are more items, pass it to get the next page
+	Continue string `json:"continue,omitempty"`
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/server/internal/lib"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WatchHandler is an interface that is used to stream the changes of the
+// resources to the http clients.
+type WatchHandler interface {
+	// Eips returns the changes of the Eips.
+	Eips() *lib.Broadcaster
+	// BgpPeers returns the changes of the BgpPeers.
+	BgpPeers() *lib.Broadcaster
+	// BgpConfs returns the changes of the BgpConfs.
+	BgpConfs() *lib.Broadcaster
+	// Allocations returns the changes of the addresses allocated from the
+	// Eips, keyed by Eip and address.
+	Allocations() *lib.Broadcaster
+}
+
+// watchHandler is an implementation of the WatchHandler backed by the
+// informers of the cache.
+type watchHandler struct {
+	eips        *lib.Broadcaster
+	bgpPeers    *lib.Broadcaster
+	bgpConfs    *lib.Broadcaster
+	allocations *lib.Broadcaster
+	informers   []informer
+}
+
+type informer struct {
+	cache.Informer
+	broadcasters []*lib.Broadcaster
+}
+
+// NewWatchHandler returns a new instance of watchHandler which implements the
+// WatchHandler interface. The event handlers are added to the informers of
+// the cache, the cache has to be started and Synced called before serving.
+func NewWatchHandler(ctx context.Context, informers cache.Informers) (*watchHandler, error) {
+	w := &watchHandler{
+		eips:        lib.NewBroadcaster(lib.DefaultHistorySize),
+		bgpPeers:    lib.NewBroadcaster(lib.DefaultHistorySize),
+		bgpConfs:    lib.NewBroadcaster(lib.DefaultHistorySize),
+		allocations: lib.NewBroadcaster(lib.DefaultHistorySize),
+	}
+
+	eipInformer, err := informers.GetInformer(ctx, &v1alpha2.Eip{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := eipInformer.AddEventHandler(objectEventHandler(w.eips)); err != nil {
+		return nil, err
+	}
+	if _, err := eipInformer.AddEventHandler(allocationEventHandler(w.allocations)); err != nil {
+		return nil, err
+	}
+	w.informers = append(w.informers, informer{eipInformer, []*lib.Broadcaster{w.eips, w.allocations}})
+
+	for obj, b := range map[client.Object]*lib.Broadcaster{
+		&v1alpha2.BgpPeer{}: w.bgpPeers,
+		&v1alpha2.BgpConf{}: w.bgpConfs,
+	} {
+		i, err := informers.GetInformer(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := i.AddEventHandler(objectEventHandler(b)); err != nil {
+			return nil, err
+		}
+		w.informers = append(w.informers, informer{i, []*lib.Broadcaster{b}})
+	}
+
+	return w, nil
+}
+
+// Synced waits for the informers to sync and marks the broadcasters synced
+// at the resourceVersion of the initial list.
+func (w *watchHandler) Synced(ctx context.Context) error {
+	for _, i := range w.informers {
+		if !toolscache.WaitForCacheSync(ctx.Done(), i.HasSynced) {
+			return fmt.Errorf("failed to wait for the watch caches to sync")
+		}
+		var rv string
+		if s, ok := i.Informer.(interface{ LastSyncResourceVersion() string }); ok {
+			rv = s.LastSyncResourceVersion()
+		}
+		for _, b := range i.broadcasters {
+			b.Synced(rv)
+		}
+	}
+	return nil
+}
+
+// Close closes the streams of the watchers.
+func (w *watchHandler) Close() {
+	for _, b := range []*lib.Broadcaster{w.eips, w.bgpPeers, w.bgpConfs, w.allocations} {
+		b.Close()
+	}
+}
+
+func (w *watchHandler) Eips() *lib.Broadcaster {
+	return w.eips
+}
+
+func (w *watchHandler) BgpPeers() *lib.Broadcaster {
+	return w.bgpPeers
+}
+
+func (w *watchHandler) BgpConfs() *lib.Broadcaster {
+	return w.bgpConfs
+}
+
+func (w *watchHandler) Allocations() *lib.Broadcaster {
+	return w.allocations
+}
+
+// deletedObject returns the object of a delete notification, the informer
+// passes a tombstone when it missed the deletion.
+func deletedObject(obj interface{}) (client.Object, bool) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	o, ok := obj.(client.Object)
+	return o, ok
+}
+
+func objectEventHandler(b *lib.Broadcaster) toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if o, ok := obj.(client.Object); ok {
+				b.Publish(lib.Added, o.GetName(), o, o.GetResourceVersion(), isInInitialList)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if o, ok := obj.(client.Object); ok {
+				b.Publish(lib.Modified, o.GetName(), o, o.GetResourceVersion(), false)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if o, ok := deletedObject(obj); ok {
+				b.Publish(lib.Deleted, o.GetName(), o, o.GetResourceVersion(), false)
+			}
+		},
+	}
+}
+
+// allocationEventHandler publishes the changes of the Used status of the
+// Eips as the changes of the allocations.
+func allocationEventHandler(b *lib.Broadcaster) toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if eip, ok := obj.(*v1alpha2.Eip); ok {
+				publishAllocations(b, &v1alpha2.Eip{}, eip, isInInitialList)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			old, ok := oldObj.(*v1alpha2.Eip)
+			if !ok {
+				return
+			}
+			if eip, ok := newObj.(*v1alpha2.Eip); ok {
+				publishAllocations(b, old, eip, false)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if o, ok := deletedObject(obj); ok {
+				if eip, ok := o.(*v1alpha2.Eip); ok {
+					deleted := eip.DeepCopy()
+					deleted.Status.Used = nil
+					publishAllocations(b, eip, deleted, false)
+				}
+			}
+		},
+	}
+}
+
+// publishAllocations publishes the difference between the allocations of the
+// old and the new Eip at the resourceVersion of the new Eip.
+func publishAllocations(b *lib.Broadcaster, old, eip *v1alpha2.Eip, initial bool) {
+	rv := eip.ResourceVersion
+	for ip, used := range eip.Status.Used {
+		key := eip.Name + "/" + ip
+		oldUsed, ok := old.Status.Used[ip]
+		switch {
+		case !ok:
+			b.Publish(lib.Added, key, newAllocation(eip, ip, used), rv, initial)
+		case oldUsed != used || !reflect.DeepEqual(old.GetProtocols(), eip.GetProtocols()):
+			b.Publish(lib.Modified, key, newAllocation(eip, ip, used), rv, initial)
+		}
+	}
+	for ip, used := range old.Status.Used {
+		if _, ok := eip.Status.Used[ip]; !ok {
+			b.Publish(lib.Deleted, eip.Name+"/"+ip, newAllocation(old, ip, used), rv, initial)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/server/internal/lib"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+func TestAllocationEventHandler(t *testing.T) {
+	b := lib.NewBroadcaster(lib.DefaultHistorySize)
+	h := allocationEventHandler(b)
+
+	eip := &v1alpha2.Eip{
+		ObjectMeta: metav1.ObjectMeta{Name: "eip", ResourceVersion: "10"},
+		Status:     v1alpha2.EipStatus{Used: map[string]string{"10.0.0.1": "default/svc1"}},
+	}
+	h.OnAdd(eip, true)
+	b.Synced("10")
+
+	_, w, err := b.Watch("10")
+	assert.NoError(t, err)
+
+	updated := eip.DeepCopy()
+	updated.ResourceVersion = "11"
+	updated.Status.Used = map[string]string{
+		"10.0.0.1": "default/svc1;default/svc2",
+		"10.0.0.2": "default/svc3",
+	}
+	h.OnUpdate(eip, updated)
+	h.OnDelete(toolscache.DeletedFinalStateUnknown{Key: "eip", Obj: updated})
+
+	var events []lib.Event
+	for i := 0; i < 4; i++ {
+		events = append(events, <-w.ResultChan())
+	}
+	// the changes of an Eip are published in any order, numbered at its
+	// resourceVersion
+	for i, event := range events {
+		assert.Equal(t, fmt.Sprintf("11.%d", i), event.ID)
+		events[i].ID = ""
+	}
+	assert.ElementsMatch(t, []lib.Event{
+		{Type: lib.Modified, Object: Allocation{IP: "10.0.0.1", Eip: "eip", Protocol: "bgp", Services: []string{"default/svc1", "default/svc2"}}},
+		{Type: lib.Added, Object: Allocation{IP: "10.0.0.2", Eip: "eip", Protocol: "bgp", Services: []string{"default/svc3"}}},
+	}, events[:2])
+	assert.ElementsMatch(t, []string{"10.0.0.1", "10.0.0.2"}, []string{
+		events[2].Object.(Allocation).IP, events[3].Object.(Allocation).IP,
+	})
+	assert.Equal(t, lib.Deleted, events[2].Type)
+	assert.Equal(t, lib.Deleted, events[3].Type)
+
+	// a watch resumed in the middle of the changes gets the rest of them
+	resumed, _, err := b.Watch("11.1")
+	assert.NoError(t, err)
+	assert.Equal(t, events[2:], clearIDs(resumed))
+	resumed, _, err = b.Watch("11")
+	assert.NoError(t, err)
+	assert.Empty(t, resumed)
+
+	current, _, err := b.Watch("")
+	assert.NoError(t, err)
+	assert.Empty(t, current)
+}
+
+func clearIDs(events []lib.Event) []lib.Event {
+	for i := range events {
+		events[i].ID = ""
+	}
+	return events
+}
@@ -0,0 +1,199 @@
+package lib
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const authCacheSize = 1024
+
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	authKey
+)
+
+// Auth authenticates the bearer token of a request by TokenReview and
+// authorizes the request by SubjectAccessReview, so that the callers of the
+// REST API need the same RBAC permissions as with kubectl. The reviews are
+// cached for ttl.
+type Auth struct {
+	client    kubernetes.Interface
+	ttl       time.Duration
+	tokens    *cache.LRUExpireCache
+	decisions *cache.LRUExpireCache
+}
+
+func NewAuth(client kubernetes.Interface, ttl time.Duration) *Auth {
+	return &Auth{
+		client:    client,
+		ttl:       ttl,
+		tokens:    cache.NewLRUExpireCache(authCacheSize),
+		decisions: cache.NewLRUExpireCache(authCacheSize),
+	}
+}
+
+// UserFrom returns the authenticated user of the request.
+func UserFrom(ctx context.Context) (authenticationv1.UserInfo, bool) {
+	user, ok := ctx.Value(userKey).(authenticationv1.UserInfo)
+	return user, ok
+}
+
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// tokenKey is the key of the token in the cache, the token isn't kept in
+// memory in plain text.
+func tokenKey(token string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(token))
+}
+
+func (a *Auth) authenticate(ctx context.Context, token string) (authenticationv1.UserInfo, error) {
+	key := tokenKey(token)
+	if user, ok := a.tokens.Get(key); ok {
+		return user.(authenticationv1.UserInfo), nil
+	}
+
+	review, err := a.client.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return authenticationv1.UserInfo{}, err
+	}
+	if !review.Status.Authenticated {
+		return authenticationv1.UserInfo{}, fmt.Errorf("invalid bearer token: %s", review.Status.Error)
+	}
+
+	a.tokens.Add(key, review.Status.User, a.ttl)
+	return review.Status.User, nil
+}
+
+func (a *Auth) authorize(ctx context.Context, user authenticationv1.UserInfo, attrs authorizationv1.ResourceAttributes) (bool, string, error) {
+	key := fmt.Sprintf("%s/%s/%v/%v/%s/%s/%s/%s/%s", user.Username, user.UID, user.Groups, user.Extra,
+		attrs.Verb, attrs.Group, attrs.Resource, attrs.Namespace, attrs.Name)
+	if allowed, ok := a.decisions.Get(key); ok {
+		return allowed.(bool), "", nil
+	}
+
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review, err := a.client.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:               user.Username,
+			UID:                user.UID,
+			Groups:             user.Groups,
+			Extra:              extra,
+			ResourceAttributes: &attrs,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, "", err
+	}
+
+	a.decisions.Add(key, review.Status.Allowed, a.ttl)
+	return review.Status.Allowed, review.Status.Reason, nil
+}
+
+// Authenticate is the middleware rejecting the requests without a valid
+// bearer token.
+func (a *Auth) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// preflight requests of the browsers carry no credentials
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := bearerToken(r)
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, errors.NewUnauthorized("missing bearer token"))
+			return
+		}
+
+		user, err := a.authenticate(r.Context(), token)
+		if err != nil {
+			klog.V(4).Infof("authenticate %s %s error: %v", r.Method, r.URL.Path, err)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, errors.NewUnauthorized("invalid bearer token"))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userKey, user)
+		ctx = context.WithValue(ctx, authKey, a)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Resource is the resource authorized by the requests of an endpoint.
+type Resource struct {
+	Group    string
+	Resource string
+	// Namespaced resources are authorized in the namespace of the `namespace`
+	// query param, in all namespaces if it is empty
+	Namespaced bool
+}
+
+// Authorize returns the middleware checking that the caller is allowed to
+// verb the resource named by the `name` url param. The requests pass through
+// if the server runs without authentication.
+func Authorize(resource Resource, verb string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			a, ok := r.Context().Value(authKey).(*Auth)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			user, _ := UserFrom(r.Context())
+
+			attrs := authorizationv1.ResourceAttributes{
+				Group:    resource.Group,
+				Resource: resource.Resource,
+				Verb:     verb,
+				Name:     chi.URLParam(r, "name"),
+			}
+			if resource.Namespaced {
+				attrs.Namespace = r.URL.Query().Get("namespace")
+			}
+			allowed, reason, err := a.authorize(r.Context(), user, attrs)
+			if err != nil {
+				klog.Errorf("authorize %s %s error: %v", r.Method, r.URL.Path, err)
+				writeError(w, errors.NewInternalError(err))
+				return
+			}
+			if !allowed {
+				msg := fmt.Sprintf("user %q cannot %s %s", user.Username, verb, resource.Resource)
+				if reason != "" {
+					msg += ": " + reason
+				}
+				gr := schema.GroupResource{Group: attrs.Group, Resource: attrs.Resource}
+				writeError(w, errors.NewForbidden(gr, attrs.Name, fmt.Errorf("%s", msg)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newAuthServer(auth *Auth) http.Handler {
+	r := chi.NewRouter()
+	if auth != nil {
+		r.Use(auth.Authenticate)
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		user, _ := UserFrom(r.Context())
+		writeResponse(w, http.StatusOK, user.Username)
+	}
+	eips := Resource{Group: "network.kubesphere.io", Resource: "eips"}
+	r.With(Authorize(eips, "get")).Get("/apis/v1/eip/{name}", ok)
+	r.With(Authorize(eips, "delete")).Delete("/apis/v1/eip/{name}", ok)
+	return r
+}
+
+func serve(handler http.Handler, method, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/apis/v1/eip/eip-1", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuth(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	tokenReviews, accessReviews := 0, 0
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		tokenReviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if review.Spec.Token == "viewer-token" {
+			review.Status.Authenticated = true
+			review.Status.User = authenticationv1.UserInfo{Username: "viewer", Groups: []string{"system:authenticated"}}
+		}
+		return true, review, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		accessReviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		assert.Equal(t, "network.kubesphere.io", attrs.Group)
+		assert.Equal(t, "eip-1", attrs.Name)
+		review.Status.Allowed = review.Spec.User == "viewer" && attrs.Verb == "get"
+		return true, review, nil
+	})
+	auth := NewAuth(client, time.Minute)
+	handler := newAuthServer(auth)
+
+	w := serve(handler, http.MethodGet, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+
+	w = serve(handler, http.MethodGet, "unknown-token")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = serve(handler, http.MethodGet, "viewer-token")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "\"viewer\"\n", w.Body.String())
+
+	w = serve(handler, http.MethodDelete, "viewer-token")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// the reviews are cached
+	tokenReviews, accessReviews = 0, 0
+	assert.Equal(t, http.StatusOK, serve(handler, http.MethodGet, "viewer-token").Code)
+	assert.Equal(t, http.StatusForbidden, serve(handler, http.MethodDelete, "viewer-token").Code)
+	assert.Equal(t, 0, tokenReviews)
+	assert.Equal(t, 0, accessReviews)
+
+	// the tokens are cached by their hashes
+	_, ok := auth.tokens.Get("viewer-token")
+	assert.False(t, ok)
+	_, ok = auth.tokens.Get(tokenKey("viewer-token"))
+	assert.True(t, ok)
+}
+
+func TestAuthDisabled(t *testing.T) {
+	w := serve(newAuthServer(nil), http.MethodDelete, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -2,17 +2,21 @@ package lib
 
 import (
 	"encoding/json"
+	stderrors "errors"
 	"io"
 	"net/http"
 	"reflect"
 
 	"github.com/go-chi/chi/v5"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // Router is an interface which all rest Router must implement.
 type Router interface {
 	Register(httpRouter chi.Router)
+	// Routes describes the endpoints registered for the OpenAPI document.
+	Routes() []Route
 }
 
 // readRequestBody reads the request body and unmarshals it into the given object.
@@ -38,33 +42,46 @@ func readRequestBody(r *http.Request, bodyObj interface{}) error {
 func ServeRequest(req InboundRequest) {
 	if req.ReqBody != nil {
 		if err := readRequestBody(req.R, req.ReqBody); err != nil {
-			writeResponse(req.W, http.StatusBadRequest, err)
+			writeError(req.W, errors.NewBadRequest(err.Error()))
 			return
 		}
 	}
 	resp, err := req.EndpointLogic()
 	if err != nil {
-		switch {
-		case errors.IsTimeout(err) || errors.IsServerTimeout(err):
-			writeResponse(req.W, http.StatusGatewayTimeout, err)
-		case errors.IsNotFound(err):
-			writeResponse(req.W, http.StatusNotFound, err)
-		case errors.IsAlreadyExists(err) || errors.IsConflict(err):
-			writeResponse(req.W, http.StatusConflict, err)
-		case errors.IsBadRequest(err):
-			writeResponse(req.W, http.StatusBadRequest, err)
-		case errors.IsTooManyRequests(err):
-			writeResponse(req.W, http.StatusTooManyRequests, err)
-		case errors.IsNotAcceptable(err):
-			writeResponse(req.W, http.StatusNotAcceptable, err)
-		default:
-			writeResponse(req.W, http.StatusInternalServerError, err)
-		}
+		writeError(req.W, err)
 	} else {
 		writeResponse(req.W, req.StatusCode, resp)
 	}
 }
 
+// statusOf returns the error as a Status, the errors which don't come from the
+// kube-apiserver are internal errors.
+func statusOf(err error) metav1.Status {
+	var status metav1.Status
+	var apiStatus errors.APIStatus
+	if stderrors.As(err, &apiStatus) {
+		status = apiStatus.Status()
+	} else {
+		status = errors.NewInternalError(err).ErrStatus
+	}
+	status.Kind, status.APIVersion = "Status", "v1"
+	status.Status = metav1.StatusFailure
+	if status.Code == 0 {
+		status.Code = http.StatusInternalServerError
+	}
+	return status
+}
+
+// writeError writes the error as a Status with the status code of the error.
+func writeError(w http.ResponseWriter, err error) {
+	status := statusOf(err)
+	code := int(status.Code)
+	if errors.IsServerTimeout(err) {
+		code = http.StatusGatewayTimeout
+	}
+	writeResponse(w, code, status)
+}
+
 // writeResponse writes the response to the writer with status code and
 // response body.
 func writeResponse(w http.ResponseWriter, statusCode int, resp interface{}) error {
@@ -72,12 +89,17 @@ func writeResponse(w http.ResponseWriter, statusCode int, resp interface{}) erro
 		statusCode = http.StatusOK
 	}
 	w.Header().Set("Content-Type", "application/json")
-	if resp != nil {
-		if err := json.NewEncoder(w).Encode(resp); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return err
-		}
+	if resp == nil {
+		w.WriteHeader(statusCode)
+		return nil
+	}
+	// The status code can't be changed once the body is written
+	body, err := json.Marshal(resp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return err
 	}
 	w.WriteHeader(statusCode)
-	return nil
+	_, err = w.Write(append(body, '\n'))
+	return err
 }
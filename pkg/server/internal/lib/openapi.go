@@ -0,0 +1,334 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Route is an endpoint of the REST API, it is registered to the http router
+// and described in the OpenAPI document.
+type Route struct {
+	Method string
+	// Path of the endpoint, the path params are in braces e.g. /eip/{name}
+	Path string
+	// Resource and Verb authorize the requests
+	Resource Resource
+	Verb     string
+	// OperationID names the operation in the document and the Go client
+	OperationID string
+	Summary     string
+	// Query are the query params
+	Query []Param
+	// Body is a value of the type of the request body, nil if none
+	Body interface{}
+	// BodyContentType is the content type of the body, application/json by
+	// default
+	BodyContentType string
+	Responses       []Response
+	Handler         http.HandlerFunc
+}
+
+// Param is a query param of a route.
+type Param struct {
+	Name        string
+	Description string
+	// Type is the JSON type of the param, string by default
+	Type string
+}
+
+// Response is a response of a route.
+type Response struct {
+	Status      int
+	Description string
+	// Body is a value of the type of the response body, nil if none
+	Body interface{}
+	// ContentType is the content type of the body, application/json by
+	// default
+	ContentType string
+}
+
+// RegisterRoutes registers the routes to the router, authorized by their
+// resource and verb.
+func RegisterRoutes(r chi.Router, routes []Route) {
+	for _, route := range routes {
+		r.With(Authorize(route.Resource, route.Verb)).Method(route.Method, route.Path, route.Handler)
+	}
+}
+
+// OpenAPI is an OpenAPI v3 document.
+type OpenAPI struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]*PathItem  `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem are the operations of a path by method.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Parameters  []Parameter                `json:"parameters,omitempty"`
+	RequestBody *RequestBody               `json:"requestBody,omitempty"`
+	Responses   map[string]*ResponseObject `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// ResponseObject is a response of an operation of the document.
+type ResponseObject struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+// Schema is a JSON schema of the document.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// NewOpenAPI returns the OpenAPI document of the routes, the schemas are
+// generated from the Go types of the bodies. The errors are Status objects.
+func NewOpenAPI(title, version string, routes []Route) *OpenAPI {
+	g := newSchemaGenerator()
+	doc := &OpenAPI{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: title, Version: version},
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			SecuritySchemes: map[string]SecurityScheme{"bearer": {Type: "http", Scheme: "bearer"}},
+		},
+		Security: []map[string][]string{{"bearer": {}}},
+	}
+	errorSchema := g.schema(reflect.TypeOf(metav1.Status{}))
+
+	for _, route := range routes {
+		op := &Operation{
+			OperationID: route.OperationID,
+			Summary:     route.Summary,
+			Responses: map[string]*ResponseObject{
+				"default": {Description: "error", Content: map[string]MediaType{"application/json": {Schema: errorSchema}}},
+			},
+		}
+		for _, m := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+			op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+		for _, p := range route.Query {
+			typ := p.Type
+			if typ == "" {
+				typ = "string"
+			}
+			op.Parameters = append(op.Parameters, Parameter{Name: p.Name, In: "query", Description: p.Description, Schema: &Schema{Type: typ}})
+		}
+		if route.Body != nil {
+			op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+				contentType(route.BodyContentType): {Schema: g.schema(reflect.TypeOf(route.Body))},
+			}}
+		}
+		for _, resp := range route.Responses {
+			r := &ResponseObject{Description: resp.Description}
+			if resp.Body != nil {
+				r.Content = map[string]MediaType{contentType(resp.ContentType): {Schema: g.schema(reflect.TypeOf(resp.Body))}}
+			}
+			op.Responses[fmt.Sprint(resp.Status)] = r
+		}
+
+		item, ok := doc.Paths[route.Path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[route.Path] = item
+		}
+		(*item)[strings.ToLower(route.Method)] = op
+	}
+
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+// ServeHTTP serves the document.
+func (o *OpenAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, o)
+}
+
+func contentType(t string) string {
+	if t == "" {
+		return "application/json"
+	}
+	return t
+}
+
+// openAPISchemaType is implemented by the kubernetes types marshalled as
+// a JSON scalar, e.g. metav1.Time or resource.Quantity.
+type openAPISchemaType interface {
+	OpenAPISchemaType() []string
+	OpenAPISchemaFormat() string
+}
+
+var (
+	openAPISchemaTypeIface = reflect.TypeOf((*openAPISchemaType)(nil)).Elem()
+	rawMessageType         = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaGenerator generates the schemas of the Go types as marshalled by
+// encoding/json. The named structs are components of the document.
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	types   map[string]reflect.Type
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		schemas: map[string]*Schema{},
+		types:   map[string]reflect.Type{},
+	}
+}
+
+// schemaName returns the name of the component of the type, the package
+// name is prefixed and the full path is used on conflicts.
+func (g *schemaGenerator) schemaName(t reflect.Type) string {
+	name := path.Base(t.PkgPath()) + "." + t.Name()
+	if other, ok := g.types[name]; ok && other != t {
+		name = strings.NewReplacer("/", ".", "~", "_").Replace(t.PkgPath()) + "." + t.Name()
+	}
+	return name
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Implements(openAPISchemaTypeIface) || reflect.PtrTo(t).Implements(openAPISchemaTypeIface) {
+		v := reflect.New(t).Interface().(openAPISchemaType)
+		s := &Schema{Format: v.OpenAPISchemaFormat()}
+		if types := v.OpenAPISchemaType(); len(types) == 1 {
+			s.Type = types[0]
+		}
+		return s
+	}
+	if t == rawMessageType {
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := g.schemaName(t)
+		if _, ok := g.types[name]; !ok {
+			// Registered before the fields for the recursive types
+			g.types[name] = t
+			g.schemas[name] = &Schema{}
+			*g.schemas[name] = *g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.addFields(s, t)
+	sort.Strings(s.Required)
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		// The embedded structs without a name are inlined by encoding/json
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			g.addFields(s, ft)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.schema(f.Type)
+		switch f.Type.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		default:
+			if !strings.Contains(opts, "omitempty") {
+				s.Required = append(s.Required, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package lib
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type testSpec struct {
+	Name     string            `json:"name"`
+	Size     int32             `json:"size,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Children []*testObject     `json:"children,omitempty"`
+	Secret   string            `json:"-"`
+	internal string
+}
+
+type testObject struct {
+	metav1.TypeMeta `json:",inline"`
+	Created         metav1.Time `json:"created"`
+	Spec            testSpec    `json:"spec"`
+	Data            []byte      `json:"data,omitempty"`
+}
+
+func TestSchemaGenerator(t *testing.T) {
+	g := newSchemaGenerator()
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/lib.testObject"}, g.schema(reflect.TypeOf(&testObject{})))
+
+	object := g.schemas["lib.testObject"]
+	// the embedded structs are inlined
+	assert.Contains(t, object.Properties, "kind")
+	assert.Contains(t, object.Properties, "apiVersion")
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, object.Properties["created"])
+	assert.Equal(t, &Schema{Type: "string", Format: "byte"}, object.Properties["data"])
+	assert.Equal(t, []string{"created", "spec"}, object.Required)
+
+	spec := g.schemas["lib.testSpec"]
+	assert.Equal(t, []string{"children", "labels", "name", "size"}, sortedKeys(spec.Properties))
+	assert.Equal(t, []string{"name"}, spec.Required)
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}}, spec.Properties["labels"])
+	// the recursive types refer to the component
+	assert.Equal(t, "#/components/schemas/lib.testObject", spec.Properties["children"].Items.Ref)
+}
+
+func TestNewOpenAPI(t *testing.T) {
+	doc := NewOpenAPI("test", "v0", []Route{{
+		Method: http.MethodPut, Path: "/objects/{name}", Verb: "update",
+		OperationID: "updateObject",
+		Query:       []Param{{Name: "dryRun"}, {Name: "limit", Type: "integer"}},
+		Body:        testObject{},
+		Responses:   []Response{{Status: http.StatusOK, Description: "updated", Body: testObject{}}},
+	}})
+
+	op := (*doc.Paths["/objects/{name}"])["put"]
+	assert.Equal(t, "updateObject", op.OperationID)
+	assert.Equal(t, []Parameter{
+		{Name: "name", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+		{Name: "dryRun", In: "query", Schema: &Schema{Type: "string"}},
+		{Name: "limit", In: "query", Schema: &Schema{Type: "integer"}},
+	}, op.Parameters)
+	assert.Equal(t, "#/components/schemas/lib.testObject", op.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/v1.Status", op.Responses["default"].Content["application/json"].Schema.Ref)
+	assert.Contains(t, doc.Components.Schemas, "lib.testSpec")
+}
+
+func sortedKeys(m map[string]*Schema) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
 	"github.com/openelb/openelb/pkg/server/options"
+	"github.com/openelb/openelb/pkg/version"
 )
 
 type server struct {
@@ -16,24 +17,41 @@ type server struct {
 	options options.Options
 }
 
-func NewHTTPServer(routers []Router, options options.Options) *server {
-	httpRouter := chi.NewRouter()
-
+// NewHTTPServer returns the server of the routers, the requests are
+// authenticated and authorized by auth unless it is nil. The OpenAPI document
+// of the routers is served at /openapi.json without authentication.
+func NewHTTPServer(routers []Router, options options.Options, auth *Auth) *server {
+	var routes []Route
 	for _, endpoint := range routers {
-		endpoint.Register(httpRouter)
+		routes = append(routes, endpoint.Routes()...)
 	}
 
+	httpRouter := chi.NewRouter()
+	httpRouter.Method(http.MethodGet, "/openapi.json", NewOpenAPI("OpenELB API", version.Get().GitVersion, routes))
+	httpRouter.Group(func(r chi.Router) {
+		if auth != nil {
+			r.Use(auth.Authenticate)
+		}
+		for _, endpoint := range routers {
+			endpoint.Register(r)
+		}
+	})
+
 	return &server{
 		handler: cors.New(cors.Options{
 			AllowedOrigins:   []string{"http://localhost:3000"},
 			AllowCredentials: true,
 			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
-			AllowedHeaders:   []string{"Accept", "Content-Type"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Last-Event-ID"},
 		}).Handler(httpRouter),
 		options: options,
 	}
 }
 
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
 func (s *server) ListenAndServe(stopCh <-chan struct{}) error {
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.options.Port),
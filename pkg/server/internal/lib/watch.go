@@ -0,0 +1,337 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+)
+
+// EventType is the type of a change streamed to the watchers.
+type EventType string
+
+const (
+	Added    EventType = "ADDED"
+	Modified EventType = "MODIFIED"
+	Deleted  EventType = "DELETED"
+)
+
+const (
+	// DefaultHistorySize is the number of the changes kept to resume the
+	// watches.
+	DefaultHistorySize = 1000
+	// watcherBuffer is the number of the changes buffered for a watcher, a
+	// watcher falling behind is closed and has to resume.
+	watcherBuffer = 100
+	// heartbeatPeriod keeps the idle streams open through the proxies.
+	heartbeatPeriod = 30 * time.Second
+)
+
+// Event is a change of an object.
+type Event struct {
+	Type   EventType   `json:"type"`
+	Object interface{} `json:"object"`
+	// ID is used to resume the watch after the event, it's empty for the
+	// current objects but the last one
+	ID string `json:"-"`
+}
+
+// position is the position of a change in the history. Several changes may
+// be published at a resourceVersion, e.g. the allocations of an Eip, so they
+// are numbered by index.
+type position struct {
+	rv    uint64
+	index uint64
+}
+
+// allIndexes is the index of the position after all the changes at a
+// resourceVersion.
+const allIndexes = math.MaxUint64
+
+func (p position) less(o position) bool {
+	return p.rv < o.rv || (p.rv == o.rv && p.index < o.index)
+}
+
+// String returns the id of the change, `<resourceVersion>.<index>`.
+func (p position) String() string {
+	if p.index == allIndexes {
+		return strconv.FormatUint(p.rv, 10)
+	}
+	return strconv.FormatUint(p.rv, 10) + "." + strconv.FormatUint(p.index, 10)
+}
+
+// parsePosition parses the id of a change, or a resourceVersion which is the
+// position after all the changes at it.
+func parsePosition(id string) (position, error) {
+	rv, index, found := strings.Cut(id, ".")
+	p := position{index: allIndexes}
+	var err error
+	if p.rv, err = parseResourceVersion(rv); err != nil {
+		return p, err
+	}
+	if found {
+		if p.index, err = strconv.ParseUint(index, 10, 64); err != nil || p.index == allIndexes {
+			return p, fmt.Errorf("invalid event id %s", id)
+		}
+	}
+	return p, nil
+}
+
+type record struct {
+	event Event
+	pos   position
+}
+
+// Broadcaster keeps the current objects and the recent changes of a
+// resource, and streams the changes to the watchers. The changes are
+// ordered by resourceVersion and numbered at the same one, so a watcher can
+// resume from the last change it received as long as the change is still in
+// the history.
+type Broadcaster struct {
+	lock    sync.Mutex
+	size    int
+	objects map[string]interface{}
+	history []record
+	// oldest is the position after which the history is complete
+	oldest position
+	last   uint64
+	// count is the number of the changes in the history at last
+	count    uint64
+	synced   bool
+	watchers map[*Watcher]struct{}
+}
+
+// NewBroadcaster returns a broadcaster keeping size changes in the history.
+func NewBroadcaster(size int) *Broadcaster {
+	return &Broadcaster{
+		size:     size,
+		objects:  map[string]interface{}{},
+		watchers: map[*Watcher]struct{}{},
+	}
+}
+
+// parseResourceVersion returns the resourceVersion as a number, the
+// resourceVersions are the etcd revisions for the kube-apiserver.
+func parseResourceVersion(rv string) (uint64, error) {
+	return strconv.ParseUint(rv, 10, 64)
+}
+
+// Publish records the change of the object with the key. The changes of the
+// initial list of the informer only update the current objects.
+func (b *Broadcaster) Publish(typ EventType, key string, obj interface{}, resourceVersion string, initial bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if typ == Deleted {
+		delete(b.objects, key)
+	} else {
+		b.objects[key] = obj
+	}
+
+	// The resourceVersion of a deleted object missed by the informer is
+	// the last one known, keep the history ordered anyway.
+	rv, _ := parseResourceVersion(resourceVersion)
+	if rv < b.last {
+		rv = b.last
+	}
+	if rv > b.last {
+		b.count = 0
+	}
+	b.last = rv
+	if initial {
+		return
+	}
+
+	pos := position{rv: rv, index: b.count}
+	b.count++
+	r := record{event: Event{Type: typ, Object: obj, ID: pos.String()}, pos: pos}
+	b.history = append(b.history, r)
+	if len(b.history) > b.size {
+		b.oldest = b.history[0].pos
+		b.history = b.history[1:]
+	}
+
+	for w := range b.watchers {
+		select {
+		case w.ch <- r.event:
+		default:
+			klog.V(4).Infof("watcher falling behind at resourceVersion %d, closing", rv)
+			b.stop(w)
+		}
+	}
+}
+
+// Synced marks the informer as synced at the resourceVersion, the history
+// is complete from there.
+func (b *Broadcaster) Synced(resourceVersion string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	rv, err := parseResourceVersion(resourceVersion)
+	if err != nil {
+		rv = b.last
+	}
+	if synced := (position{rv: rv, index: allIndexes}); b.oldest.less(synced) {
+		b.oldest = synced
+	}
+	b.synced = true
+}
+
+// Watch returns the changes after the event id or the resourceVersion and a
+// watcher of the next ones. Without a resourceVersion the current objects
+// are returned as added. A resourceVersion older than the history is
+// expired, the client has to list again.
+func (b *Broadcaster) Watch(resourceVersion string) ([]Event, *Watcher, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if !b.synced {
+		return nil, nil, errors.NewServiceUnavailable("the watch cache is not synced")
+	}
+
+	var events []Event
+	if resourceVersion == "" || resourceVersion == "0" {
+		keys := make([]string, 0, len(b.objects))
+		for key := range b.objects {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			events = append(events, Event{Type: Added, Object: b.objects[key]})
+		}
+		// a client disconnected before the last one starts over
+		if len(events) != 0 {
+			events[len(events)-1].ID = strconv.FormatUint(b.last, 10)
+		}
+	} else {
+		pos, err := parsePosition(resourceVersion)
+		if err != nil || pos.less(b.oldest) {
+			return nil, nil, errors.NewResourceExpired(fmt.Sprintf("too old resource version: %s (%s)", resourceVersion, b.oldest))
+		}
+		i := sort.Search(len(b.history), func(i int) bool {
+			return pos.less(b.history[i].pos)
+		})
+		for _, r := range b.history[i:] {
+			events = append(events, r.event)
+		}
+	}
+
+	w := &Watcher{ch: make(chan Event, watcherBuffer), b: b}
+	b.watchers[w] = struct{}{}
+	return events, w, nil
+}
+
+// Close closes all the watchers.
+func (b *Broadcaster) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for w := range b.watchers {
+		b.stop(w)
+	}
+}
+
+func (b *Broadcaster) stop(w *Watcher) {
+	if _, ok := b.watchers[w]; ok {
+		delete(b.watchers, w)
+		close(w.ch)
+	}
+}
+
+// Watcher receives the changes published after it was created.
+type Watcher struct {
+	ch chan Event
+	b  *Broadcaster
+}
+
+// ResultChan returns the changes, the channel is closed when the watcher is
+// stopped or falls behind.
+func (w *Watcher) ResultChan() <-chan Event {
+	return w.ch
+}
+
+// Stop stops receiving the changes.
+func (w *Watcher) Stop() {
+	w.b.lock.Lock()
+	defer w.b.lock.Unlock()
+	w.b.stop(w)
+}
+
+// ServeWatch streams the changes of the broadcaster as server-sent events.
+// The stream resumes from the resourceVersion query parameter or the
+// Last-Event-ID header set by the EventSource on reconnection.
+func ServeWatch(w http.ResponseWriter, r *http.Request, b *Broadcaster) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, errors.NewInternalError(fmt.Errorf("streaming unsupported")))
+		return
+	}
+
+	rv := r.URL.Query().Get("resourceVersion")
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		rv = id
+	}
+	events, watcher, err := b.Watch(rv)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer watcher.Stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range events {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatPeriod)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes the event as a server-sent event, the id to resume from
+// is omitted if empty so the EventSource keeps the previous one.
+func writeEvent(w http.ResponseWriter, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if event.ID != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", event.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
@@ -0,0 +1,147 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+func eventTypes(events []Event) []EventType {
+	types := []EventType{}
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestBroadcaster(t *testing.T) {
+	b := NewBroadcaster(3)
+	b.Publish(Added, "a", "a1", "10", true)
+	b.Publish(Added, "b", "b1", "11", true)
+
+	_, _, err := b.Watch("")
+	assert.True(t, errors.IsServiceUnavailable(err))
+	b.Synced("12")
+
+	// the current objects are returned without a resourceVersion
+	events, w, err := b.Watch("")
+	assert.NoError(t, err)
+	assert.Equal(t, []Event{
+		{Type: Added, Object: "a1"},
+		{Type: Added, Object: "b1", ID: "11"},
+	}, events)
+
+	b.Publish(Modified, "a", "a2", "13", false)
+	b.Publish(Deleted, "b", "b1", "11", false)
+	assert.Equal(t, Event{Type: Modified, Object: "a2", ID: "13.0"}, <-w.ResultChan())
+	// the missed deletion is kept in order
+	assert.Equal(t, Event{Type: Deleted, Object: "b1", ID: "13.1"}, <-w.ResultChan())
+	w.Stop()
+	_, ok := <-w.ResultChan()
+	assert.False(t, ok)
+
+	events, _, err = b.Watch("12")
+	assert.NoError(t, err)
+	assert.Equal(t, []EventType{Modified, Deleted}, eventTypes(events))
+	// the watch resumes after the event in the middle of the changes at a
+	// resourceVersion
+	events, _, err = b.Watch("13.0")
+	assert.NoError(t, err)
+	assert.Equal(t, []Event{{Type: Deleted, Object: "b1", ID: "13.1"}}, events)
+	events, _, err = b.Watch("13")
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	b.Publish(Added, "c", "c1", "14", false)
+	b.Publish(Added, "d", "d1", "15", false)
+	events, _, err = b.Watch("13")
+	assert.NoError(t, err)
+	assert.Equal(t, []EventType{Added, Added}, eventTypes(events))
+
+	// the history starts after the event 13.0
+	_, _, err = b.Watch("12")
+	assert.True(t, errors.IsResourceExpired(err))
+	events, _, err = b.Watch("13.0")
+	assert.NoError(t, err)
+	assert.Len(t, events, 3)
+	_, _, err = b.Watch("13.1.1")
+	assert.True(t, errors.IsResourceExpired(err))
+	_, _, err = b.Watch("abc")
+	assert.True(t, errors.IsResourceExpired(err))
+
+	events, _, err = b.Watch("")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"a2", "c1", "d1"}, []interface{}{events[0].Object, events[1].Object, events[2].Object})
+}
+
+func TestBroadcasterSlowWatcher(t *testing.T) {
+	b := NewBroadcaster(DefaultHistorySize)
+	b.Synced("1")
+	_, w, err := b.Watch("1")
+	assert.NoError(t, err)
+
+	for i := 0; i <= watcherBuffer; i++ {
+		b.Publish(Added, "a", "a", "2", false)
+	}
+	received := 0
+	for range w.ResultChan() {
+		received++
+	}
+	assert.Equal(t, watcherBuffer, received)
+}
+
+func TestServeWatch(t *testing.T) {
+	b := NewBroadcaster(DefaultHistorySize)
+	b.Publish(Added, "a", map[string]string{"name": "a"}, "5", true)
+	b.Synced("5")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWatch(w, r, b)
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?resourceVersion=1")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusGone, resp.StatusCode)
+	resp.Body.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() string {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			assert.NoError(t, err)
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+	assert.Equal(t, "id: 5\nevent: ADDED\ndata: {\"type\":\"ADDED\",\"object\":{\"name\":\"a\"}}\n", readEvent())
+
+	b.Publish(Deleted, "a", map[string]string{"name": "a"}, "6", false)
+	assert.Equal(t, "id: 6.0\nevent: DELETED\ndata: {\"type\":\"DELETED\",\"object\":{\"name\":\"a\"}}\n", readEvent())
+
+	// the stream resumes from the last event id
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	req.Header.Set("Last-Event-ID", "5")
+	resp2, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp2.Body.Close()
+	reader = bufio.NewReader(resp2.Body)
+	assert.Equal(t, "id: 6.0\nevent: DELETED\ndata: {\"type\":\"DELETED\",\"object\":{\"name\":\"a\"}}\n", readEvent())
+}
@@ -0,0 +1,55 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/openelb/openelb/pkg/server/internal/handler"
+	"github.com/openelb/openelb/pkg/server/internal/lib"
+)
+
+type allocationRouter struct {
+	handler handler.AllocationHandler
+}
+
+func (a *allocationRouter) Register(r chi.Router) {
+	lib.RegisterRoutes(r, a.Routes())
+}
+
+func (a *allocationRouter) Routes() []lib.Route {
+	return []lib.Route{
+		{
+			Method: http.MethodGet, Path: "/apis/v1/allocations", Resource: eipResource, Verb: "list",
+			OperationID: "listAllocations", Summary: "List the addresses allocated from the Eips",
+			Query: listParams,
+			Responses: []lib.Response{
+				{Status: http.StatusOK, Description: "the allocations", Body: listOf(handler.Allocation{})},
+			},
+			Handler: a.list,
+		},
+	}
+}
+
+// NewAllocationRouter returns a new instance of allocationRouter which
+// implements the Router interface. This is used to register the endpoints to
+// the router.
+func NewAllocationRouter(handler handler.AllocationHandler) *allocationRouter {
+	return &allocationRouter{
+		handler: handler,
+	}
+}
+
+func (a *allocationRouter) list(w http.ResponseWriter, r *http.Request) {
+	lib.ServeRequest(lib.InboundRequest{
+		W: w,
+		R: r,
+		EndpointLogic: func() (interface{}, error) {
+			opts, err := listOptions(r)
+			if err != nil {
+				return nil, err
+			}
+			return a.handler.List(r.Context(), opts)
+		},
+		StatusCode: http.StatusOK,
+	})
+}
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -9,16 +10,65 @@ import (
 	"github.com/openelb/openelb/pkg/server/internal/lib"
 )
 
+// bgpConfResource is the resource of BgpConf authorized by the requests.
+var bgpConfResource = lib.Resource{Group: v1alpha2.GroupVersion.Group, Resource: "bgpconfs"}
+
 type bgpConfRouter struct {
 	handler handler.BgpConfHandler
 }
 
 func (b *bgpConfRouter) Register(r chi.Router) {
-	r.Post("/apis/v1/bgp/conf", b.create)
-	r.Get("/apis/v1/bgp/conf", b.get)
-	r.Patch("/apis/v1/bgp/conf", b.patch)
-	r.Put("/apis/v1/bgp/conf", b.update)
-	r.Delete("/apis/v1/bgp/conf", b.delete)
+	lib.RegisterRoutes(r, b.Routes())
+}
+
+func (b *bgpConfRouter) Routes() []lib.Route {
+	return []lib.Route{
+		{
+			Method: http.MethodPost, Path: "/apis/v1/bgp/conf", Resource: bgpConfResource, Verb: "create",
+			OperationID: "createBgpConf", Summary: "Create a BgpConf",
+			Query: []lib.Param{dryRunParam},
+			Body:  v1alpha2.BgpConf{},
+			Responses: []lib.Response{
+				{Status: http.StatusCreated, Description: "created", Body: handler.Create{}},
+			},
+			Handler: b.create,
+		},
+		{
+			Method: http.MethodGet, Path: "/apis/v1/bgp/conf", Resource: bgpConfResource, Verb: "get",
+			OperationID: "getBgpConf", Summary: "Get a BgpConf",
+			Responses: []lib.Response{
+				{Status: http.StatusOK, Description: "the BgpConf", Body: v1alpha2.BgpConf{}},
+			},
+			Handler: b.get,
+		},
+		{
+			Method: http.MethodPatch, Path: "/apis/v1/bgp/conf", Resource: bgpConfResource, Verb: "patch",
+			OperationID: "patchBgpConf", Summary: "Patch a BgpConf with a merge patch",
+			Body:            json.RawMessage{},
+			BodyContentType: "application/merge-patch+json",
+			Responses: []lib.Response{
+				{Status: http.StatusOK, Description: "updated", Body: handler.Update{}},
+			},
+			Handler: b.patch,
+		},
+		{
+			Method: http.MethodPut, Path: "/apis/v1/bgp/conf", Resource: bgpConfResource, Verb: "update",
+			OperationID: "updateBgpConf", Summary: "Update a BgpConf",
+			Body: v1alpha2.BgpConf{},
+			Responses: []lib.Response{
+				{Status: http.StatusOK, Description: "updated", Body: handler.Update{}},
+			},
+			Handler: b.update,
+		},
+		{
+			Method: http.MethodDelete, Path: "/apis/v1/bgp/conf", Resource: bgpConfResource, Verb: "delete",
+			OperationID: "deleteBgpConf", Summary: "Delete a BgpConf",
+			Responses: []lib.Response{
+				{Status: http.StatusNoContent, Description: "deleted"},
+			},
+			Handler: b.delete,
+		},
+	}
 }
 
 // NewBgpConfRouter returns a new instance of bgpConfRouter which
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -9,17 +10,80 @@ import (
 	"github.com/openelb/openelb/pkg/server/internal/lib"
 )
 
+// bgpPeerResource is the resource of BgpPeer authorized by the requests.
+var bgpPeerResource = lib.Resource{Group: v1alpha2.GroupVersion.Group, Resource: "bgppeers"}
+
 type bgpPeerRouter struct {
 	handler handler.BgpPeerHandler
 }
 
 func (b *bgpPeerRouter) Register(r chi.Router) {
-	r.Post("/apis/v1/bgp", b.create)
-	r.Get("/apis/v1/bgp/{name}", b.get)
-	r.Get("/apis/v1/bgp", b.list)
-	r.Patch("/apis/v1/bgp/{name}", b.patch)
-	r.Put("/apis/v1/bgp/{name}", b.update)
-	r.Delete("/apis/v1/bgp/{name}", b.delete)
+	lib.RegisterRoutes(r, b.Routes())
+}
+
+func (b *bgpPeerRouter) Routes() []lib.Route {
+	return []lib.Route{
+		{
+			Method: http.MethodPost, Path: "/apis/v1/bgp", Resource: bgpPeerResource, Verb: "create",
+			OperationID: "createBgpPeer", Summary: "Create a BgpPeer",
+			Query: []lib.Param{dryRunParam},
+			Body:  v1alpha2.BgpPeer{},
+			Responses: []lib.Response{
+				{Status: http.StatusCreated, Description: "created", Body: handler.Create{}},
+				{Status: http.StatusOK, Description: "the result of the dry run", Body: handler.DryRun{}},
+			},
+			Handler: b.create,
+		},
+		{
+			Method: http.MethodGet, Path: "/apis/v1/bgp/{name}", Resource: bgpPeerResource, Verb: "get",
+			OperationID: "getBgpPeer", Summary: "Get a BgpPeer",
+			Responses: []lib.Response{
+				{Status: http.StatusOK, Description: "the BgpPeer", Body: v1alpha2.BgpPeer{}},
+			},
+			Handler: b.get,
+		},
+		{
+			Method: http.MethodGet, Path: "/apis/v1/bgp", Resource: bgpPeerResource, Verb: "list",
+			OperationID: "listBgpPeers", Summary: "List the BgpPeers",
+			Responses: []lib.Response{
+				{Status: http.StatusOK, Description: "the BgpPeers", Body: v1alpha2.BgpPeerList{}},
+			},
+			Handler: b.list,
+		},
+		{
+			Method: http.MethodPatch, Path: "/apis/v1/bgp/{name}", Resource: bgpPeerResource, Verb: "patch",
+			OperationID: "patchBgpPeer", Summary: "Patch a BgpPeer with a merge patch",
+			Query:           []lib.Param{dryRunParam},
+			Body:            json.RawMessage{},
+			BodyContentType: "application/merge-patch+json",
+			Responses: []lib.Response{
+				{Status: http.StatusOK, Description: "updated", Body: handler.Update{}},
+				{Status: http.StatusOK, Description: "the result of the dry run", Body: handler.DryRun{}},
+			},
+			Handler: b.patch,
+		},
+		{
+			Method: http.MethodPut, Path: "/apis/v1/bgp/{name}", Resource: bgpPeerResource, Verb: "update",
+			OperationID: "updateBgpPeer", Summary: "Update a BgpPeer",
+			Query: []lib.Param{dryRunParam},
+			Body:  v1alpha2.BgpPeer{},
+			Responses: []lib.Response{
+				{Status: http.StatusOK, Description: "updated", Body: handler.Update{}},
+				{Status: http.StatusOK, Description: "the result of the dry run", Body: handler.DryRun{}},
+			},
+			Handler: b.update,
+		},
+		{
+			Method: http.MethodDelete, Path: "/apis/v1/bgp/{name}", Resource: bgpPeerResource, Verb: "delete",
+			OperationID: "deleteBgpPeer", Summary: "Delete a BgpPeer",
+			Query: []lib.Param{dryRunParam},
+			Responses: []lib.Response{
+				{Status: http.StatusNoContent, Description: "deleted"},
+				{Status: http.StatusOK, Description: "the result of the dry run", Body: handler.DryRun{}},
+			},
+			Handler: b.delete,
+		},
+	}
 }
 
 // NewBgpPeerRouter returns a new instance of bgpPeerRouter which
@@ -33,14 +97,21 @@ func NewBgpPeerRouter(handler handler.BgpPeerHandler) *bgpPeerRouter {
 
 func (b *bgpPeerRouter) create(w http.ResponseWriter, r *http.Request) {
 	var bgpPeer v1alpha2.BgpPeer
+	dry, err := dryRun(r)
 	lib.ServeRequest(lib.InboundRequest{
 		W: w,
 		R: r,
 		EndpointLogic: func() (interface{}, error) {
+			if err != nil {
+				return nil, err
+			}
+			if dry {
+				return b.handler.DryRun(r.Context(), handler.OperationCreate, bgpPeer.Name, &bgpPeer, nil)
+			}
 			return b.handler.Create(r.Context(), &bgpPeer)
 		},
 		ReqBody:    &bgpPeer,
-		StatusCode: http.StatusCreated,
+		StatusCode: statusCode(dry, http.StatusCreated),
 	})
 }
 
@@ -70,39 +141,60 @@ func (b *bgpPeerRouter) list(w http.ResponseWriter, r *http.Request) {
 func (b *bgpPeerRouter) patch(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "name")
 	var patch []byte
+	dry, err := dryRun(r)
 	lib.ServeRequest(lib.InboundRequest{
 		W: w,
 		R: r,
 		EndpointLogic: func() (interface{}, error) {
+			if err != nil {
+				return nil, err
+			}
+			if dry {
+				return b.handler.DryRun(r.Context(), handler.OperationPatch, name, nil, patch)
+			}
 			return b.handler.Patch(r.Context(), name, patch)
 		},
 		ReqBody:    &patch,
-		StatusCode: http.StatusOK,
+		StatusCode: statusCode(dry, http.StatusOK),
 	})
 }
 
 func (b *bgpPeerRouter) update(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "name")
 	var bgpPeer v1alpha2.BgpPeer
+	dry, err := dryRun(r)
 	lib.ServeRequest(lib.InboundRequest{
 		W: w,
 		R: r,
 		EndpointLogic: func() (interface{}, error) {
+			if err != nil {
+				return nil, err
+			}
+			if dry {
+				return b.handler.DryRun(r.Context(), handler.OperationUpdate, name, &bgpPeer, nil)
+			}
 			return b.handler.Update(r.Context(), name, &bgpPeer)
 		},
 		ReqBody:    &bgpPeer,
-		StatusCode: http.StatusOK,
+		StatusCode: statusCode(dry, http.StatusOK),
 	})
 }
 
 func (b *bgpPeerRouter) delete(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "name")
+	dry, err := dryRun(r)
 	lib.ServeRequest(lib.InboundRequest{
 		W: w,
 		R: r,
 		EndpointLogic: func() (interface{}, error) {
+			if err != nil {
+				return nil, err
+			}
+			if dry {
+				return b.handler.DryRun(r.Context(), handler.OperationDelete, name, nil, nil)
+			}
 			return b.handler.Delete(r.Context(), name)
 		},
-		StatusCode: http.StatusNoContent,
+		StatusCode: statusCode(dry, http.StatusNoContent),
 	})
 }
@@ -0,0 +1,55 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/openelb/openelb/pkg/server/internal/handler"
+	"github.com/openelb/openelb/pkg/server/internal/lib"
+)
+
+type bgpSessionRouter struct {
+	handler handler.BgpSessionHandler
+}
+
+func (b *bgpSessionRouter) Register(r chi.Router) {
+	lib.RegisterRoutes(r, b.Routes())
+}
+
+func (b *bgpSessionRouter) Routes() []lib.Route {
+	return []lib.Route{
+		{
+			Method: http.MethodGet, Path: "/apis/v1/bgp/sessions", Resource: bgpPeerResource, Verb: "list",
+			OperationID: "listBgpSessions", Summary: "List the sessions of the BgpPeers on the nodes",
+			Query: listParams,
+			Responses: []lib.Response{
+				{Status: http.StatusOK, Description: "the sessions", Body: listOf(handler.BgpSession{})},
+			},
+			Handler: b.list,
+		},
+	}
+}
+
+// NewBgpSessionRouter returns a new instance of bgpSessionRouter which
+// implements the Router interface. This is used to register the endpoints to
+// the router.
+func NewBgpSessionRouter(handler handler.BgpSessionHandler) *bgpSessionRouter {
+	return &bgpSessionRouter{
+		handler: handler,
+	}
+}
+
+func (b *bgpSessionRouter) list(w http.ResponseWriter, r *http.Request) {
+	lib.ServeRequest(lib.InboundRequest{
+		W: w,
+		R: r,
+		EndpointLogic: func() (interface{}, error) {
+			opts, err := listOptions(r)
+			if err != nil {
+				return nil, err
+			}
+			return b.handler.List(r.Context(), opts)
+		},
+		StatusCode: http.StatusOK,
+	})
+}
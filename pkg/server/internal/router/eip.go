@@ -1,6 +1,7 @@
 package router
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -9,17 +10,80 @@ import (
 	"github.com/openelb/openelb/pkg/server/internal/lib"
 )
 
+// eipResource is the resource of Eip authorized by the requests.
+var eipResource = lib.Resource{Group: v1alpha2.GroupVersion.Group, Resource: "eips"}
+
 type eipRouter struct {
 	handler handler.EipHandler
 }
 
 func (e *eipRouter) Register(r chi.Router) {
-	r.Post("/apis/v1/eip", e.create)
-	r.Get("/apis/v1/eip/{name}", e.get)
-	r.Get("/apis/v1/eip", e.list)
-	r.Patch("/apis/v1/eip/{name}", e.patch)
-	r.Put("/apis/v1/eip/{name}", e.update)
-	r.Delete("/apis/v1/eip/{name}", e.delete)
+	lib.RegisterRoutes(r, e.Routes())
+}
+
+func (e *eipRouter) Routes() []lib.Route {
+	return []lib.Route{
+		{
+			Method: http.MethodPost, Path: "/apis/v1/eip", Resource: eipResource, Verb: "create",
+			OperationID: "createEip", Summary: "Create an Eip",
+			Query: []lib.Param{dryRunParam},
+			Body:  v1alpha2.Eip{},
+			Responses: []lib.Response{
+				{Status: http.StatusCreated, Description: "created", Body: handler.Create{}},
+				{Status: http.StatusOK, Description: "the result of the dry run", Body: handler.DryRun{}},
+			},
+			Handler: e.create,
+		},
+		{
+			Method: http.MethodGet, Path: "/apis/v1/eip/{name}", Resource: eipResource, Verb: "get",
+			OperationID: "getEip", Summary: "Get an Eip",
+			Responses: []lib.Response{
+				{Status: http.StatusOK, Description: "the Eip", Body: v1alpha2.Eip{}},
+			},
+			Handler: e.get,
+		},
+		{
+			Method: http.MethodGet, Path: "/apis/v1/eip", Resource: eipResource, Verb: "list",
+			OperationID: "listEips", Summary: "List the Eips",
+			Responses: []lib.Response{
+				{Status: http.StatusOK, Description: "the Eips", Body: v1alpha2.EipList{}},
+			},
+			Handler: e.list,
+		},
+		{
+			Method: http.MethodPatch, Path: "/apis/v1/eip/{name}", Resource: eipResource, Verb: "patch",
+			OperationID: "patchEip", Summary: "Patch an Eip with a merge patch",
+			Query:           []lib.Param{dryRunParam},
+			Body:            json.RawMessage{},
+			BodyContentType: "application/merge-patch+json",
+			Responses: []lib.Response{
+				{Status: http.StatusOK, Description: "updated", Body: handler.Update{}},
+				{Status: http.StatusOK, Description: "the result of the dry run", Body: handler.DryRun{}},
+			},
+			Handler: e.patch,
+		},
+		{
+			Method: http.MethodPut, Path: "/apis/v1/eip/{name}", Resource: eipResource, Verb: "update",
+			OperationID: "updateEip", Summary: "Update an Eip",
+			Query: []lib.Param{dryRunParam},
+			Body:  v1alpha2.Eip{},
+			Responses: []lib.Response{
+				{Status: http.StatusOK, Description: "updated", Body: handler.Update{}},
+				{Status: http.StatusOK, Description: "the result of the dry run", Body: handler.DryRun{}},
+			},
+			Handler: e.update,
+		},
+		{
+			Method: http.MethodDelete, Path: "/apis/v1/eip/{name}", Resource: eipResource, Verb: "delete",
+			OperationID: "deleteEip", Summary: "Delete an Eip",
+			Query: []lib.Param{dryRunParam},
+			Responses: []lib.Response{
+				{Status: http.StatusNoContent, Description: "deleted"},
+				{Status: http.StatusOK, Description: "the result of the dry run", Body: handler.DryRun{}},
+			},
+			Handler: e.delete,
+		},
+	}
 }
 
 // NewEipRouter returns a new instance of eipRouter which implements the
@@ -32,14 +96,21 @@ func NewEipRouter(handler handler.EipHandler) *eipRouter {
 
 func (e *eipRouter) create(w http.ResponseWriter, r *http.Request) {
 	var eip v1alpha2.Eip
+	dry, err := dryRun(r)
 	lib.ServeRequest(lib.InboundRequest{
 		W: w,
 		R: r,
 		EndpointLogic: func() (interface{}, error) {
+			if err != nil {
+				return nil, err
+			}
+			if dry {
+				return e.handler.DryRun(r.Context(), handler.OperationCreate, eip.Name, &eip, nil)
+			}
 			return e.handler.Create(r.Context(), &eip)
 		},
 		ReqBody:    &eip,
-		StatusCode: http.StatusCreated,
+		StatusCode: statusCode(dry, http.StatusCreated),
 	})
 }
 
@@ -68,39 +139,60 @@ func (e *eipRouter) list(w http.ResponseWriter, r *http.Request) {
 func (e *eipRouter) patch(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "name")
 	var patch []byte
+	dry, err := dryRun(r)
 	lib.ServeRequest(lib.InboundRequest{
 		W: w,
 		R: r,
 		EndpointLogic: func() (interface{}, error) {
+			if err != nil {
+				return nil, err
+			}
+			if dry {
+				return e.handler.DryRun(r.Context(), handler.OperationPatch, name, nil, patch)
+			}
 			return e.handler.Patch(r.Context(), name, patch)
 		},
 		ReqBody:    &patch,
-		StatusCode: http.StatusOK,
+		StatusCode: statusCode(dry, http.StatusOK),
 	})
 }
 
 func (b *eipRouter) update(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "name")
 	var eip v1alpha2.Eip
+	dry, err := dryRun(r)
 	lib.ServeRequest(lib.InboundRequest{
 		W: w,
 		R: r,
 		EndpointLogic: func() (interface{}, error) {
+			if err != nil {
+				return nil, err
+			}
+			if dry {
+				return b.handler.DryRun(r.Context(), handler.OperationUpdate, name, &eip, nil)
+			}
 			return b.handler.Update(r.Context(), name, &eip)
 		},
 		ReqBody:    &eip,
-		StatusCode: http.StatusOK,
+		StatusCode: statusCode(dry, http.StatusOK),
 	})
 }
 
 func (e *eipRouter) delete(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "name")
+	dry, err := dryRun(r)
 	lib.ServeRequest(lib.InboundRequest{
 		W: w,
 		R: r,
 		EndpointLogic: func() (interface{}, error) {
+			if err != nil {
+				return nil, err
+			}
+			if dry {
+				return e.handler.DryRun(r.Context(), handler.OperationDelete, name, nil, nil)
+			}
 			return e.handler.Delete(r.Context(), name)
 		},
-		StatusCode: http.StatusNoContent,
+		StatusCode: statusCode(dry, http.StatusNoContent),
 	})
 }
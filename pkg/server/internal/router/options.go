@@ -0,0 +1,82 @@
+package router
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/openelb/openelb/pkg/server/internal/handler"
+	"github.com/openelb/openelb/pkg/server/internal/lib"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// listOptions parses the query params of the read-only views, e.g.
+// `?namespace=default&eip=eip-1&protocol=layer2&limit=20&continue=<token>`.
+func listOptions(r *http.Request) (handler.ListOptions, error) {
+	query := r.URL.Query()
+	opts := handler.ListOptions{
+		Namespace: query.Get("namespace"),
+		Eip:       query.Get("eip"),
+		Protocol:  query.Get("protocol"),
+		Node:      query.Get("node"),
+		Continue:  query.Get("continue"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return opts, errors.NewBadRequest("invalid limit " + limit)
+		}
+		opts.Limit = n
+	}
+	return opts, nil
+}
+
+// dryRun returns true if the change is only validated, `?dryRun=true` or
+// `?dryRun=All` as for the kube-apiserver.
+func dryRun(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("dryRun")
+	if value == "" {
+		return false, nil
+	}
+	if value == metav1.DryRunAll {
+		return true, nil
+	}
+	dry, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.NewBadRequest("invalid dryRun " + value)
+	}
+	return dry, nil
+}
+
+// statusCode returns the status code of a change, a dry run doesn't create
+// or delete anything.
+func statusCode(dry bool, code int) int {
+	if dry {
+		return http.StatusOK
+	}
+	return code
+}
+
+// dryRunParam is the query param of the changes validated without being
+// persisted.
+var dryRunParam = lib.Param{Name: "dryRun", Description: "validate the change and report its impact without persisting it, true or All"}
+
+// listParams are the query params of the read-only views.
+var listParams = []lib.Param{
+	{Name: "namespace", Description: "namespace of the services"},
+	{Name: "eip", Description: "name of the Eip"},
+	{Name: "protocol", Description: "protocol of the Eip"},
+	{Name: "node", Description: "name of the node"},
+	{Name: "limit", Description: "maximum number of items returned", Type: "integer"},
+	{Name: "continue", Description: "token of the next page returned by the previous list"},
+}
+
+// listOf returns a value of a page of the read-only view of the items for
+// the OpenAPI document, the items of handler.List are untyped.
+func listOf(item interface{}) interface{} {
+	return reflect.New(reflect.StructOf([]reflect.StructField{
+		{Name: "Items", Type: reflect.SliceOf(reflect.TypeOf(item)), Tag: `json:"items"`},
+		{Name: "Continue", Type: reflect.TypeOf(""), Tag: `json:"continue,omitempty"`},
+	})).Elem().Interface()
+}
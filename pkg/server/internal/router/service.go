@@ -0,0 +1,57 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/openelb/openelb/pkg/server/internal/handler"
+	"github.com/openelb/openelb/pkg/server/internal/lib"
+)
+
+// serviceResource is the resource of Service authorized by the requests.
+var serviceResource = lib.Resource{Resource: "services", Namespaced: true}
+
+type serviceRouter struct {
+	handler handler.ServiceHandler
+}
+
+func (s *serviceRouter) Register(r chi.Router) {
+	lib.RegisterRoutes(r, s.Routes())
+}
+
+func (s *serviceRouter) Routes() []lib.Route {
+	return []lib.Route{
+		{
+			Method: http.MethodGet, Path: "/apis/v1/services", Resource: serviceResource, Verb: "list",
+			OperationID: "listServices", Summary: "List the services exposed by the Eips",
+			Query: listParams,
+			Responses: []lib.Response{
+				{Status: http.StatusOK, Description: "the services", Body: listOf(handler.Service{})},
+			},
+			Handler: s.list,
+		},
+	}
+}
+
+// NewServiceRouter returns a new instance of serviceRouter which implements
+// the Router interface. This is used to register the endpoints to the router.
+func NewServiceRouter(handler handler.ServiceHandler) *serviceRouter {
+	return &serviceRouter{
+		handler: handler,
+	}
+}
+
+func (s *serviceRouter) list(w http.ResponseWriter, r *http.Request) {
+	lib.ServeRequest(lib.InboundRequest{
+		W: w,
+		R: r,
+		EndpointLogic: func() (interface{}, error) {
+			opts, err := listOptions(r)
+			if err != nil {
+				return nil, err
+			}
+			return s.handler.List(r.Context(), opts)
+		},
+		StatusCode: http.StatusOK,
+	})
+}
@@ -0,0 +1,69 @@
+package router
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/server/internal/handler"
+	"github.com/openelb/openelb/pkg/server/internal/lib"
+)
+
+// watchParams are the query params of the watch endpoints, the
+// Last-Event-ID header takes precedence over the resourceVersion.
+var watchParams = []lib.Param{
+	{Name: "resourceVersion", Description: "resume after the event with the id, the current objects are sent as added if empty"},
+}
+
+type watchRouter struct {
+	handler handler.WatchHandler
+}
+
+func (wr *watchRouter) Register(r chi.Router) {
+	lib.RegisterRoutes(r, wr.Routes())
+}
+
+func (wr *watchRouter) Routes() []lib.Route {
+	return []lib.Route{
+		wr.route("/apis/v1/watch/eip", eipResource, "watchEips", "Watch the changes of the Eips", v1alpha2.Eip{}, wr.handler.Eips),
+		wr.route("/apis/v1/watch/bgp", bgpPeerResource, "watchBgpPeers", "Watch the changes of the BgpPeers", v1alpha2.BgpPeer{}, wr.handler.BgpPeers),
+		wr.route("/apis/v1/watch/bgp/conf", bgpConfResource, "watchBgpConfs", "Watch the changes of the BgpConfs", v1alpha2.BgpConf{}, wr.handler.BgpConfs),
+		wr.route("/apis/v1/watch/allocations", eipResource, "watchAllocations", "Watch the changes of the allocations", handler.Allocation{}, wr.handler.Allocations),
+	}
+}
+
+// NewWatchRouter returns a new instance of watchRouter which implements the
+// Router interface. This is used to register the endpoints to the router.
+func NewWatchRouter(handler handler.WatchHandler) *watchRouter {
+	return &watchRouter{
+		handler: handler,
+	}
+}
+
+// route returns the route streaming the changes of the broadcaster as
+// server-sent events, the data of the events are described by obj.
+func (wr *watchRouter) route(path string, resource lib.Resource, operationID, summary string,
+	obj interface{}, broadcaster func() *lib.Broadcaster) lib.Route {
+	return lib.Route{
+		Method: http.MethodGet, Path: path, Resource: resource, Verb: "watch",
+		OperationID: operationID, Summary: summary,
+		Query: watchParams,
+		Responses: []lib.Response{
+			{Status: http.StatusOK, Description: "the stream of the events", Body: eventOf(obj), ContentType: "text/event-stream"},
+			{Status: http.StatusGone, Description: "the resourceVersion is too old, list again"},
+		},
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			lib.ServeWatch(w, r, broadcaster())
+		},
+	}
+}
+
+// eventOf returns a value of the data of the events of the object for the
+// OpenAPI document, the object of lib.Event is untyped.
+func eventOf(obj interface{}) interface{} {
+	return reflect.New(reflect.StructOf([]reflect.StructField{
+		{Name: "Type", Type: reflect.TypeOf(lib.Added), Tag: `json:"type"`},
+		{Name: "Object", Type: reflect.TypeOf(obj), Tag: `json:"object"`},
+	})).Elem().Interface()
+}
@@ -1,17 +1,27 @@
 package options
 
-import "github.com/spf13/pflag"
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+)
 
 type Options struct {
-	Port int
+	Port         int
+	EnableAuth   bool
+	AuthCacheTTL time.Duration
 }
 
 func NewOptions() *Options {
 	return &Options{
-		Port: 8080,
+		Port:         8080,
+		EnableAuth:   true,
+		AuthCacheTTL: 10 * time.Second,
 	}
 }
 
 func (options *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.IntVar(&options.Port, "http-port", options.Port, "The port that the http server serves at")
+	fs.BoolVar(&options.EnableAuth, "enable-auth", options.EnableAuth, "Authenticate the bearer token of the requests by TokenReview and authorize them by SubjectAccessReview")
+	fs.DurationVar(&options.AuthCacheTTL, "auth-cache-ttl", options.AuthCacheTTL, "The duration to cache the token and access reviews")
 }
@@ -1,22 +1,59 @@
 package server
 
 import (
+	"context"
+
 	"github.com/openelb/openelb/pkg/client"
 	"github.com/openelb/openelb/pkg/server/internal/handler"
 	"github.com/openelb/openelb/pkg/server/internal/lib"
 	"github.com/openelb/openelb/pkg/server/internal/router"
 	"github.com/openelb/openelb/pkg/server/options"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 )
 
-func SetupHTTPServer(stopCh <-chan struct{}, opts *options.Options) error {
+// SetupHTTPServer serves the REST API until the context is done, the watch
+// endpoints are backed by the informers of the cache.
+func SetupHTTPServer(ctx context.Context, kubeClient kubernetes.Interface, informers cache.Cache, opts *options.Options) error {
 	bgpConfService := handler.NewBgpConfHandler(client.Client)
 	bgpPeerService := handler.NewBgpPeerHandler(client.Client)
 	eipService := handler.NewEipHandler(client.Client)
+	allocationService := handler.NewAllocationHandler(client.Client, kubeClient)
+	serviceService := handler.NewServiceHandler(client.Client, kubeClient)
+	bgpSessionService := handler.NewBgpSessionHandler(client.Client)
+	watchService, err := handler.NewWatchHandler(ctx, informers)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := informers.Start(ctx); err != nil {
+			klog.Errorf("watch cache stopped: %v", err)
+		}
+	}()
+	if err := watchService.Synced(ctx); err != nil {
+		return err
+	}
+	go func() {
+		// The streams would hold the shutdown of the server
+		<-ctx.Done()
+		watchService.Close()
+	}()
+
+	var auth *lib.Auth
+	if opts.EnableAuth {
+		auth = lib.NewAuth(kubeClient, opts.AuthCacheTTL)
+	}
 
 	server := lib.NewHTTPServer([]lib.Router{
 		router.NewBgpConfRouter(bgpConfService),
 		router.NewBgpPeerRouter(bgpPeerService),
 		router.NewEipRouter(eipService),
-	}, *opts)
-	return server.ListenAndServe(stopCh)
+		router.NewAllocationRouter(allocationService),
+		router.NewServiceRouter(serviceService),
+		router.NewBgpSessionRouter(bgpSessionService),
+		router.NewWatchRouter(watchService),
+	}, *opts, auth)
+	return server.ListenAndServe(ctx.Done())
 }
@@ -1,12 +1,18 @@
 package bgp
 
 import (
+	"context"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	bgpapi "github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/constant"
+	api "github.com/osrg/gobgp/api"
+	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
@@ -28,7 +34,7 @@ var _ = BeforeSuite(func() {
 		GrpcHosts: ":50052",
 	}
 
-	b = NewGoBgpd(bgpOptions)
+	b = NewGoBgpd(bgpOptions, nil)
 	ch = make(chan struct{})
 
 	go b.Start(ch)
@@ -42,7 +48,7 @@ var _ = AfterSuite(func() {
 var _ = Describe("BGP test", func() {
 	Context("Create/Update/Delete BgpConf", func() {
 		It("Add BgpConf", func() {
-			err := b.HandleBgpGlobalConfig(&bgpapi.BgpConf{
+			err := b.HandleBgpGlobalConfig(context.Background(), &bgpapi.BgpConf{
 				Spec: bgpapi.BgpConfSpec{
 					As:         65003,
 					RouterId:   "10.0.255.254",
@@ -53,7 +59,7 @@ var _ = Describe("BGP test", func() {
 		})
 
 		It("Update BgpConf", func() {
-			err := b.HandleBgpGlobalConfig(&bgpapi.BgpConf{
+			err := b.HandleBgpGlobalConfig(context.Background(), &bgpapi.BgpConf{
 				Spec: bgpapi.BgpConfSpec{
 					As:         65002,
 					RouterId:   "10.0.255.253",
@@ -64,7 +70,7 @@ var _ = Describe("BGP test", func() {
 		})
 
 		It("Delete BgpConf", func() {
-			err := b.HandleBgpGlobalConfig(&bgpapi.BgpConf{
+			err := b.HandleBgpGlobalConfig(context.Background(), &bgpapi.BgpConf{
 				Spec: bgpapi.BgpConfSpec{
 					RouterId: "10.0.255.254",
 				},
@@ -86,7 +92,7 @@ var _ = Describe("BGP test", func() {
 		})
 
 		It("Add BgpConf", func() {
-			err := b.HandleBgpGlobalConfig(&bgpapi.BgpConf{
+			err := b.HandleBgpGlobalConfig(context.Background(), &bgpapi.BgpConf{
 				Spec: bgpapi.BgpConfSpec{
 					As:         65003,
 					RouterId:   "10.0.255.254",
@@ -179,5 +185,127 @@ var _ = Describe("BGP test", func() {
 				Expect(len(toDelete)).Should(Equal(0))
 			})
 		})
+
+		Context("Hitless BgpConf changes", func() {
+			ip := "100.100.100.101"
+			nexthops := []string{"1.1.1.1"}
+			conf := &bgpapi.BgpConf{
+				Spec: bgpapi.BgpConfSpec{
+					As:         65003,
+					RouterId:   "10.0.255.254",
+					ListenPort: 17900,
+				},
+			}
+
+			It("Should keep the sessions and routes if the policy changes", func() {
+				Expect(b.SetBalancer(ip, []corev1.Node{{
+					Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+						{Type: corev1.NodeInternalIP, Address: nexthops[0]},
+					}},
+				}})).ShouldNot(HaveOccurred())
+
+				cm := &corev1.ConfigMap{Data: map[string]string{
+					constant.OpenELBBgpName: "[global.apply-policy.config]\n  default-export-policy = \"accept-route\"\n",
+				}}
+				Expect(b.HandleBgpGlobalConfig(context.Background(), conf, "", false, cm)).ShouldNot(HaveOccurred())
+
+				Expect(b.getPeers()).Should(HaveLen(1))
+				err, toAdd, _ := b.retriveRoutes(ip, 32, nexthops)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(toAdd).Should(BeEmpty())
+			})
+
+			It("Should keep the sessions and report the fields changed without the as", func() {
+				conf.Spec.UseMultiplePaths = true
+				Expect(b.HandleBgpGlobalConfig(context.Background(), conf, "", false, nil)).ShouldNot(HaveOccurred())
+
+				Expect(b.getPeers()).Should(HaveLen(1))
+				Expect(b.global.UseMultiplePaths).Should(BeFalse())
+				Expect(b.GetBgpConfStatus().Status.NodesConfStatus).Should(ContainElement(
+					HaveField("PendingFields", Equal([]string{"useMultiplePaths"}))))
+				err, toAdd, _ := b.retriveRoutes(ip, 32, nexthops)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(toAdd).Should(BeEmpty())
+			})
+
+			It("Should restart and inject the routes again if the as changes", func() {
+				conf.Spec.As = 65004
+				Expect(b.HandleBgpGlobalConfig(context.Background(), conf, "", false, nil)).ShouldNot(HaveOccurred())
+
+				Expect(b.getPeers()).Should(BeEmpty())
+				err, toAdd, _ := b.retriveRoutes(ip, 32, nexthops)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(toAdd).Should(BeEmpty())
+				Expect(b.global.As).Should(Equal(uint32(65004)))
+				Expect(b.global.UseMultiplePaths).Should(BeTrue())
+				Expect(b.pending).Should(BeEmpty())
+			})
+
+			It("Should restore the missing routes and delete the orphaned ones", func() {
+				orphan := "100.100.100.102"
+				Expect(b.addMultiRoutes(orphan, 32, nexthops)).ShouldNot(HaveOccurred())
+				Expect(b.deleteMultiRoutes(ip, 32, nexthops)).ShouldNot(HaveOccurred())
+
+				missing, err := b.syncRIB()
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(missing).Should(BeTrue())
+				err, toAdd, _ := b.retriveRoutes(ip, 32, nexthops)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(toAdd).Should(BeEmpty())
+				err, toAdd, _ = b.retriveRoutes(orphan, 32, nexthops)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(toAdd).Should(Equal(nexthops))
+				Expect(b.ListBalancers()).Should(Equal([]string{ip}))
+
+				// the balancers aren't reloaded if no path is missing
+				missing, err = b.syncRIB()
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(missing).Should(BeFalse())
+
+				Expect(b.DelBalancer(ip)).ShouldNot(HaveOccurred())
+				Expect(b.ListBalancers()).Should(BeEmpty())
+			})
+		})
+	})
+})
+
+var _ = Describe("reload", func() {
+	It("Should return when the manager stops without a receiver", func() {
+		b := &Bgp{reloadChan: make(chan event.GenericEvent)}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			b.reload(ctx.Done())
+			close(done)
+		}()
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+})
+
+var _ = Describe("diffGlobalConf", func() {
+	It("Should restart only if the as or the router id changes", func() {
+		applied := &api.Global{As: 65001, RouterId: "10.0.0.1", ListenPort: 179}
+		Expect(diffGlobalConf(nil, applied).restart).Should(BeTrue())
+
+		desired := proto.Clone(applied).(*api.Global)
+		Expect(diffGlobalConf(applied, desired)).Should(Equal(globalChange{}))
+
+		for _, change := range []func(*api.Global){
+			func(g *api.Global) { g.As = 65002 },
+			func(g *api.Global) { g.RouterId = "10.0.0.2" },
+		} {
+			desired := proto.Clone(applied).(*api.Global)
+			change(desired)
+			Expect(diffGlobalConf(applied, desired).restart).Should(BeTrue())
+		}
+
+		desired.ListenPort = 17900
+		desired.ListenAddresses = []string{"0.0.0.0"}
+		desired.UseMultiplePaths = true
+		desired.GracefulRestart = &api.GracefulRestart{Enabled: true}
+		Expect(diffGlobalConf(applied, desired)).Should(Equal(globalChange{
+			pending: []string{"listenPort", "listenAddresses", "useMultiplePaths", "gracefulRestart"},
+		}))
 	})
 })
@@ -1,17 +1,60 @@
 package bgp
 
 import (
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
 	bgpapi "github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/constant"
 	api "github.com/osrg/gobgp/api"
 	"golang.org/x/net/context"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
 )
 
-func (b *Bgp) HandleBgpGlobalConfig(global *bgpapi.BgpConf, rack string, delete bool, cm *corev1.ConfigMap) error {
+// globalChange is the difference between the applied and the desired global
+// config. gobgpd has no api to change the global config of a running server,
+// only the changes of the as or the router id restart it, the other fields
+// are left pending until the next restart instead of tearing down every
+// session.
+type globalChange struct {
+	restart bool
+	pending []string
+}
+
+func diffGlobalConf(applied, desired *api.Global) globalChange {
+	if applied == nil || applied.As != desired.As || applied.RouterId != desired.RouterId {
+		return globalChange{restart: true}
+	}
+
+	change := globalChange{}
+	if applied.ListenPort != desired.ListenPort {
+		change.pending = append(change.pending, "listenPort")
+	}
+	if !reflect.DeepEqual(applied.ListenAddresses, desired.ListenAddresses) {
+		change.pending = append(change.pending, "listenAddresses")
+	}
+	if !reflect.DeepEqual(applied.Families, desired.Families) {
+		change.pending = append(change.pending, "families")
+	}
+	if applied.UseMultiplePaths != desired.UseMultiplePaths {
+		change.pending = append(change.pending, "useMultiplePaths")
+	}
+	if !proto.Equal(applied.GracefulRestart, desired.GracefulRestart) {
+		change.pending = append(change.pending, "gracefulRestart")
+	}
+	return change
+}
+
+// HandleBgpGlobalConfig applies the BgpConf, ctx is the context of the
+// manager which cancels the reload after a restart.
+func (b *Bgp) HandleBgpGlobalConfig(ctx context.Context, global *bgpapi.BgpConf, rack string, delete bool, cm *corev1.ConfigMap) error {
 	b.rack = rack
 
 	if delete {
+		b.global = nil
+		b.policy = ""
+		b.pending = nil
 		return b.bgpServer.StopBgp(context.Background(), nil)
 	}
 
@@ -20,16 +63,72 @@ func (b *Bgp) HandleBgpGlobalConfig(global *bgpapi.BgpConf, rack string, delete
 		return err
 	}
 
+	change := diffGlobalConf(b.global, request)
+	if change.restart {
+		if b.global != nil {
+			klog.Infof("bgp as or router id changed, restarting gobgpd")
+		}
+		return b.restartBgp(ctx, request, cm)
+	}
+
+	if len(change.pending) != 0 && !reflect.DeepEqual(change.pending, b.pending) {
+		klog.Warningf("bgp global config %v can't be changed without restarting gobgpd, pending until the as or the router id changes", change.pending)
+	}
+	b.pending = change.pending
+
+	if policyOf(cm) == b.policy {
+		return nil
+	}
+	err = b.updatePolicy(cm)
+	if err != nil {
+		klog.Errorf("failed to update bgp policy: %v", err)
+		return err
+	}
+	b.policy = policyOf(cm)
+
+	// readvertise the routes with the new policy without resetting the sessions
+	return b.bgpServer.ResetPeer(context.Background(), &api.ResetPeerRequest{
+		Address:   "all",
+		Soft:      true,
+		Direction: api.ResetPeerRequest_BOTH,
+	})
+}
+
+// restartBgp restarts gobgpd with the global config, the paths of the
+// desired rib are added again after it's restarted. The peers are added back
+// by the reconciler of the BgpConf.
+func (b *Bgp) restartBgp(ctx context.Context, request *api.Global, cm *corev1.ConfigMap) error {
 	b.bgpServer.StopBgp(context.Background(), nil)
-	err = b.bgpServer.StartBgp(context.Background(), &api.StartBgpRequest{
+	b.global = nil
+	b.policy = ""
+	b.pending = nil
+	err := b.bgpServer.StartBgp(context.Background(), &api.StartBgpRequest{
 		Global: request,
 	})
 	if err != nil {
 		return err
 	}
+	b.global = request
+
+	if _, err := b.syncRIB(); err != nil {
+		klog.Errorf("failed to resync bgp paths: %v", err)
+	}
+	// the balancers are derived from the eips again, they may be changed
+	// while gobgpd was stopped
+	go b.reload(ctx.Done())
+
 	err = b.updatePolicy(cm)
 	if err != nil {
 		klog.Errorf("failed to update bgp policy: %v", err)
+		return err
+	}
+	b.policy = policyOf(cm)
+	return nil
+}
+
+func policyOf(cm *corev1.ConfigMap) string {
+	if cm == nil {
+		return ""
 	}
-	return err
+	return cm.Data[constant.OpenELBBgpName]
 }
@@ -2,6 +2,7 @@ package bgp
 
 import (
 	"sync"
+	"time"
 
 	"github.com/openelb/openelb/pkg/speaker"
 	api "github.com/osrg/gobgp/api"
@@ -9,18 +10,27 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 )
 
 var _ speaker.Speaker = &Bgp{}
 
-func NewGoBgpd(bgpOptions *BgpOptions) *Bgp {
+func NewGoBgpd(bgpOptions *BgpOptions, reloadChan chan event.GenericEvent) *Bgp {
 	maxSize := 4 << 20 //4MB
 	grpcOpts := []grpc.ServerOption{grpc.MaxRecvMsgSize(maxSize), grpc.MaxSendMsgSize(maxSize)}
 
 	bgpServer := server.NewBgpServer(server.GrpcListenAddress(bgpOptions.GrpcHosts), server.GrpcOption(grpcOpts))
 
+	resyncPeriod := bgpOptions.ResyncPeriod
+	if resyncPeriod <= 0 {
+		resyncPeriod = NewBgpOptions().ResyncPeriod
+	}
+
 	return &Bgp{
-		bgpServer: bgpServer,
+		bgpServer:    bgpServer,
+		rib:          newRib(),
+		resyncPeriod: resyncPeriod,
+		reloadChan:   reloadChan,
 	}
 }
 
@@ -28,7 +38,16 @@ func (b *Bgp) Start(stopCh <-chan struct{}) error {
 	klog.Info("gobgpd starting")
 	go b.bgpServer.Serve()
 
-	<-stopCh
+	ticker := time.NewTicker(b.resyncPeriod)
+	defer ticker.Stop()
+	for running := true; running; {
+		select {
+		case <-stopCh:
+			running = false
+		case <-ticker.C:
+			b.resync(stopCh)
+		}
+	}
 	klog.Info("gobgpd ending")
 	err := b.bgpServer.StopBgp(context.Background(), &api.StopBgpRequest{})
 	if err != nil {
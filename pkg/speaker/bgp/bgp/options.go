@@ -1,25 +1,69 @@
 package bgp
 
 import (
+	"time"
+
+	"github.com/openelb/openelb/pkg/constant"
+	api "github.com/osrg/gobgp/api"
 	"github.com/osrg/gobgp/pkg/server"
 	"github.com/spf13/pflag"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 )
 
 type BgpOptions struct {
-	GrpcHosts string `long:"api-hosts" description:"specify the hosts that gobgpd listens on" default:":50051"`
+	GrpcHosts    string `long:"api-hosts" description:"specify the hosts that gobgpd listens on" default:":50051"`
+	ResyncPeriod time.Duration
+	// the interval to restore the port forward rules of gobgpd
+	PortForwardResyncPeriod time.Duration
+	Backend                 string
+	Frr                     *FrrOptions
+}
+
+// FrrOptions are the options of the frr backend, the config of frr is
+// rendered to ConfigPath and applied by the ReloadCmd.
+type FrrOptions struct {
+	ConfigPath string
+	// the config of frr not managed by openelb, prepended to the rendered one
+	BaseConfigPath string
+	ReloadCmd      string
+	Vtysh          string
 }
 
 func NewBgpOptions() *BgpOptions {
 	return &BgpOptions{
-		GrpcHosts: ":50051",
+		GrpcHosts:               ":50051",
+		ResyncPeriod:            30 * time.Second,
+		PortForwardResyncPeriod: 30 * time.Second,
+		Backend:                 constant.BgpBackendGoBgp,
+		Frr: &FrrOptions{
+			ConfigPath: "/etc/frr/frr.conf",
+			ReloadCmd:  "/usr/lib/frr/frr-reload.py --reload",
+			Vtysh:      "vtysh",
+		},
 	}
 }
 
 func (options *BgpOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&options.GrpcHosts, "api-hosts", options.GrpcHosts, "specify the hosts that gobgpd listens on")
+	fs.DurationVar(&options.ResyncPeriod, "bgp-resync-period", options.ResyncPeriod, "specify the interval to restore the paths missing in gobgpd and delete the orphaned ones")
+	fs.DurationVar(&options.PortForwardResyncPeriod, "bgp-port-forward-resync-period", options.PortForwardResyncPeriod, "specify the interval to restore the rules forwarding the bgp connections to gobgpd on the nodes of calico")
+	fs.StringVar(&options.Backend, "bgp-backend", options.Backend, "specify the bgp implementation of the bgp speaker, gobgp(embedded) or frr")
+	fs.StringVar(&options.Frr.ConfigPath, "frr-config", options.Frr.ConfigPath, "specify the path of the config file rendered for frr, only for the frr backend")
+	fs.StringVar(&options.Frr.BaseConfigPath, "frr-base-config", options.Frr.BaseConfigPath, "specify the path of the frr config not managed by openelb, prepended to the rendered config")
+	fs.StringVar(&options.Frr.ReloadCmd, "frr-reload-cmd", options.Frr.ReloadCmd, "specify the command applying the frr config, the path of the config is appended")
+	fs.StringVar(&options.Frr.Vtysh, "frr-vtysh", options.Frr.Vtysh, "specify the vtysh of frr, used to query the status of the peers")
 }
 
 type Bgp struct {
 	bgpServer *server.BgpServer
 	rack      string
+	// the global config and the policy applied to gobgpd
+	global *api.Global
+	policy string
+	// the fields of the global config waiting for gobgpd to restart
+	pending []string
+
+	rib          *rib
+	resyncPeriod time.Duration
+	reloadChan   chan event.GenericEvent
 }
@@ -179,11 +179,6 @@ func (b *Bgp) getPeers() []*api.Peer {
 }
 
 func (b *Bgp) SetBalancer(ip string, nodes []corev1.Node) error {
-	err := b.ready()
-	if err != nil {
-		return err
-	}
-
 	var nexthops []string
 	for _, node := range nodes {
 		rack := ""
@@ -199,6 +194,12 @@ func (b *Bgp) SetBalancer(ip string, nodes []corev1.Node) error {
 		}
 	}
 
+	// the paths are added by the resync if gobgpd isn't ready
+	b.rib.set(ip, nexthops)
+	if err := b.ready(); err != nil {
+		return err
+	}
+
 	klog.Infof("bgp setBalancer ip:%s nexthops:%s", ip, nexthops)
 	return b.setBalancer(ip, nexthops)
 }
@@ -240,6 +241,7 @@ func (b *Bgp) deleteMultiRoutes(ip string, prefix uint32, nexthops []string) err
 }
 
 func (b *Bgp) DelBalancer(ip string) error {
+	b.rib.delete(ip)
 	err := b.ready()
 	if err != nil {
 		klog.Warning(err)
@@ -96,8 +96,9 @@ func (b *Bgp) GetBgpConfStatus() bgpapi.BgpConf {
 		Status: bgpapi.BgpConfStatus{
 			NodesConfStatus: map[string]bgpapi.NodeConfStatus{
 				util.GetNodeName(): {
-					RouterId: result.Global.RouterId,
-					As:       result.Global.As,
+					RouterId:      result.Global.RouterId,
+					As:            result.Global.As,
+					PendingFields: b.pending,
 				},
 			},
 		},
@@ -163,6 +164,12 @@ func (b *Bgp) HandleBgpPeer(neighbor *bgpapi.BgpPeer, delete bool) error {
 }
 
 func (b *Bgp) UpdatePeerMetrics(peer *bgpapi.BgpPeer, delete bool) {
+	UpdatePeerMetrics(peer, delete)
+}
+
+// UpdatePeerMetrics updates the metrics of the session of the peer on the
+// node from its status, it's shared by the bgp backends.
+func UpdatePeerMetrics(peer *bgpapi.BgpPeer, delete bool) {
 	status := peer.Status
 	for node, peerStatus := range status.NodesPeerStatus {
 		var state float64 = 0
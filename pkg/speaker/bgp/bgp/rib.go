@@ -0,0 +1,149 @@
+package bgp
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/constant"
+	api "github.com/osrg/gobgp/api"
+	"golang.org/x/net/context"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// rib is the desired state of the paths of the balancers, the global rib of
+// gobgpd is synced to it after gobgpd is restarted and periodically.
+type rib struct {
+	lock     sync.Mutex
+	nexthops map[string][]string
+}
+
+func newRib() *rib {
+	return &rib{nexthops: make(map[string][]string)}
+}
+
+func (r *rib) set(ip string, nexthops []string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.nexthops[ip] = append([]string{}, nexthops...)
+}
+
+func (r *rib) delete(ip string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.nexthops, ip)
+}
+
+func (r *rib) list() map[string][]string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	result := make(map[string][]string, len(r.nexthops))
+	for ip, nexthops := range r.nexthops {
+		result[ip] = append([]string{}, nexthops...)
+	}
+	return result
+}
+
+// ListBalancers returns the ips of the balancers in the desired rib.
+func (b *Bgp) ListBalancers() []string {
+	ips := []string{}
+	for ip := range b.rib.list() {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// syncRIB adds the paths of the desired rib missing in the global rib of
+// gobgpd and deletes the local paths not in the desired rib, the paths
+// received from the peers are untouched. It returns true if some paths were
+// missing.
+func (b *Bgp) syncRIB() (bool, error) {
+	if err := b.ready(); err != nil {
+		return false, err
+	}
+
+	desired := b.rib.list()
+	// the number of the local paths of the desired ips
+	present := map[string]int{}
+	orphans := map[string][]*api.Path{}
+	for _, family := range []*api.Family{getFamily("0.0.0.0"), getFamily("::")} {
+		err := b.bgpServer.ListPath(context.Background(), &api.ListPathRequest{
+			TableType: api.TableType_GLOBAL,
+			Family:    family,
+		}, func(d *api.Destination) {
+			ip := strings.Split(d.Prefix, "/")[0]
+			_, ok := desired[ip]
+			for _, path := range d.Paths {
+				if net.ParseIP(path.NeighborIp) != nil {
+					continue
+				}
+				if ok {
+					present[ip]++
+				} else {
+					orphans[d.Prefix] = append(orphans[d.Prefix], path)
+				}
+			}
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for prefix, paths := range orphans {
+		klog.Infof("bgp resync delete orphaned paths of %s", prefix)
+		for _, path := range paths {
+			if err := b.bgpServer.DeletePath(context.Background(), &api.DeletePathRequest{Path: path}); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	missing := false
+	for ip, nexthops := range desired {
+		if present[ip] < len(nexthops) {
+			klog.Infof("bgp resync restore the missing paths of %s", ip)
+			missing = true
+		}
+		if err := b.setBalancer(ip, nexthops); err != nil {
+			return missing, err
+		}
+	}
+	return missing, nil
+}
+
+// resync syncs the global rib, and asks the speaker manager to derive the
+// balancers from the eips and services again only if some paths were
+// missing, since the reload sets every balancer.
+func (b *Bgp) resync(stopCh <-chan struct{}) {
+	missing, err := b.syncRIB()
+	if err != nil {
+		klog.V(4).Infof("bgp resync rib: %v", err)
+	}
+
+	if missing {
+		b.reload(stopCh)
+	}
+}
+
+// reload sends the reload event of the bgp speaker to the eip reconciler, the
+// same way as the layer2 speaker does when the members change.
+func (b *Bgp) reload(stopCh <-chan struct{}) {
+	if b.reloadChan == nil {
+		return
+	}
+
+	evt := v1alpha2.Eip{}
+	evt.Name = constant.BgpReloadEIPName
+	evt.Namespace = constant.BgpReloadEIPNamespace
+	select {
+	case b.reloadChan <- event.GenericEvent{Object: &evt}:
+	case <-stopCh:
+	}
+}
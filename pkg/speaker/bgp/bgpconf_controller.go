@@ -23,7 +23,6 @@ import (
 
 	"github.com/openelb/openelb/api/v1alpha2"
 	"github.com/openelb/openelb/pkg/constant"
-	bgpd "github.com/openelb/openelb/pkg/speaker/bgp/bgp"
 	"github.com/openelb/openelb/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -51,7 +50,7 @@ var (
 // BgpConfReconciler reconciles a BgpConf object
 type BgpConfReconciler struct {
 	client.Client
-	BgpServer *bgpd.Bgp
+	BgpServer BgpServer
 	record.EventRecorder
 }
 
@@ -73,7 +72,7 @@ func (r *BgpConfReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	clone := instance.DeepCopy()
 	if util.IsDeletionCandidate(clone, constant.FinalizerName) {
-		if err := r.BgpServer.HandleBgpGlobalConfig(clone, "", true, nil); err != nil {
+		if err := r.BgpServer.HandleBgpGlobalConfig(ctx, clone, "", true, nil); err != nil {
 			klog.Errorf("cannot delete bgp conf, maybe need to delete manually: %v", err)
 		}
 
@@ -110,10 +109,15 @@ func (r *BgpConfReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	if err != nil {
 		return ctrl.Result{}, err
 	}
-	err = r.BgpServer.HandleBgpGlobalConfig(clone, rack, false, cm)
+	err = r.BgpServer.HandleBgpGlobalConfig(ctx, clone, rack, false, cm)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	status := r.BgpServer.GetBgpConfStatus().Status.NodesConfStatus[util.GetNodeName()]
+	if len(status.PendingFields) != 0 {
+		r.Eventf(instance, corev1.EventTypeWarning, "PendingRestart", "bgp global config %v on node %s takes effect once the as or the router id changes",
+			status.PendingFields, util.GetNodeName())
+	}
 
 	if clone.Annotations == nil {
 		clone.Annotations = make(map[string]string)
@@ -169,7 +173,8 @@ func (r *BgpConfReconciler) reconfigPeers() error {
 	ctx := context.Background()
 
 	//Add all the neighbor that exist and match node back in, since
-	//the neighbor was reset when the global configuration was updated earlier.
+	//the neighbor was reset if gobgpd was restarted by the global configuration,
+	//updating an unchanged neighbor doesn't reset its session.
 	var peers v1alpha2.BgpPeerList
 	err := r.List(ctx, &peers)
 	if err != nil {
@@ -290,7 +295,7 @@ func (r *BgpConfReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctl.Watch(source.Kind(mgr.GetCache(), &corev1.Node{}), &EnqueueRequestForNode{Client: r.Client, peer: false}, np)
 }
 
-func SetupBgpConfReconciler(bgpServer *bgpd.Bgp, mgr ctrl.Manager) error {
+func SetupBgpConfReconciler(bgpServer BgpServer, mgr ctrl.Manager) error {
 	bgpConf := BgpConfReconciler{
 		Client:        mgr.GetClient(),
 		BgpServer:     bgpServer,
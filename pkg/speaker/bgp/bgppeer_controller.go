@@ -25,7 +25,6 @@ import (
 	"github.com/openelb/openelb/api/v1alpha2"
 	"github.com/openelb/openelb/pkg/constant"
 	"github.com/openelb/openelb/pkg/metrics"
-	bgpd "github.com/openelb/openelb/pkg/speaker/bgp/bgp"
 	"github.com/openelb/openelb/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -44,7 +43,7 @@ import (
 // BgpPeerReconciler reconciles a BgpPeer object
 type BgpPeerReconciler struct {
 	client.Client
-	BgpServer *bgpd.Bgp
+	BgpServer BgpServer
 	record.EventRecorder
 }
 
@@ -204,7 +203,7 @@ func (r BgpPeerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		}).Complete(r)
 }
 
-func SetupBgpPeerReconciler(bgpServer *bgpd.Bgp, mgr ctrl.Manager) error {
+func SetupBgpPeerReconciler(bgpServer BgpServer, mgr ctrl.Manager) error {
 	bgpPeer := BgpPeerReconciler{
 		Client:        mgr.GetClient(),
 		BgpServer:     bgpServer,
@@ -0,0 +1,137 @@
+package frr
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	bgpd "github.com/openelb/openelb/pkg/speaker/bgp/bgp"
+)
+
+// client applies the config to frr and queries the status of its peers.
+type client interface {
+	Reload(config []byte) error
+	Neighbors() (map[string]neighborStatus, error)
+}
+
+// neighborStatus is a neighbor of `show bgp neighbors json`.
+type neighborStatus struct {
+	RemoteAs       uint32 `json:"remoteAs"`
+	LocalAs        uint32 `json:"localAs"`
+	RemoteRouterId string `json:"remoteRouterId"`
+	NbrDesc        string `json:"nbrDesc"`
+	BgpState       string `json:"bgpState"`
+
+	ConfiguredHoldTimeMsecs  int64 `json:"bgpTimerConfiguredHoldTimeMsecs"`
+	ConfiguredKeepAliveMsecs int64 `json:"bgpTimerConfiguredKeepAliveIntervalMsecs"`
+	HoldTimeMsecs            int64 `json:"bgpTimerHoldTimeMsecs"`
+	ConnectRetryTimer        int64 `json:"connectRetryTimer"`
+
+	MessageStats struct {
+		OpensSent         int64 `json:"opensSent"`
+		OpensRecv         int64 `json:"opensRecv"`
+		NotificationsSent int64 `json:"notificationsSent"`
+		NotificationsRecv int64 `json:"notificationsRecv"`
+		UpdatesSent       int64 `json:"updatesSent"`
+		UpdatesRecv       int64 `json:"updatesRecv"`
+		KeepalivesSent    int64 `json:"keepalivesSent"`
+		KeepalivesRecv    int64 `json:"keepalivesRecv"`
+		RouteRefreshSent  int64 `json:"routeRefreshSent"`
+		RouteRefreshRecv  int64 `json:"routeRefreshRecv"`
+		TotalSent         int64 `json:"totalSent"`
+		TotalRecv         int64 `json:"totalRecv"`
+	} `json:"messageStats"`
+}
+
+// toNodePeerStatus converts the status to the one of gobgp, so the status of
+// the BgpPeers and the metrics don't depend on the backend.
+func (n neighborStatus) toNodePeerStatus(address string) v1alpha2.NodePeerStatus {
+	itoa := func(i int64) string { return strconv.FormatInt(i, 10) }
+	stats := n.MessageStats
+	return v1alpha2.NodePeerStatus{
+		PeerState: v1alpha2.PeerState{
+			Description:     n.NbrDesc,
+			LocalAs:         n.LocalAs,
+			NeighborAddress: address,
+			PeerAs:          n.RemoteAs,
+			SessionState:    strings.ToUpper(n.BgpState),
+			RouterId:        n.RemoteRouterId,
+			Messages: &v1alpha2.Messages{
+				Received: &v1alpha2.Message{
+					Notification: itoa(stats.NotificationsRecv),
+					Update:       itoa(stats.UpdatesRecv),
+					Open:         itoa(stats.OpensRecv),
+					Keepalive:    itoa(stats.KeepalivesRecv),
+					Refresh:      itoa(stats.RouteRefreshRecv),
+					Total:        itoa(stats.TotalRecv),
+				},
+				Sent: &v1alpha2.Message{
+					Notification: itoa(stats.NotificationsSent),
+					Update:       itoa(stats.UpdatesSent),
+					Open:         itoa(stats.OpensSent),
+					Keepalive:    itoa(stats.KeepalivesSent),
+					Refresh:      itoa(stats.RouteRefreshSent),
+					Total:        itoa(stats.TotalSent),
+				},
+			},
+		},
+		TimersState: v1alpha2.TimersState{
+			ConnectRetry:       itoa(n.ConnectRetryTimer),
+			HoldTime:           itoa(n.ConfiguredHoldTimeMsecs / 1000),
+			KeepaliveInterval:  itoa(n.ConfiguredKeepAliveMsecs / 1000),
+			NegotiatedHoldTime: itoa(n.HoldTimeMsecs / 1000),
+		},
+	}
+}
+
+// execClient writes the config to the file and runs the reload command and
+// vtysh of frr.
+type execClient struct {
+	opts *bgpd.FrrOptions
+}
+
+func (c *execClient) Reload(config []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(c.opts.ConfigPath), ".openelb-frr-*.conf")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(config); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.opts.ConfigPath); err != nil {
+		return err
+	}
+
+	args := append(strings.Fields(c.opts.ReloadCmd), c.opts.ConfigPath)
+	if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("reload frr: %v, %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (c *execClient) Neighbors() (map[string]neighborStatus, error) {
+	out, err := exec.Command(c.opts.Vtysh, "-c", "show bgp neighbors json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("query frr neighbors: %v", err)
+	}
+	return parseNeighbors(out)
+}
+
+func parseNeighbors(data []byte) (map[string]neighborStatus, error) {
+	neighbors := map[string]neighborStatus{}
+	if err := json.Unmarshal(data, &neighbors); err != nil {
+		return nil, fmt.Errorf("parse frr neighbors: %v", err)
+	}
+	return neighbors, nil
+}
@@ -0,0 +1,291 @@
+package frr
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	api "github.com/osrg/gobgp/api"
+)
+
+const (
+	familyIPv4 = "ipv4 unicast"
+	familyIPv6 = "ipv6 unicast"
+)
+
+// frrConfig is the config of frr rendered from the BgpConf, the BgpPeers and
+// the balancers announced by the node.
+type frrConfig struct {
+	Hostname string
+	// the config not managed by openelb
+	Base   string
+	Router *routerConfig
+}
+
+type routerConfig struct {
+	As              uint32
+	RouterId        string
+	MultiPath       bool
+	GracefulRestart *gracefulRestartConfig
+	Neighbors       []*neighborConfig
+	Families        []*familyConfig
+}
+
+type gracefulRestartConfig struct {
+	RestartTime   uint32
+	StalePathTime uint32
+}
+
+type neighborConfig struct {
+	// the address or the interface of the neighbor
+	Name          string
+	Interface     bool
+	RemoteAs      uint32
+	LocalAs       uint32
+	Description   string
+	Password      string
+	Port          uint32
+	Passive       bool
+	Multihop      uint32
+	KeepaliveTime uint32
+	HoldTime      uint32
+	ConnectRetry  uint32
+	Shutdown      bool
+}
+
+type familyConfig struct {
+	Name      string
+	Networks  []string
+	Neighbors []*familyNeighborConfig
+}
+
+type familyNeighborConfig struct {
+	Name      string
+	AllowAsIn uint32
+	// the arguments of remove-private-AS
+	RemovePrivateAs string
+}
+
+var configTemplate = template.Must(template.New("frr.conf").Parse(`! Generated by openelb-speaker, do not edit.
+frr defaults traditional
+hostname {{.Hostname}}
+!
+{{- if .Base}}
+{{.Base}}
+!
+{{- end}}
+{{- with .Router}}
+router bgp {{.As}}
+ bgp router-id {{.RouterId}}
+ no bgp ebgp-requires-policy
+ no bgp network import-check
+ no bgp default ipv4-unicast
+{{- if .MultiPath}}
+ bgp bestpath as-path multipath-relax
+{{- end}}
+{{- with .GracefulRestart}}
+ bgp graceful-restart
+{{- if .RestartTime}}
+ bgp graceful-restart restart-time {{.RestartTime}}
+{{- end}}
+{{- if .StalePathTime}}
+ bgp graceful-restart stalepath-time {{.StalePathTime}}
+{{- end}}
+{{- end}}
+{{- range .Neighbors}}
+ neighbor {{.Name}}{{if .Interface}} interface{{end}} remote-as {{.RemoteAs}}
+{{- if .LocalAs}}
+ neighbor {{.Name}} local-as {{.LocalAs}}
+{{- end}}
+{{- if .Description}}
+ neighbor {{.Name}} description {{.Description}}
+{{- end}}
+{{- if .Password}}
+ neighbor {{.Name}} password {{.Password}}
+{{- end}}
+{{- if .Port}}
+ neighbor {{.Name}} port {{.Port}}
+{{- end}}
+{{- if .Passive}}
+ neighbor {{.Name}} passive
+{{- end}}
+{{- if .Multihop}}
+ neighbor {{.Name}} ebgp-multihop {{.Multihop}}
+{{- end}}
+{{- if and .KeepaliveTime .HoldTime}}
+ neighbor {{.Name}} timers {{.KeepaliveTime}} {{.HoldTime}}
+{{- end}}
+{{- if .ConnectRetry}}
+ neighbor {{.Name}} timers connect {{.ConnectRetry}}
+{{- end}}
+{{- if .Shutdown}}
+ neighbor {{.Name}} shutdown
+{{- end}}
+{{- end}}
+{{- range .Families}}
+ !
+ address-family {{.Name}}
+{{- range .Networks}}
+  network {{.}}
+{{- end}}
+{{- range .Neighbors}}
+  neighbor {{.Name}} activate
+{{- if .AllowAsIn}}
+  neighbor {{.Name}} allowas-in {{.AllowAsIn}}
+{{- end}}
+{{- if .RemovePrivateAs}}
+  neighbor {{.Name}} remove-private-AS {{.RemovePrivateAs}}
+{{- end}}
+{{- end}}
+ exit-address-family
+{{- end}}
+exit
+!
+{{- end}}
+`))
+
+func (c *frrConfig) render() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := configTemplate.Execute(&buf, c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// newRouterConfig returns the config of the bgp router, the balancers are
+// announced by the node itself with the node as the nexthop.
+func newRouterConfig(global *v1alpha2.BgpConfSpec, peers map[string]*v1alpha2.BgpPeerSpec, balancers []string) *routerConfig {
+	if global == nil || global.As == 0 {
+		return nil
+	}
+
+	router := &routerConfig{
+		As:        global.As,
+		RouterId:  global.RouterId,
+		MultiPath: global.UseMultiplePaths,
+	}
+	if gr := global.GracefulRestart; gr != nil && gr.Enabled && !gr.HelperOnly {
+		router.GracefulRestart = &gracefulRestartConfig{
+			RestartTime:   gr.RestartTime,
+			StalePathTime: gr.StaleRoutesTime,
+		}
+	}
+
+	families := map[string]*familyConfig{
+		familyIPv4: {Name: familyIPv4},
+		familyIPv6: {Name: familyIPv6},
+	}
+	for _, ip := range balancers {
+		if net.ParseIP(ip).To4() != nil {
+			families[familyIPv4].Networks = append(families[familyIPv4].Networks, ip+"/32")
+		} else {
+			families[familyIPv6].Networks = append(families[familyIPv6].Networks, ip+"/128")
+		}
+	}
+
+	names := make([]string, 0, len(peers))
+	for name := range peers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		peer := peers[name]
+		router.Neighbors = append(router.Neighbors, newNeighborConfig(name, global.As, peer))
+
+		afi := &familyNeighborConfig{
+			Name:            name,
+			AllowAsIn:       peer.Conf.AllowOwnAs,
+			RemovePrivateAs: removePrivateAs(peer.Conf.RemovePrivateAs),
+		}
+		for _, family := range peerFamilies(peer) {
+			families[family].Neighbors = append(families[family].Neighbors, afi)
+		}
+	}
+
+	for _, name := range []string{familyIPv4, familyIPv6} {
+		if len(families[name].Networks) != 0 || len(families[name].Neighbors) != 0 {
+			router.Families = append(router.Families, families[name])
+		}
+	}
+	return router
+}
+
+func newNeighborConfig(name string, as uint32, peer *v1alpha2.BgpPeerSpec) *neighborConfig {
+	n := &neighborConfig{
+		Name:        name,
+		Interface:   peer.Conf.NeighborAddress == "",
+		RemoteAs:    peer.Conf.PeerAs,
+		Description: strings.Join(strings.Fields(peer.Conf.Description), " "),
+		Password:    peer.Conf.AuthPassword,
+		Shutdown:    peer.Conf.AdminDown,
+	}
+	if peer.Conf.LocalAs != 0 && peer.Conf.LocalAs != as {
+		n.LocalAs = peer.Conf.LocalAs
+	}
+	if peer.Transport != nil {
+		n.Port = peer.Transport.RemotePort
+		n.Passive = peer.Transport.PassiveMode
+	}
+	if peer.EbgpMultihop != nil && peer.EbgpMultihop.Enabled {
+		n.Multihop = peer.EbgpMultihop.MultihopTtl
+		if n.Multihop == 0 {
+			n.Multihop = 255
+		}
+	}
+	if peer.Timers != nil && peer.Timers.Config != nil {
+		n.KeepaliveTime = parseSeconds(peer.Timers.Config.KeepaliveInterval)
+		n.HoldTime = parseSeconds(peer.Timers.Config.HoldTime)
+		n.ConnectRetry = parseSeconds(peer.Timers.Config.ConnectRetry)
+	}
+	return n
+}
+
+// peerFamilies returns the families the peer is activated in, the family of
+// the neighbor address by default.
+func peerFamilies(peer *v1alpha2.BgpPeerSpec) []string {
+	families := []string{}
+	for _, afiSafi := range peer.AfiSafis {
+		if afiSafi == nil || afiSafi.Config == nil || afiSafi.Config.Family == nil || !afiSafi.Config.Enabled {
+			continue
+		}
+		if afiSafi.Config.Family.Safi != api.Family_SAFI_UNICAST.String() {
+			continue
+		}
+		switch afiSafi.Config.Family.Afi {
+		case api.Family_AFI_IP.String():
+			families = append(families, familyIPv4)
+		case api.Family_AFI_IP6.String():
+			families = append(families, familyIPv6)
+		}
+	}
+	if len(peer.AfiSafis) != 0 {
+		return families
+	}
+
+	if ip := net.ParseIP(peer.Conf.NeighborAddress); ip != nil && ip.To4() == nil {
+		return []string{familyIPv6}
+	}
+	return []string{familyIPv4}
+}
+
+func removePrivateAs(mode string) string {
+	switch mode {
+	case api.PeerConf_ALL.String():
+		return "all"
+	case api.PeerConf_REPLACE.String():
+		return "all replace-AS"
+	}
+	return ""
+}
+
+func parseSeconds(s string) uint32 {
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return uint32(n)
+}
@@ -0,0 +1,113 @@
+package frr
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the rendered frr config")
+
+func TestRenderConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		global    *v1alpha2.BgpConfSpec
+		peers     map[string]*v1alpha2.BgpPeerSpec
+		balancers []string
+		base      string
+	}{
+		{
+			name: "no-bgpconf",
+			base: "log syslog informational",
+		},
+		{
+			name:   "no-peers",
+			global: &v1alpha2.BgpConfSpec{As: 65001, RouterId: "10.0.0.1"},
+		},
+		{
+			name: "ipv4",
+			global: &v1alpha2.BgpConfSpec{
+				As:               65001,
+				RouterId:         "10.0.0.1",
+				UseMultiplePaths: true,
+				GracefulRestart:  &v1alpha2.GracefulRestart{Enabled: true, RestartTime: 120, StaleRoutesTime: 360},
+			},
+			peers: map[string]*v1alpha2.BgpPeerSpec{
+				"10.0.0.254": {
+					Conf: &v1alpha2.PeerConf{
+						NeighborAddress: "10.0.0.254",
+						PeerAs:          65000,
+						Description:     "top of rack\nswitch",
+						AuthPassword:    "secret",
+						AllowOwnAs:      2,
+					},
+					Transport:    &v1alpha2.Transport{RemotePort: 17900, PassiveMode: true},
+					EbgpMultihop: &v1alpha2.EbgpMultihop{Enabled: true, MultihopTtl: 3},
+					Timers: &v1alpha2.Timers{Config: &v1alpha2.TimersConfig{
+						KeepaliveInterval: "10",
+						HoldTime:          "30",
+						ConnectRetry:      "5",
+					}},
+				},
+				"10.0.0.253": {
+					Conf: &v1alpha2.PeerConf{
+						NeighborAddress: "10.0.0.253",
+						PeerAs:          65000,
+						LocalAs:         65100,
+						RemovePrivateAs: "ALL",
+						AdminDown:       true,
+					},
+				},
+			},
+			balancers: []string{"172.22.0.10", "172.22.0.11"},
+		},
+		{
+			name:   "dual-stack",
+			global: &v1alpha2.BgpConfSpec{As: 65001, RouterId: "10.0.0.1", GracefulRestart: &v1alpha2.GracefulRestart{Enabled: true, HelperOnly: true}},
+			peers: map[string]*v1alpha2.BgpPeerSpec{
+				"10.0.0.254": {
+					Conf: &v1alpha2.PeerConf{NeighborAddress: "10.0.0.254", PeerAs: 65001},
+					AfiSafis: []*v1alpha2.AfiSafi{
+						{Config: &v1alpha2.AfiSafiConfig{Family: &v1alpha2.Family{Afi: "AFI_IP", Safi: "SAFI_UNICAST"}, Enabled: true}},
+						{Config: &v1alpha2.AfiSafiConfig{Family: &v1alpha2.Family{Afi: "AFI_IP6", Safi: "SAFI_UNICAST"}, Enabled: true}},
+					},
+				},
+				"fd00::254": {
+					Conf: &v1alpha2.PeerConf{NeighborAddress: "fd00::254", PeerAs: 65000, RemovePrivateAs: "REPLACE"},
+				},
+				"eth1": {
+					Conf: &v1alpha2.PeerConf{NeighborInterface: "eth1", PeerAs: 65002},
+					AfiSafis: []*v1alpha2.AfiSafi{
+						{Config: &v1alpha2.AfiSafiConfig{Family: &v1alpha2.Family{Afi: "AFI_IP6", Safi: "SAFI_UNICAST"}, Enabled: true}},
+					},
+				},
+			},
+			balancers: []string{"172.22.0.10", "fd00:1::10"},
+			base:      "log syslog informational\n!\ninterface eth1\n ipv6 nd ra-interval 10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &frrConfig{
+				Hostname: "node1",
+				Base:     tt.base,
+				Router:   newRouterConfig(tt.global, tt.peers, tt.balancers),
+			}
+			got, err := config.render()
+			assert.NoError(t, err)
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, got, 0644))
+			}
+			want, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(want), string(got))
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package frr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/speaker"
+	bgpd "github.com/openelb/openelb/pkg/speaker/bgp/bgp"
+	"github.com/openelb/openelb/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+var _ speaker.Speaker = &Frr{}
+var _ speaker.BalancerLister = &Frr{}
+
+// Frr is the bgp backend rendering the config of an external frr from the
+// BgpConf, the BgpPeers and the balancers. Unlike gobgp announcing the paths
+// of all the nexthops, every node announces the balancers it's a nexthop of.
+type Frr struct {
+	opts         *bgpd.FrrOptions
+	client       client
+	resyncPeriod time.Duration
+
+	lock   sync.Mutex
+	global *v1alpha2.BgpConfSpec
+	peers  map[string]*v1alpha2.BgpPeerSpec
+	// the balancers and whether they're announced by the node
+	balancers map[string]bool
+	// the config applied to frr, it's applied again by the resync if dirty
+	applied []byte
+	dirty   bool
+}
+
+func NewFrr(bgpOptions *bgpd.BgpOptions) *Frr {
+	resyncPeriod := bgpOptions.ResyncPeriod
+	if resyncPeriod <= 0 {
+		resyncPeriod = bgpd.NewBgpOptions().ResyncPeriod
+	}
+
+	return newFrr(bgpOptions.Frr, &execClient{opts: bgpOptions.Frr}, resyncPeriod)
+}
+
+func newFrr(opts *bgpd.FrrOptions, c client, resyncPeriod time.Duration) *Frr {
+	return &Frr{
+		opts:         opts,
+		client:       c,
+		resyncPeriod: resyncPeriod,
+		peers:        map[string]*v1alpha2.BgpPeerSpec{},
+		balancers:    map[string]bool{},
+	}
+}
+
+// Start applies the config again if it failed to be applied, frr keeps
+// announcing the balancers while the speaker restarts.
+func (f *Frr) Start(stopCh <-chan struct{}) error {
+	klog.Info("frr backend starting")
+	ticker := time.NewTicker(f.resyncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			klog.Info("frr backend ending")
+			return nil
+		case <-ticker.C:
+			f.lock.Lock()
+			if f.dirty {
+				if err := f.apply(); err != nil {
+					klog.Errorf("failed to apply frr config: %v", err)
+				}
+			}
+			f.lock.Unlock()
+		}
+	}
+}
+
+func (f *Frr) SetBalancer(ip string, nodes []corev1.Node) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	announced := false
+	for _, node := range nodes {
+		if node.Name == util.GetNodeName() {
+			announced = true
+			break
+		}
+	}
+	f.balancers[ip] = announced
+
+	klog.Infof("frr setBalancer ip:%s announced:%t", ip, announced)
+	return f.apply()
+}
+
+func (f *Frr) DelBalancer(ip string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if _, ok := f.balancers[ip]; !ok {
+		return nil
+	}
+	delete(f.balancers, ip)
+
+	klog.Infof("frr delBalancer ip:%s", ip)
+	return f.apply()
+}
+
+func (f *Frr) ConfigureWithEIP(config speaker.Config, deleted bool) error {
+	return nil
+}
+
+// ListBalancers returns the ips of the balancers set.
+func (f *Frr) ListBalancers() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	ips := []string{}
+	for ip := range f.balancers {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+func (f *Frr) HandleBgpGlobalConfig(ctx context.Context, global *v1alpha2.BgpConf, rack string, delete bool, cm *corev1.ConfigMap) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if delete {
+		f.global = nil
+		return f.apply()
+	}
+
+	if cm != nil && cm.Data[constant.OpenELBBgpName] != "" {
+		klog.Warningf("bgp policy of configmap %s/%s is gobgp specific, it's ignored by the frr backend", cm.Namespace, cm.Name)
+	}
+	if global.Spec.ListenPort > 0 || len(global.Spec.ListenAddresses) != 0 {
+		klog.Warning("bgp listenPort and listenAddresses are options of the bgpd daemon of frr, they're ignored by the frr backend")
+	}
+
+	spec := global.Spec.DeepCopy()
+	f.global = spec
+	return f.apply()
+}
+
+func (f *Frr) HandleBgpPeer(neighbor *v1alpha2.BgpPeer, deleted bool) error {
+	if neighbor.Spec.Conf == nil {
+		return fmt.Errorf("field Spec.Conf should not be empty")
+	}
+	name := peerName(&neighbor.Spec)
+	if name == "" {
+		return fmt.Errorf("field Spec.Conf.NeighborAddress invalid")
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.UpdatePeerMetrics(neighbor, deleted)
+	if deleted {
+		delete(f.peers, name)
+	} else {
+		f.peers[name] = neighbor.Spec.DeepCopy()
+	}
+	return f.apply()
+}
+
+// HandleBgpPeerStatus returns the BgpPeers with the status of their sessions
+// on the node, the peers without a BgpPeer are deleted.
+func (f *Frr) HandleBgpPeerStatus(bgpPeers []v1alpha2.BgpPeer) []*v1alpha2.BgpPeer {
+	neighbors, err := f.client.Neighbors()
+	if err != nil {
+		klog.Errorf("failed to get the status of frr neighbors: %v", err)
+		return nil
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var result []*v1alpha2.BgpPeer
+	found := map[string]bool{}
+	for _, bgpPeer := range bgpPeers {
+		if bgpPeer.Spec.Conf == nil {
+			continue
+		}
+		name := peerName(&bgpPeer.Spec)
+		found[name] = true
+
+		neighbor, ok := neighbors[name]
+		if _, configured := f.peers[name]; !ok || !configured {
+			continue
+		}
+
+		clone := bgpPeer.DeepCopy()
+		if clone.Status.NodesPeerStatus == nil {
+			clone.Status.NodesPeerStatus = make(map[string]v1alpha2.NodePeerStatus)
+		}
+		clone.Status.NodesPeerStatus[util.GetNodeName()] = neighbor.toNodePeerStatus(bgpPeer.Spec.Conf.NeighborAddress)
+		result = append(result, clone)
+	}
+
+	deleted := false
+	for name := range f.peers {
+		if !found[name] {
+			klog.Infof("delete useless bgp peer: %s", name)
+			delete(f.peers, name)
+			deleted = true
+		}
+	}
+	if deleted {
+		if err := f.apply(); err != nil {
+			klog.Errorf("failed to apply frr config: %v", err)
+		}
+	}
+
+	return result
+}
+
+func (f *Frr) GetBgpConfStatus() v1alpha2.BgpConf {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	status := v1alpha2.NodeConfStatus{}
+	if f.global != nil && !f.dirty {
+		status.As = f.global.As
+		status.RouterId = f.global.RouterId
+	}
+	return v1alpha2.BgpConf{
+		Status: v1alpha2.BgpConfStatus{
+			NodesConfStatus: map[string]v1alpha2.NodeConfStatus{
+				util.GetNodeName(): status,
+			},
+		},
+	}
+}
+
+func (f *Frr) UpdatePeerMetrics(peer *v1alpha2.BgpPeer, delete bool) {
+	bgpd.UpdatePeerMetrics(peer, delete)
+}
+
+// render returns the config of frr, the caller must hold the lock.
+func (f *Frr) render() ([]byte, error) {
+	config := &frrConfig{Hostname: util.GetNodeName()}
+	if f.opts.BaseConfigPath != "" {
+		base, err := os.ReadFile(f.opts.BaseConfigPath)
+		if err != nil {
+			return nil, err
+		}
+		config.Base = strings.TrimSpace(string(base))
+	}
+
+	announced := []string{}
+	for ip, ok := range f.balancers {
+		if ok {
+			announced = append(announced, ip)
+		}
+	}
+	sort.Strings(announced)
+	config.Router = newRouterConfig(f.global, f.peers, announced)
+
+	return config.render()
+}
+
+// apply renders the config and reloads frr if it's changed, the caller must
+// hold the lock.
+func (f *Frr) apply() error {
+	config, err := f.render()
+	if err != nil {
+		f.dirty = true
+		return err
+	}
+
+	if !f.dirty && bytes.Equal(config, f.applied) {
+		return nil
+	}
+
+	if err := f.client.Reload(config); err != nil {
+		f.dirty = true
+		return err
+	}
+	f.applied = config
+	f.dirty = false
+	return nil
+}
+
+func peerName(spec *v1alpha2.BgpPeerSpec) string {
+	if spec.Conf.NeighborAddress != "" {
+		return spec.Conf.NeighborAddress
+	}
+	return spec.Conf.NeighborInterface
+}
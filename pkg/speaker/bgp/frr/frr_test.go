@@ -0,0 +1,120 @@
+package frr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/constant"
+	bgpd "github.com/openelb/openelb/pkg/speaker/bgp/bgp"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeClient struct {
+	configs   []string
+	err       error
+	neighbors map[string]neighborStatus
+}
+
+func (c *fakeClient) Reload(config []byte) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.configs = append(c.configs, string(config))
+	return nil
+}
+
+func (c *fakeClient) Neighbors() (map[string]neighborStatus, error) {
+	return c.neighbors, nil
+}
+
+func (c *fakeClient) last() string {
+	if len(c.configs) == 0 {
+		return ""
+	}
+	return c.configs[len(c.configs)-1]
+}
+
+func TestFrrBalancers(t *testing.T) {
+	os.Setenv(constant.EnvNodeName, "node1")
+	defer os.Unsetenv(constant.EnvNodeName)
+
+	c := &fakeClient{}
+	f := newFrr(bgpd.NewBgpOptions().Frr, c, time.Minute)
+	assert.NoError(t, f.HandleBgpGlobalConfig(context.Background(), &v1alpha2.BgpConf{Spec: v1alpha2.BgpConfSpec{As: 65001, RouterId: "10.0.0.1"}}, "", false, nil))
+	assert.NoError(t, f.HandleBgpPeer(&v1alpha2.BgpPeer{Spec: v1alpha2.BgpPeerSpec{
+		Conf: &v1alpha2.PeerConf{NeighborAddress: "10.0.0.254", PeerAs: 65000},
+	}}, false))
+	assert.Contains(t, c.last(), " neighbor 10.0.0.254 remote-as 65000\n")
+
+	node1 := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	node2 := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}}
+
+	// only the balancers the node is a nexthop of are announced
+	assert.NoError(t, f.SetBalancer("172.22.0.10", []corev1.Node{node1, node2}))
+	assert.NoError(t, f.SetBalancer("172.22.0.11", []corev1.Node{node2}))
+	assert.Contains(t, c.last(), "  network 172.22.0.10/32\n")
+	assert.NotContains(t, c.last(), "172.22.0.11")
+	assert.Equal(t, []string{"172.22.0.10", "172.22.0.11"}, f.ListBalancers())
+
+	// frr isn't reloaded if the config isn't changed
+	reloads := len(c.configs)
+	assert.NoError(t, f.SetBalancer("172.22.0.11", []corev1.Node{node2}))
+	assert.Len(t, c.configs, reloads)
+
+	assert.NoError(t, f.DelBalancer("172.22.0.10"))
+	assert.NotContains(t, c.last(), "172.22.0.10")
+
+	// the config failed to be applied is applied again
+	c.err = errors.New("frr-reload.py failed")
+	assert.Error(t, f.SetBalancer("172.22.0.12", []corev1.Node{node1}))
+	assert.True(t, f.dirty)
+	assert.Equal(t, v1alpha2.NodeConfStatus{}, f.GetBgpConfStatus().Status.NodesConfStatus["node1"])
+	c.err = nil
+	assert.NoError(t, f.apply())
+	assert.Contains(t, c.last(), "  network 172.22.0.12/32\n")
+	assert.Equal(t, v1alpha2.NodeConfStatus{As: 65001, RouterId: "10.0.0.1"}, f.GetBgpConfStatus().Status.NodesConfStatus["node1"])
+
+	assert.NoError(t, f.HandleBgpGlobalConfig(context.Background(), nil, "", true, nil))
+	assert.False(t, strings.Contains(c.last(), "router bgp"))
+}
+
+func TestFrrPeerStatus(t *testing.T) {
+	os.Setenv(constant.EnvNodeName, "node1")
+	defer os.Unsetenv(constant.EnvNodeName)
+
+	data, err := os.ReadFile("testdata/neighbors.json")
+	assert.NoError(t, err)
+	neighbors, err := parseNeighbors(data)
+	assert.NoError(t, err)
+
+	c := &fakeClient{neighbors: neighbors}
+	f := newFrr(bgpd.NewBgpOptions().Frr, c, time.Minute)
+	assert.NoError(t, f.HandleBgpGlobalConfig(context.Background(), &v1alpha2.BgpConf{Spec: v1alpha2.BgpConfSpec{As: 65001, RouterId: "10.0.0.1"}}, "", false, nil))
+	peer := v1alpha2.BgpPeer{
+		ObjectMeta: metav1.ObjectMeta{Name: "tor"},
+		Spec:       v1alpha2.BgpPeerSpec{Conf: &v1alpha2.PeerConf{NeighborAddress: "10.0.0.254", PeerAs: 65000}},
+	}
+	assert.NoError(t, f.HandleBgpPeer(&peer, false))
+	assert.NoError(t, f.HandleBgpPeer(&v1alpha2.BgpPeer{
+		Spec: v1alpha2.BgpPeerSpec{Conf: &v1alpha2.PeerConf{NeighborAddress: "10.0.0.253", PeerAs: 65000}},
+	}, false))
+
+	// the peer 10.0.0.253 has no BgpPeer any more
+	result := f.HandleBgpPeerStatus([]v1alpha2.BgpPeer{peer})
+	assert.Len(t, result, 1)
+	status := result[0].Status.NodesPeerStatus["node1"]
+	assert.Equal(t, "ESTABLISHED", status.PeerState.SessionState)
+	assert.Equal(t, uint32(65000), status.PeerState.PeerAs)
+	assert.Equal(t, "10.0.0.254", status.PeerState.NeighborAddress)
+	assert.Equal(t, "2", status.PeerState.Messages.Received.Update)
+	assert.Equal(t, "30", status.TimersState.HoldTime)
+	assert.Equal(t, "9", status.TimersState.NegotiatedHoldTime)
+	assert.NotContains(t, c.last(), "10.0.0.253")
+}
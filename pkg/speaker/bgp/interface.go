@@ -0,0 +1,21 @@
+package bgp
+
+import (
+	"context"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/speaker"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// BgpServer is the bgp backend of the speaker driven by the BgpConf and
+// BgpPeer reconcilers, gobgp embedded in the speaker or an external frr.
+type BgpServer interface {
+	speaker.Speaker
+
+	HandleBgpGlobalConfig(ctx context.Context, global *v1alpha2.BgpConf, rack string, delete bool, cm *corev1.ConfigMap) error
+	HandleBgpPeer(neighbor *v1alpha2.BgpPeer, delete bool) error
+	HandleBgpPeerStatus(bgpPeers []v1alpha2.BgpPeer) []*v1alpha2.BgpPeer
+	GetBgpConfStatus() v1alpha2.BgpConf
+	UpdatePeerMetrics(peer *v1alpha2.BgpPeer, delete bool)
+}
@@ -0,0 +1,236 @@
+package bgp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/nettool"
+	"github.com/openelb/openelb/pkg/nettool/iptables"
+	"github.com/openelb/openelb/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// PortForwardReconciler forwards the bgp connections of the peers to the
+// listen port of gobgpd on the nodes labeled with the calico cni, where bird
+// of calico is listening on the bgp port.
+type PortForwardReconciler struct {
+	client.Client
+	Iptables     iptables.IptablesIface
+	ResyncPeriod time.Duration
+
+	lock sync.Mutex
+	// the rules in the chain, nil if the chain isn't created by the reconciler
+	rules map[string]portForward
+}
+
+type portForward struct {
+	routerIP string
+	localIP  string
+	port     int32
+}
+
+func (p portForward) key() string {
+	return strings.Join(nettool.GenerateCretiriaAndAction(p.routerIP, p.localIP, p.port), " ")
+}
+
+// Reconcile syncs the chain with the BgpConf, the BgpPeers and the node, the
+// request is ignored since the chain is shared by all of them.
+func (r *PortForwardReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	forwards, err := r.desiredForwards(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if forwards == nil {
+		return ctrl.Result{}, r.cleanup()
+	}
+	if err := r.sync(forwards); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.ResyncPeriod}, nil
+}
+
+// desiredForwards returns the rules of the chain, nil if the chain isn't needed.
+func (r *PortForwardReconciler) desiredForwards(ctx context.Context) (map[string]portForward, error) {
+	node := &corev1.Node{}
+	if err := r.Get(ctx, types.NamespacedName{Name: util.GetNodeName()}, node); err != nil {
+		return nil, err
+	}
+	if node.Labels[constant.OpenELBCNI] != constant.OpenELBCNICalico {
+		return nil, nil
+	}
+
+	conf := &v1alpha2.BgpConf{}
+	if err := r.Get(ctx, client.ObjectKey{Name: "default"}, conf); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !conf.DeletionTimestamp.IsZero() || util.DutyOfCNI(nil, conf) {
+		return nil, nil
+	}
+	port := conf.Spec.ListenPort
+	if port < 0 {
+		// gobgpd doesn't listen
+		return nil, nil
+	}
+	if port == 0 || fmt.Sprint(port) == nettool.BGPPort {
+		klog.Warningf("bgp listenPort %d conflicts with bird of calico on node %s", port, node.Name)
+		return nil, nil
+	}
+
+	nodeIP := util.GetNodeIP(*node).To4()
+	if nodeIP == nil {
+		return nil, fmt.Errorf("node %s has no ipv4 internal address", node.Name)
+	}
+
+	peers := &v1alpha2.BgpPeerList{}
+	if err := r.List(ctx, peers); err != nil {
+		return nil, err
+	}
+	forwards := map[string]portForward{}
+	for _, peer := range peers.Items {
+		if peer.Spec.Conf == nil || !peer.DeletionTimestamp.IsZero() || util.DutyOfCNI(nil, &peer) {
+			continue
+		}
+		match, err := peerMatchNode(&peer, node)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			continue
+		}
+		ip := net.ParseIP(peer.Spec.Conf.NeighborAddress)
+		if ip == nil || ip.To4() == nil {
+			klog.Warningf("bgp connections of peer %s aren't forwarded, only ipv4 neighbors are supported", peer.Name)
+			continue
+		}
+		forward := portForward{routerIP: ip.String(), localIP: nodeIP.String(), port: port}
+		forwards[forward.key()] = forward
+	}
+	return forwards, nil
+}
+
+// sync creates the chain and updates its rules, the caller must hold the lock.
+func (r *PortForwardReconciler) sync(forwards map[string]portForward) error {
+	if err := nettool.EnsureChainOfBGP(r.Iptables); err != nil {
+		return err
+	}
+	if r.rules == nil {
+		// drop the rules left by the previous speaker
+		if err := r.Iptables.ClearChain("nat", nettool.BgpNatChain); err != nil {
+			return err
+		}
+		r.rules = map[string]portForward{}
+	}
+
+	for key, f := range r.rules {
+		if _, ok := forwards[key]; ok {
+			continue
+		}
+		if err := nettool.DeletePortForwardOfBGP(r.Iptables, f.routerIP, f.localIP, f.port); err != nil {
+			return err
+		}
+		delete(r.rules, key)
+	}
+	for key, f := range forwards {
+		if err := nettool.AddPortForwardOfBGP(r.Iptables, f.routerIP, f.localIP, f.port); err != nil {
+			return err
+		}
+		r.rules[key] = f
+	}
+	return nil
+}
+
+// cleanup deletes the chain created by the reconciler, the caller must hold the lock.
+func (r *PortForwardReconciler) cleanup() error {
+	if r.rules == nil {
+		return nil
+	}
+	if err := nettool.DeleteChainOfBGP(r.Iptables); err != nil {
+		return err
+	}
+	r.rules = nil
+	return nil
+}
+
+// Start deletes the chain when the speaker is stopped, bird of calico takes
+// over the bgp connections again.
+func (r *PortForwardReconciler) Start(ctx context.Context) error {
+	<-ctx.Done()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err := r.cleanup(); err != nil {
+		klog.Errorf("failed to delete chain %s: %v", nettool.BgpNatChain, err)
+	}
+	return nil
+}
+
+func (r *PortForwardReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	enqueue := handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "default"}}}
+	})
+	localNode := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetName() == util.GetNodeName()
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("BgpPortForwardController").
+		For(&v1alpha2.BgpConf{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return shouldReconcile(obj)
+		}))).
+		Watches(&v1alpha2.BgpPeer{}, enqueue).
+		Watches(&corev1.Node{}, enqueue, builder.WithPredicates(localNode, predicate.Or(predicate.LabelChangedPredicate{}, nodeAddressChanged))).
+		Complete(r)
+}
+
+// nodeAddressChanged passes the updates of the addresses of a node, the rules
+// forward the connections to the internal ip of the node.
+var nodeAddressChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		old, ok := e.ObjectOld.(*corev1.Node)
+		if !ok {
+			return false
+		}
+		new, ok := e.ObjectNew.(*corev1.Node)
+		if !ok {
+			return false
+		}
+		return !reflect.DeepEqual(old.Status.Addresses, new.Status.Addresses)
+	},
+}
+
+func SetupPortForwardReconciler(ipt iptables.IptablesIface, resyncPeriod time.Duration, mgr ctrl.Manager) error {
+	portForward := &PortForwardReconciler{
+		Client:       mgr.GetClient(),
+		Iptables:     ipt,
+		ResyncPeriod: resyncPeriod,
+	}
+	if err := portForward.SetupWithManager(mgr); err != nil {
+		return err
+	}
+
+	return mgr.Add(portForward)
+}
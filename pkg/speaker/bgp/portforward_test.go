@@ -0,0 +1,129 @@
+package bgp
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/nettool"
+	"github.com/openelb/openelb/pkg/nettool/iptables"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestPortForwardReconciler(t *testing.T) {
+	os.Setenv(constant.EnvNodeName, "node1")
+	defer os.Unsetenv(constant.EnvNodeName)
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node1",
+			Labels: map[string]string{constant.OpenELBCNI: constant.OpenELBCNICalico, "rack": "a"},
+		},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "192.168.0.1"},
+		}},
+	}
+	conf := &v1alpha2.BgpConf{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec:       v1alpha2.BgpConfSpec{As: 65001, ListenPort: 17900},
+	}
+	peer1 := &v1alpha2.BgpPeer{
+		ObjectMeta: metav1.ObjectMeta{Name: "peer1"},
+		Spec:       v1alpha2.BgpPeerSpec{Conf: &v1alpha2.PeerConf{NeighborAddress: "192.168.0.254", PeerAs: 65000}},
+	}
+	peer2 := &v1alpha2.BgpPeer{
+		ObjectMeta: metav1.ObjectMeta{Name: "peer2"},
+		Spec: v1alpha2.BgpPeerSpec{
+			Conf:         &v1alpha2.PeerConf{NeighborAddress: "192.168.0.253", PeerAs: 65000},
+			NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"rack": "b"}},
+		},
+	}
+	peer3 := &v1alpha2.BgpPeer{
+		ObjectMeta: metav1.ObjectMeta{Name: "peer3"},
+		Spec:       v1alpha2.BgpPeerSpec{Conf: &v1alpha2.PeerConf{NeighborAddress: "fd00::254", PeerAs: 65000}},
+	}
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = v1alpha2.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(node, conf, peer1, peer2, peer3).Build()
+	ipt := iptables.NewFakeIPTables()
+	// left by the previous speaker
+	assert.NoError(t, nettool.EnsureChainOfBGP(ipt))
+	assert.NoError(t, nettool.AddPortForwardOfBGP(ipt, "192.168.0.100", "192.168.0.1", 17900))
+
+	r := &PortForwardReconciler{Client: c, Iptables: ipt}
+	ctx := context.Background()
+	reconcile := func() {
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "default"}})
+		assert.NoError(t, err)
+	}
+	rules := func() []iptables.IptablesRule {
+		return ipt.Data["nat"][nettool.BgpNatChain]
+	}
+
+	reconcile()
+	assert.Len(t, ipt.Data["nat"]["PREROUTING"], 1)
+	assert.Len(t, rules(), 1)
+	assert.Equal(t, nettool.GenerateCretiriaAndAction("192.168.0.254", "192.168.0.1", 17900), rules()[0].Rule)
+
+	// the peer matches the node
+	peer2.Spec.NodeSelector = nil
+	assert.NoError(t, c.Update(ctx, peer2))
+	reconcile()
+	assert.Len(t, rules(), 2)
+
+	// the listen port is changed
+	conf.Spec.ListenPort = 17901
+	assert.NoError(t, c.Update(ctx, conf))
+	reconcile()
+	assert.Len(t, rules(), 2)
+	for _, rule := range rules() {
+		assert.Equal(t, "192.168.0.1:17901", rule.Rule[len(rule.Rule)-1])
+	}
+
+	assert.NoError(t, c.Delete(ctx, peer1))
+	reconcile()
+	assert.Len(t, rules(), 1)
+	assert.Equal(t, nettool.GenerateCretiriaAndAction("192.168.0.253", "192.168.0.1", 17901), rules()[0].Rule)
+
+	// the chain is deleted once the node isn't labeled
+	delete(node.Labels, constant.OpenELBCNI)
+	assert.NoError(t, c.Update(ctx, node))
+	reconcile()
+	assert.NotContains(t, ipt.Data["nat"], nettool.BgpNatChain)
+	assert.Empty(t, ipt.Data["nat"]["PREROUTING"])
+
+	node.Labels[constant.OpenELBCNI] = constant.OpenELBCNICalico
+	assert.NoError(t, c.Update(ctx, node))
+	reconcile()
+	assert.Len(t, rules(), 1)
+
+	// and when the speaker is stopped
+	stopped, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.NoError(t, r.Start(stopped))
+	assert.NotContains(t, ipt.Data["nat"], nettool.BgpNatChain)
+	assert.Empty(t, ipt.Data["nat"]["PREROUTING"])
+}
+
+func TestNodeAddressChanged(t *testing.T) {
+	old := &corev1.Node{Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+		{Type: corev1.NodeInternalIP, Address: "192.168.0.1"},
+	}}}
+	new := old.DeepCopy()
+	new.Labels = map[string]string{"rack": "a"}
+	assert.False(t, nodeAddressChanged.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: new}))
+
+	new.Status.Addresses[0].Address = "192.168.0.2"
+	assert.True(t, nodeAddressChanged.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: new}))
+}
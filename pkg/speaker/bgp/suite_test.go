@@ -160,7 +160,7 @@ var _ = BeforeSuite(func(done Done) {
 	Expect(mgr).ToNot(BeNil())
 
 	// Setup all Controllers
-	bgpServer = bgpd.NewGoBgpd(bgpd.NewBgpOptions())
+	bgpServer = bgpd.NewGoBgpd(bgpd.NewBgpOptions(), nil)
 	go func() {
 		err := bgpServer.Start(stopCh.Done())
 		if err != nil {
@@ -22,13 +22,19 @@ import (
 
 	"github.com/openelb/openelb/api/v1alpha2"
 	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/util"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
@@ -38,18 +44,42 @@ type EIPReconciler struct {
 	record.EventRecorder
 
 	Reload   chan event.GenericEvent
-	Reloader func(context.Context) error
+	Reloader func(context.Context, string) error
 	Handler  func(context.Context, *v1alpha2.Eip) error
 }
 
 func (e *EIPReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	localNode := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetName() == util.GetNodeName()
+	})
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha2.Eip{}).
 		WatchesRawSource(&source.Channel{Source: e.Reload}, &handler.EnqueueRequestForObject{}).
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(e.labelSelectedEips),
+			builder.WithPredicates(localNode, predicate.LabelChangedPredicate{})).
 		Named("EIPController").
 		Complete(e)
 }
 
+// labelSelectedEips returns the eips whose interfaces are selected by the
+// labels of the node, they're configured again once the labels change.
+func (e *EIPReconciler) labelSelectedEips(ctx context.Context, _ client.Object) []reconcile.Request {
+	eips := &v1alpha2.EipList{}
+	if err := e.List(ctx, eips); err != nil {
+		klog.Warningf("list eips failed, err: %s", err.Error())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, eip := range eips.Items {
+		if _, ok := interfaceLabel(eip.Spec.Interface); ok {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: eip.Name}})
+		}
+	}
+	return requests
+}
+
 //+kubebuilder:rbac:groups=network.kubesphere.io,resources=eips,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=network.kubesphere.io,resources=eips/status,verbs=get;update;patch
 
@@ -63,8 +93,8 @@ func (e *EIPReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		klog.V(4).Infof("Finished syncing eip %s in %s", req.Name, time.Since(startTime))
 	}()
 
-	if e.reloadLayer2Speaker(req) {
-		return ctrl.Result{}, e.Reloader(ctx)
+	if protocol, ok := e.reloadSpeaker(req); ok {
+		return ctrl.Result{}, e.Reloader(ctx, protocol)
 	}
 
 	eip := &v1alpha2.Eip{}
@@ -78,6 +108,19 @@ func (e *EIPReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	return ctrl.Result{}, e.Handler(ctx, eip)
 }
 
-func (e *EIPReconciler) reloadLayer2Speaker(req ctrl.Request) bool {
-	return req.Name == constant.Layer2ReloadEIPName && req.Namespace == constant.Layer2ReloadEIPNamespace
+// reloadSpeaker returns the protocol of the speaker asking for a resync.
+func (e *EIPReconciler) reloadSpeaker(req ctrl.Request) (string, bool) {
+	if req.Name == constant.Layer2ReloadEIPName && req.Namespace == constant.Layer2ReloadEIPNamespace {
+		return constant.OpenELBProtocolLayer2, true
+	}
+
+	if req.Name == constant.BgpReloadEIPName && req.Namespace == constant.BgpReloadEIPNamespace {
+		return constant.OpenELBProtocolBGP, true
+	}
+
+	if req.Namespace == constant.PluginReloadEIPNamespace {
+		return req.Name, true
+	}
+
+	return "", false
 }
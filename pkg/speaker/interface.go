@@ -9,6 +9,8 @@ type Config struct {
 	Name    string
 	IPRange iprange.Range
 	Iface   string
+	// the labels of the node, used by the label interface selector
+	NodeLabels map[string]string
 }
 
 type Speaker interface {
@@ -17,3 +19,18 @@ type Speaker interface {
 	Start(stopCh <-chan struct{}) error
 	ConfigureWithEIP(config Config, deleted bool) error
 }
+
+// BalancerLister is implemented by the speakers keeping the desired state of
+// the balancers, the balancers not used by any eip are deleted by the resync.
+type BalancerLister interface {
+	ListBalancers() []string
+}
+
+// BalancerOwner is implemented by the speakers electing the node receiving
+// the traffic of a balancer, e.g. layer2 and vip.
+type BalancerOwner interface {
+	OwnBalancer(ip string) bool
+	// NotifyOwnership sets the function called when the node may have
+	// gained or lost some balancers.
+	NotifyOwnership(notify func())
+}
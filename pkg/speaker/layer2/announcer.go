@@ -12,14 +12,22 @@ type Announcer interface {
 	Start() error
 	Stop() error
 	ContainsIP(net.IP) bool
+	// Announcing returns true if the announcer answers for the ip.
+	Announcing(net.IP) bool
 	RegisterIPRange(string, iprange.Range)
 	UnregisterIPRange(string)
 	Size() int
 }
 
-func newAnnouncer(iface *net.Interface, family iprange.Family) (Announcer, error) {
+// conflictHandler is called when an announcer sees another host claiming one
+// of its announced ips, own is the mac announced for ip and foreign is the mac
+// of the other host. It returns true if the announcer should step down and
+// stop answering for the ip.
+type conflictHandler func(ip net.IP, own, foreign net.HardwareAddr) bool
+
+func newAnnouncer(iface *net.Interface, family iprange.Family, onConflict conflictHandler) (Announcer, error) {
 	if family == iprange.V4Family {
-		return newARPAnnouncer(iface)
+		return newARPAnnouncer(iface, onConflict)
 	}
-	return newNDPAnnouncer(iface)
+	return newNDPAnnouncer(iface, onConflict)
 }
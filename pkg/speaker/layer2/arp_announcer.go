@@ -29,10 +29,11 @@ type arpAnnouncer struct {
 	conn  *arp.Client
 	p     *raw.Conn
 
-	stopCh   chan struct{}
-	lock     sync.RWMutex
-	ip2mac   map[string]net.HardwareAddr
-	ipranges map[string]iprange.Range
+	stopCh    chan struct{}
+	lock      sync.RWMutex
+	ip2mac    map[string]net.HardwareAddr
+	ipranges  map[string]iprange.Range
+	conflicts *conflictTracker
 }
 
 func (a *arpAnnouncer) RegisterIPRange(name string, r iprange.Range) {
@@ -69,6 +70,10 @@ func (a *arpAnnouncer) ContainsIP(ip net.IP) bool {
 	return false
 }
 
+func (a *arpAnnouncer) Announcing(ip net.IP) bool {
+	return a.getMac(ip.String()) != nil
+}
+
 func (a *arpAnnouncer) getMac(ip string) *net.HardwareAddr {
 	a.lock.RLock()
 	defer a.lock.RUnlock()
@@ -91,7 +96,7 @@ func (a *arpAnnouncer) setMac(ip string, mac net.HardwareAddr) {
 	a.ip2mac[ip] = mac
 }
 
-func newARPAnnouncer(ifi *net.Interface) (*arpAnnouncer, error) {
+func newARPAnnouncer(ifi *net.Interface, onConflict conflictHandler) (*arpAnnouncer, error) {
 	p, err := raw.ListenPacket(ifi, protocolARP, nil)
 	if err != nil {
 		return nil, err
@@ -104,13 +109,14 @@ func newARPAnnouncer(ifi *net.Interface) (*arpAnnouncer, error) {
 	link, _ := netlink.LinkByIndex(ifi.Index)
 	addrs, _ := netlink.AddrList(link, netlink.FAMILY_V4)
 	ret := &arpAnnouncer{
-		intf:     ifi,
-		addrs:    addrs,
-		conn:     client,
-		p:        p,
-		stopCh:   make(chan struct{}),
-		ip2mac:   make(map[string]net.HardwareAddr),
-		ipranges: make(map[string]iprange.Range),
+		intf:      ifi,
+		addrs:     addrs,
+		conn:      client,
+		p:         p,
+		stopCh:    make(chan struct{}),
+		ip2mac:    make(map[string]net.HardwareAddr),
+		ipranges:  make(map[string]iprange.Range),
+		conflicts: newConflictTracker(onConflict),
 	}
 
 	return ret, nil
@@ -178,14 +184,20 @@ func (a *arpAnnouncer) AddAnnouncedIP(ip net.IP) error {
 }
 
 func (a *arpAnnouncer) DelAnnouncedIP(ip net.IP) error {
+	a.withdraw(ip)
+	a.conflicts.forget(ip.String())
+	return nil
+}
+
+// withdraw stops answering for ip, the conflicts of ip are still known so
+// a step down isn't reported again.
+func (a *arpAnnouncer) withdraw(ip net.IP) {
 	klog.Infof("cancel respone %s's arp packet", ip)
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
 	delete(a.ip2mac, ip.String())
 	metrics.DeleteLayer2Metrics(ip.String())
-
-	return nil
 }
 
 func (a *arpAnnouncer) Start() error {
@@ -213,6 +225,18 @@ func (a *arpAnnouncer) Stop() error {
 	return nil
 }
 
+// checkConflict returns true if the packet is sent by another host claiming
+// one of our ips, either in a reply or in a gratuitous request, which means a
+// split-brain or an external ip conflict.
+func (a *arpAnnouncer) checkConflict(pkt *arp.Packet) bool {
+	conflict, stepDown := a.conflicts.check(pkt.SenderIP, a.getMac(pkt.SenderIP.String()), pkt.SenderHardwareAddr)
+	if stepDown {
+		klog.Warningf("interface %s step down from announcing %s", a.intf.Name, pkt.SenderIP)
+		a.withdraw(pkt.SenderIP)
+	}
+	return conflict
+}
+
 func (a *arpAnnouncer) processRequest() dropReason {
 	pkt, _, err := a.conn.Read()
 	if err != nil {
@@ -225,6 +249,10 @@ func (a *arpAnnouncer) processRequest() dropReason {
 		return dropReasonError
 	}
 
+	if a.checkConflict(pkt) {
+		return dropReasonConflict
+	}
+
 	// Ignore ARP replies.
 	if pkt.Operation != arp.OperationRequest {
 		return dropReasonARPReply
@@ -0,0 +1,57 @@
+package layer2
+
+import (
+	"bytes"
+	"net"
+	"sync"
+
+	"github.com/openelb/openelb/pkg/metrics"
+	"k8s.io/klog/v2"
+)
+
+// conflictTracker records foreign hosts claiming announced ips. Every claim is
+// counted in metrics, but the handler is only called once per ip and mac so a
+// chatty peer does not flood events.
+type conflictTracker struct {
+	lock     sync.Mutex
+	handler  conflictHandler
+	reported map[string]string
+}
+
+func newConflictTracker(handler conflictHandler) *conflictTracker {
+	return &conflictTracker{
+		handler:  handler,
+		reported: make(map[string]string),
+	}
+}
+
+// check compares the sender of a packet claiming ip with the mac we announce for it.
+// announced is nil if the ip is not announced by this node.
+func (c *conflictTracker) check(ip net.IP, announced *net.HardwareAddr, sender net.HardwareAddr) (conflict, stepDown bool) {
+	if announced == nil || len(sender) == 0 || bytes.Equal(*announced, sender) {
+		return false, false
+	}
+
+	metrics.UpdateConflictsDetectedMetrics(ip.String(), sender.String())
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.reported[ip.String()] == sender.String() {
+		return true, false
+	}
+	c.reported[ip.String()] = sender.String()
+
+	klog.Warningf("ip %s announced as %s is also claimed by %s", ip, *announced, sender)
+	if c.handler == nil {
+		return true, false
+	}
+	return true, c.handler(ip, *announced, sender)
+}
+
+func (c *conflictTracker) forget(ip string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.reported, ip)
+}
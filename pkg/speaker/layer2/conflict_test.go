@@ -0,0 +1,161 @@
+package layer2
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/mdlayher/arp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConflictTracker_Check(t *testing.T) {
+	ip := net.ParseIP("192.168.0.10")
+	own, _ := net.ParseMAC("02:00:00:00:00:01")
+	foreign, _ := net.ParseMAC("02:00:00:00:00:02")
+
+	calls := 0
+	c := newConflictTracker(func(net.IP, net.HardwareAddr, net.HardwareAddr) bool {
+		calls++
+		return true
+	})
+
+	conflict, stepDown := c.check(ip, nil, foreign)
+	assert.False(t, conflict, "ip not announced by us")
+	assert.False(t, stepDown)
+
+	conflict, stepDown = c.check(ip, &own, own)
+	assert.False(t, conflict, "our own announcement")
+	assert.False(t, stepDown)
+
+	conflict, stepDown = c.check(ip, &own, foreign)
+	assert.True(t, conflict)
+	assert.True(t, stepDown)
+	assert.Equal(t, 1, calls)
+
+	conflict, stepDown = c.check(ip, &own, foreign)
+	assert.True(t, conflict, "repeated claims are still conflicts")
+	assert.False(t, stepDown, "but the handler is only called once")
+	assert.Equal(t, 1, calls)
+
+	c.forget(ip.String())
+	conflict, stepDown = c.check(ip, &own, foreign)
+	assert.True(t, conflict)
+	assert.True(t, stepDown)
+	assert.Equal(t, 2, calls)
+}
+
+func TestStepDown(t *testing.T) {
+	ip := "192.168.0.10"
+	own, _ := net.ParseMAC("02:00:00:00:00:02")
+	foreign, _ := net.ParseMAC("02:00:00:00:00:01")
+
+	ownership := newLeaseOwnership(fake.NewSimpleClientset(), &Options{NodeName: "node1", LeaseDuration: 15 * time.Second}, func(string, bool) {})
+	l := &layer2Speaker{ownership: ownership, stepDown: true}
+	owned, err := ownership.Elect(ip, []string{"node1", "node2"})
+	require.NoError(t, err)
+	require.True(t, owned)
+
+	calls := 0
+	a := &arpAnnouncer{
+		intf:   &net.Interface{Name: "eth0", HardwareAddr: own},
+		ip2mac: map[string]net.HardwareAddr{ip: own},
+		conflicts: newConflictTracker(func(ip net.IP, own, foreign net.HardwareAddr) bool {
+			calls++
+			return l.handleConflict(ip, own, foreign)
+		}),
+	}
+	pkt := &arp.Packet{Operation: arp.OperationReply, SenderIP: net.ParseIP(ip), SenderHardwareAddr: foreign}
+
+	assert.True(t, a.checkConflict(pkt))
+	assert.False(t, a.Announcing(net.ParseIP(ip)))
+	assert.Equal(t, 1, calls)
+	assert.Eventually(t, func() bool {
+		ownership.lock.Lock()
+		defer ownership.lock.Unlock()
+		return ownership.steppedDown[ip] && len(ownership.owned) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// the local node isn't elected again on the next resync
+	owned, err = ownership.Elect(ip, []string{"node1", "node2"})
+	require.NoError(t, err)
+	assert.False(t, owned)
+
+	// the claims of the other host aren't reported again
+	a.setMac(ip, own)
+	assert.True(t, a.checkConflict(pkt))
+	assert.Equal(t, 1, calls)
+
+	// until the ip is released
+	require.NoError(t, ownership.Release(ip))
+	owned, err = ownership.Elect(ip, []string{"node1", "node2"})
+	require.NoError(t, err)
+	assert.True(t, owned)
+}
+
+func TestStepDownBothSides(t *testing.T) {
+	ip := "192.168.0.10"
+	macs := []string{"02:00:00:00:00:01", "02:00:00:00:00:02"}
+
+	// both nodes claim the ip after a split-brain
+	announcers := []*arpAnnouncer{}
+	ownerships := []*leaseOwnership{}
+	for i, m := range macs {
+		mac, _ := net.ParseMAC(m)
+		name := fmt.Sprintf("node%d", i+1)
+		ownership := newLeaseOwnership(fake.NewSimpleClientset(), &Options{NodeName: name, LeaseDuration: 15 * time.Second}, func(string, bool) {})
+		owned, err := ownership.Elect(ip, []string{name})
+		require.NoError(t, err)
+		require.True(t, owned)
+
+		l := &layer2Speaker{ownership: ownership, stepDown: true}
+		announcers = append(announcers, &arpAnnouncer{
+			intf:      &net.Interface{Name: "eth0", HardwareAddr: mac},
+			ip2mac:    map[string]net.HardwareAddr{ip: mac},
+			conflicts: newConflictTracker(l.handleConflict),
+		})
+		ownerships = append(ownerships, ownership)
+	}
+
+	for i, a := range announcers {
+		other := announcers[1-i]
+		pkt := &arp.Packet{Operation: arp.OperationReply, SenderIP: net.ParseIP(ip), SenderHardwareAddr: other.intf.HardwareAddr}
+		assert.True(t, a.checkConflict(pkt))
+	}
+
+	// only the node announcing the greater mac steps down
+	assert.True(t, announcers[0].Announcing(net.ParseIP(ip)))
+	assert.False(t, announcers[1].Announcing(net.ParseIP(ip)))
+	assert.Eventually(t, func() bool {
+		ownerships[1].lock.Lock()
+		defer ownerships[1].lock.Unlock()
+		return ownerships[1].steppedDown[ip]
+	}, time.Second, 10*time.Millisecond)
+	ownerships[0].lock.Lock()
+	defer ownerships[0].lock.Unlock()
+	assert.False(t, ownerships[0].steppedDown[ip])
+}
+
+func TestNDPAdvertisement(t *testing.T) {
+	ip := netip.MustParseAddr("fd00::10")
+	own, _ := net.ParseMAC("02:00:00:00:00:01")
+	foreign, _ := net.ParseMAC("02:00:00:00:00:02")
+
+	n := &ndpAnnouncer{
+		intf:   &net.Interface{Name: "eth0", HardwareAddr: own},
+		ip2mac: map[string]net.HardwareAddr{ip.String(): own},
+		conflicts: newConflictTracker(func(net.IP, net.HardwareAddr, net.HardwareAddr) bool {
+			return true
+		}),
+	}
+
+	assert.Equal(t, dropReasonAdvertisement, n.processAdvertisement(generateNDP(true, own, ip)))
+	assert.True(t, n.Announcing(ip.AsSlice()))
+
+	assert.Equal(t, dropReasonConflict, n.processAdvertisement(generateNDP(true, foreign, ip)))
+	assert.False(t, n.Announcing(ip.AsSlice()))
+}
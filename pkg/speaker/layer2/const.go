@@ -14,4 +14,6 @@ const (
 	dropReasonLeader
 	dropReasonNotNeighborSolicitation
 	dropReasonNotSourceDirection
+	dropReasonConflict
+	dropReasonAdvertisement
 )
@@ -0,0 +1,111 @@
+package layer2
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/memberlist"
+	"github.com/openelb/openelb/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// The keyring secret holds the primary memberlist key under `key`, and the
+// other keys still accepted for decryption under `keys`, separated by commas
+// or newlines. A key is rotated without restarting the speakers by:
+//  1. adding the new key to `keys`, so every speaker can decrypt with it;
+//  2. moving the new key to `key` and the old one to `keys`, switching the primary;
+//  3. removing the old key from `keys`.
+const (
+	keyringPrimaryKey = "key"
+	keyringKeysKey    = "keys"
+)
+
+//+kubebuilder:rbac:groups="",namespace=openelb-system,resources=secrets,verbs=get;list;watch
+
+// watchKeyring keeps the memberlist keyring in sync with the keyring secret.
+func (m *memberlistOwnership) watchKeyring(stopCh <-chan struct{}) {
+	if m.keyringSecret == "" || m.keyring == nil {
+		return
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(m.client, 0,
+		informers.WithNamespace(util.EnvNamespace()),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", m.keyringSecret).String()
+		}))
+
+	update := func(obj interface{}) {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok {
+			return
+		}
+
+		if err := updateKeyring(m.keyring, secret); err != nil {
+			klog.Errorf("update memberlist keyring from secret %s error: %s", secret.Name, err.Error())
+		}
+	}
+	factory.Core().V1().Secrets().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: update,
+		UpdateFunc: func(_, obj interface{}) {
+			update(obj)
+		},
+	})
+	factory.Start(stopCh)
+}
+
+// updateKeyring installs every key of the secret, switches the primary key,
+// then removes the keys no longer listed in the secret.
+func updateKeyring(keyring *memberlist.Keyring, secret *corev1.Secret) error {
+	primary := secret.Data[keyringPrimaryKey]
+	if len(primary) == 0 {
+		return fmt.Errorf("missing %s", keyringPrimaryKey)
+	}
+
+	keys := [][]byte{primary}
+	for _, k := range strings.FieldsFunc(string(secret.Data[keyringKeysKey]), func(r rune) bool {
+		return r == ',' || r == '\n'
+	}) {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, []byte(k))
+		}
+	}
+
+	for _, k := range keys {
+		if err := keyring.AddKey(k); err != nil {
+			return err
+		}
+	}
+
+	if !bytes.Equal(keyring.GetPrimaryKey(), primary) {
+		klog.Info("switch the primary memberlist key")
+		if err := keyring.UseKey(primary); err != nil {
+			return err
+		}
+	}
+
+	installed := append([][]byte{}, keyring.GetKeys()...)
+	for _, installedKey := range installed {
+		wanted := false
+		for _, k := range keys {
+			if bytes.Equal(installedKey, k) {
+				wanted = true
+				break
+			}
+		}
+
+		if !wanted {
+			klog.Info("remove a memberlist key no longer in the keyring secret")
+			if err := keyring.RemoveKey(installedKey); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package layer2
+
+import (
+	"testing"
+
+	"github.com/hashicorp/memberlist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestUpdateKeyring(t *testing.T) {
+	oldKey := []byte("openelb-speakers")
+	newKey := []byte("rotated-key-0001")
+	keyring, err := memberlist.NewKeyring(nil, oldKey)
+	require.NoError(t, err)
+
+	secret := func(primary, keys string) *corev1.Secret {
+		return &corev1.Secret{Data: map[string][]byte{
+			keyringPrimaryKey: []byte(primary),
+			keyringKeysKey:    []byte(keys),
+		}}
+	}
+
+	// install the new key
+	require.NoError(t, updateKeyring(keyring, secret(string(oldKey), string(newKey))))
+	assert.Equal(t, oldKey, keyring.GetPrimaryKey())
+	assert.Len(t, keyring.GetKeys(), 2)
+
+	// switch primary
+	require.NoError(t, updateKeyring(keyring, secret(string(newKey), string(oldKey)+"\n")))
+	assert.Equal(t, newKey, keyring.GetPrimaryKey())
+	assert.Len(t, keyring.GetKeys(), 2)
+
+	// remove the old key
+	require.NoError(t, updateKeyring(keyring, secret(string(newKey), "")))
+	assert.Equal(t, [][]byte{newKey}, keyring.GetKeys())
+
+	assert.Error(t, updateKeyring(keyring, secret("", "")))
+	assert.Error(t, updateKeyring(keyring, secret("too-short", "")))
+}
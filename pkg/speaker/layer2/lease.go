@@ -0,0 +1,311 @@
+package layer2
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/util"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+var _ Ownership = &leaseOwnership{}
+
+//+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;delete
+
+// leaseOwnership elects the announcing node of every ip through a
+// coordination.k8s.io Lease, so no extra port is needed between the speakers.
+// The holder renews the lease periodically, and any other candidate takes it
+// over once it expires.
+type leaseOwnership struct {
+	client        kubernetes.Interface
+	namespace     string
+	identity      string
+	leaseDuration time.Duration
+	onChange      func(ip string, owned bool)
+
+	lock       sync.Mutex
+	candidates map[string][]string
+	owned      map[string]time.Time
+	// the ips the local node stepped down from
+	steppedDown map[string]bool
+}
+
+func newLeaseOwnership(client kubernetes.Interface, opt *Options, onChange func(string, bool)) *leaseOwnership {
+	return &leaseOwnership{
+		client:        client,
+		namespace:     util.EnvNamespace(),
+		identity:      opt.NodeName,
+		leaseDuration: opt.LeaseDuration,
+		onChange:      onChange,
+		candidates:    make(map[string][]string),
+		owned:         make(map[string]time.Time),
+		steppedDown:   make(map[string]bool),
+	}
+}
+
+// leaseName returns a valid object name for the lease of ip.
+func leaseName(ip string) string {
+	name := constant.Layer2LeasePrefix + strings.ReplaceAll(ip, ":", "-")
+	if strings.HasSuffix(name, "-") {
+		name += "0"
+	}
+	return name
+}
+
+func (l *leaseOwnership) Start(stopCh <-chan struct{}) error {
+	t := time.NewTicker(l.leaseDuration / 3)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			l.releaseAll()
+			return nil
+		case <-t.C:
+			l.renewAll()
+		}
+	}
+}
+
+func (l *leaseOwnership) Elect(ip string, candidates []string) (bool, error) {
+	l.lock.Lock()
+	if l.steppedDown[ip] {
+		candidates = util.RemoveString(candidates, l.identity)
+	}
+	l.candidates[ip] = candidates
+	l.lock.Unlock()
+
+	if !util.ContainsString(candidates, l.identity) {
+		return false, l.release(ip)
+	}
+
+	owned, err := l.tryAcquireOrRenew(context.Background(), ip, candidates)
+	if err != nil {
+		return false, err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if owned {
+		l.owned[ip] = time.Now()
+	} else {
+		delete(l.owned, ip)
+	}
+	return owned, nil
+}
+
+// Release gives up the ip which isn't a balancer anymore, and deletes its
+// lease unless another node holds it, so the leases don't pile up with every
+// ip ever announced. A node still electing the ip creates it again.
+func (l *leaseOwnership) Release(ip string) error {
+	l.lock.Lock()
+	delete(l.candidates, ip)
+	delete(l.owned, ip)
+	delete(l.steppedDown, ip)
+	l.lock.Unlock()
+
+	ctx := context.Background()
+	leases := l.client.CoordinationV1().Leases(l.namespace)
+	lease, err := leases.Get(ctx, leaseName(ip), metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	holder := ""
+	if lease.Spec.HolderIdentity != nil {
+		holder = *lease.Spec.HolderIdentity
+	}
+	if holder != "" && holder != l.identity && !leaseExpired(lease, time.Now()) {
+		return nil
+	}
+
+	klog.Infof("delete lease %s of %s", lease.Name, ip)
+	err = leases.Delete(ctx, lease.Name, metav1.DeleteOptions{
+		// the lease taken over in the meantime is kept
+		Preconditions: &metav1.Preconditions{ResourceVersion: &lease.ResourceVersion},
+	})
+	if errors.IsNotFound(err) || errors.IsConflict(err) {
+		return nil
+	}
+	return err
+}
+
+// StepDown releases the lease of ip and leaves the local node out of its
+// candidates, so another candidate takes it over.
+func (l *leaseOwnership) StepDown(ip string) error {
+	l.lock.Lock()
+	l.steppedDown[ip] = true
+	if candidates, ok := l.candidates[ip]; ok {
+		l.candidates[ip] = util.RemoveString(candidates, l.identity)
+	}
+	l.lock.Unlock()
+
+	return l.release(ip)
+}
+
+func (l *leaseOwnership) release(ip string) error {
+	l.lock.Lock()
+	_, owned := l.owned[ip]
+	delete(l.owned, ip)
+	l.lock.Unlock()
+
+	if !owned {
+		return nil
+	}
+
+	ctx := context.Background()
+	lease, err := l.client.CoordinationV1().Leases(l.namespace).Get(ctx, leaseName(ip), metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != l.identity {
+		return nil
+	}
+
+	klog.Infof("release lease %s of %s", lease.Name, ip)
+	lease.Spec.HolderIdentity = nil
+	lease.Spec.RenewTime = nil
+	_, err = l.client.CoordinationV1().Leases(l.namespace).Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+func (l *leaseOwnership) releaseAll() {
+	l.lock.Lock()
+	ips := []string{}
+	for ip := range l.owned {
+		ips = append(ips, ip)
+	}
+	l.lock.Unlock()
+
+	for _, ip := range ips {
+		if err := l.release(ip); err != nil {
+			klog.Warningf("release lease of %s error: %s", ip, err.Error())
+		}
+	}
+}
+
+// renewAll renews the leases the local node holds and takes over the expired
+// ones it is a candidate for, reporting every change of ownership.
+func (l *leaseOwnership) renewAll() {
+	l.lock.Lock()
+	candidates := make(map[string][]string, len(l.candidates))
+	for ip, nodes := range l.candidates {
+		candidates[ip] = nodes
+	}
+	l.lock.Unlock()
+
+	for ip, nodes := range candidates {
+		if !util.ContainsString(nodes, l.identity) {
+			continue
+		}
+
+		owned, err := l.tryAcquireOrRenew(context.Background(), ip, nodes)
+
+		l.lock.Lock()
+		lastRenew, wasOwned := l.owned[ip]
+		if err != nil {
+			klog.Warningf("renew lease of %s error: %s", ip, err.Error())
+			// Keep announcing until the lease could have been taken over by others.
+			owned = wasOwned && time.Since(lastRenew) < l.leaseDuration
+		}
+		if owned && err == nil {
+			l.owned[ip] = time.Now()
+		} else if !owned {
+			delete(l.owned, ip)
+		}
+		l.lock.Unlock()
+
+		if owned != wasOwned {
+			l.onChange(ip, owned)
+		}
+	}
+}
+
+func (l *leaseOwnership) tryAcquireOrRenew(ctx context.Context, ip string, candidates []string) (bool, error) {
+	leases := l.client.CoordinationV1().Leases(l.namespace)
+	now := metav1.NewMicroTime(time.Now())
+	seconds := int32(l.leaseDuration.Seconds())
+
+	lease, err := leases.Get(ctx, leaseName(ip), metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return false, err
+		}
+
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        leaseName(ip),
+				Namespace:   l.namespace,
+				Annotations: map[string]string{constant.OpenELBLayer2LeaseIPAnnotation: ip},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &l.identity,
+				LeaseDurationSeconds: &seconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		if _, err := leases.Create(ctx, lease, metav1.CreateOptions{}); err != nil {
+			if errors.IsAlreadyExists(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		klog.Infof("[%s] acquires the lease to announce the IP address %s", l.identity, ip)
+		return true, nil
+	}
+
+	holder := ""
+	if lease.Spec.HolderIdentity != nil {
+		holder = *lease.Spec.HolderIdentity
+	}
+
+	if holder != l.identity {
+		if holder != "" && !leaseExpired(lease, now.Time) && util.ContainsString(candidates, holder) {
+			return false, nil
+		}
+
+		klog.Infof("[%s] takes over the lease to announce the IP address %s from [%s]", l.identity, ip, holder)
+		transitions := int32(0)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions = *lease.Spec.LeaseTransitions
+		}
+		transitions++
+		lease.Spec.HolderIdentity = &l.identity
+		lease.Spec.AcquireTime = &now
+		lease.Spec.LeaseTransitions = &transitions
+	}
+
+	lease.Spec.RenewTime = &now
+	lease.Spec.LeaseDurationSeconds = &seconds
+	if _, err := leases.Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+		if errors.IsConflict(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func leaseExpired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+
+	duration := time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	return lease.Spec.RenewTime.Add(duration).Before(now)
+}
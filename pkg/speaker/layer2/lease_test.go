@@ -0,0 +1,83 @@
+package layer2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openelb/openelb/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLeaseName(t *testing.T) {
+	assert.Equal(t, "openelb-layer2-192.168.0.10", leaseName("192.168.0.10"))
+	assert.Equal(t, "openelb-layer2-fd00--1", leaseName("fd00::1"))
+	assert.Equal(t, "openelb-layer2-fd00--0", leaseName("fd00::"))
+}
+
+func TestLeaseOwnership(t *testing.T) {
+	ip := "192.168.0.10"
+	client := fake.NewSimpleClientset()
+	changes := map[string]bool{}
+	newOwnership := func(node string) *leaseOwnership {
+		return newLeaseOwnership(client, &Options{NodeName: node, LeaseDuration: 15 * time.Second}, func(ip string, owned bool) {
+			changes[node] = owned
+		})
+	}
+	a, b := newOwnership("node1"), newOwnership("node2")
+
+	owned, err := a.Elect(ip, []string{"node1", "node2"})
+	require.NoError(t, err)
+	assert.True(t, owned)
+
+	owned, err = b.Elect(ip, []string{"node1", "node2"})
+	require.NoError(t, err)
+	assert.False(t, owned, "the lease is held by node1")
+
+	// node1 stops renewing, node2 takes over once the lease expires
+	leases := client.CoordinationV1().Leases(util.EnvNamespace())
+	lease, err := leases.Get(context.Background(), leaseName(ip), metav1.GetOptions{})
+	require.NoError(t, err)
+	expired := metav1.NewMicroTime(time.Now().Add(-time.Minute))
+	lease.Spec.RenewTime = &expired
+	_, err = leases.Update(context.Background(), lease, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	b.renewAll()
+	assert.True(t, changes["node2"])
+	a.renewAll()
+	assert.False(t, changes["node1"])
+
+	// a holder that is no longer a candidate is replaced right away
+	owned, err = a.Elect(ip, []string{"node1"})
+	require.NoError(t, err)
+	assert.True(t, owned)
+
+	// the lease held by another node is kept
+	require.NoError(t, b.Release(ip))
+	_, err = leases.Get(context.Background(), leaseName(ip), metav1.GetOptions{})
+	require.NoError(t, err)
+
+	// and deleted by the holder once the ip isn't a balancer anymore
+	require.NoError(t, a.Release(ip))
+	_, err = leases.Get(context.Background(), leaseName(ip), metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	require.NoError(t, a.Release(ip))
+
+	// a lease left by a stopped node is deleted once it expires
+	owned, err = b.Elect(ip, []string{"node2"})
+	require.NoError(t, err)
+	assert.True(t, owned)
+	lease, err = leases.Get(context.Background(), leaseName(ip), metav1.GetOptions{})
+	require.NoError(t, err)
+	lease.Spec.RenewTime = &expired
+	_, err = leases.Update(context.Background(), lease, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, a.Release(ip))
+	_, err = leases.Get(context.Background(), leaseName(ip), metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
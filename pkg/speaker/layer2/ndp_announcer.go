@@ -18,13 +18,14 @@ type ndpAnnouncer struct {
 	conn  *ndp.Conn
 	addrs []netlink.Addr
 
-	stopCh   chan struct{}
-	lock     sync.RWMutex
-	ip2mac   map[string]net.HardwareAddr
-	ipranges map[string]iprange.Range
+	stopCh    chan struct{}
+	lock      sync.RWMutex
+	ip2mac    map[string]net.HardwareAddr
+	ipranges  map[string]iprange.Range
+	conflicts *conflictTracker
 }
 
-func newNDPAnnouncer(ifi *net.Interface) (*ndpAnnouncer, error) {
+func newNDPAnnouncer(ifi *net.Interface, onConflict conflictHandler) (*ndpAnnouncer, error) {
 	conn, _, err := ndp.Listen(ifi, ndp.LinkLocal)
 	if err != nil {
 		return nil, fmt.Errorf("creating NDP Announcer for %s, err=%v", ifi.Name, err)
@@ -34,12 +35,13 @@ func newNDPAnnouncer(ifi *net.Interface) (*ndpAnnouncer, error) {
 	addrs, _ := netlink.AddrList(link, netlink.FAMILY_V6)
 
 	ret := &ndpAnnouncer{
-		intf:     ifi,
-		conn:     conn,
-		addrs:    addrs,
-		stopCh:   make(chan struct{}),
-		ip2mac:   make(map[string]net.HardwareAddr),
-		ipranges: make(map[string]iprange.Range),
+		intf:      ifi,
+		conn:      conn,
+		addrs:     addrs,
+		stopCh:    make(chan struct{}),
+		ip2mac:    make(map[string]net.HardwareAddr),
+		ipranges:  make(map[string]iprange.Range),
+		conflicts: newConflictTracker(onConflict),
 	}
 	return ret, nil
 }
@@ -77,11 +79,24 @@ func (n *ndpAnnouncer) DelAnnouncedIP(ip net.IP) error {
 	}
 
 	delete(n.ip2mac, ip.String())
+	n.conflicts.forget(ip.String())
 	metrics.DeleteLayer2Metrics(ip.String())
 
 	return nil
 }
 
+// withdraw stops answering for ip, the conflicts of ip are still known so
+// a step down isn't reported again. The multicast group is left once ip is
+// deleted.
+func (n *ndpAnnouncer) withdraw(ip net.IP) {
+	klog.Infof("cancel respone %s's ndp packet", ip)
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	delete(n.ip2mac, ip.String())
+	metrics.DeleteLayer2Metrics(ip.String())
+}
+
 func (n *ndpAnnouncer) JoinMulticastGroup(ip netip.Addr) error {
 	if !ip.Is6() {
 		return fmt.Errorf("join multicastgroup need ipv6")
@@ -184,6 +199,10 @@ func (n *ndpAnnouncer) processRequest() dropReason {
 		return dropReasonError
 	}
 
+	if na, ok := msg.(*ndp.NeighborAdvertisement); ok {
+		return n.processAdvertisement(na)
+	}
+
 	ns, ok := msg.(*ndp.NeighborSolicitation)
 	if !ok {
 		return dropReasonNotNeighborSolicitation
@@ -220,6 +239,36 @@ func (n *ndpAnnouncer) processRequest() dropReason {
 	return dropReasonNone
 }
 
+// processAdvertisement checks whether another host advertises one of our ips,
+// which means a split-brain or an external ip conflict.
+func (n *ndpAnnouncer) processAdvertisement(na *ndp.NeighborAdvertisement) dropReason {
+	var naHwAddr net.HardwareAddr
+	for _, o := range na.Options {
+		lla, ok := o.(*ndp.LinkLayerAddress)
+		if !ok || lla.Direction != ndp.Target {
+			continue
+		}
+		naHwAddr = lla.Addr
+		break
+	}
+
+	ip := net.IP(na.TargetAddress.AsSlice())
+	conflict, stepDown := n.conflicts.check(ip, n.getMac(na.TargetAddress.String()), naHwAddr)
+	if !conflict {
+		return dropReasonAdvertisement
+	}
+
+	if stepDown {
+		klog.Warningf("interface %s step down from announcing %s", n.intf.Name, ip)
+		n.withdraw(ip)
+	}
+	return dropReasonConflict
+}
+
+func (n *ndpAnnouncer) Announcing(ip net.IP) bool {
+	return n.getMac(ip.String()) != nil
+}
+
 func (n *ndpAnnouncer) getMac(ip string) *net.HardwareAddr {
 	n.lock.RLock()
 	defer n.lock.RUnlock()
@@ -1,26 +1,39 @@
 package layer2
 
 import (
+	"time"
+
 	"github.com/openelb/openelb/pkg/constant"
 	"github.com/openelb/openelb/pkg/util"
 	"github.com/spf13/pflag"
 )
 
 type Options struct {
-	EnableLayer2 bool
-	NodeName     string
-	BindAddr     string
-	BindPort     int
-	SecretKey    string
+	EnableLayer2  bool
+	NodeName      string
+	BindAddr      string
+	BindPort      int
+	SecretKey     string
+	KeyringSecret string
+	// StepDownOnConflict stops announcing an ip once another host is seen claiming it
+	StepDownOnConflict bool
+	// Ownership is the backend electing the announcing node, memberlist or lease
+	Ownership     string
+	LeaseDuration time.Duration
 }
 
 func NewOptions() *Options {
 	return &Options{
-		EnableLayer2: false,
-		NodeName:     util.GetNodeName(),
-		BindAddr:     "0.0.0.0",
-		BindPort:     7946,
-		SecretKey:    constant.Layer2MemberlistDefaultSecret,
+		EnableLayer2:  false,
+		NodeName:      util.GetNodeName(),
+		BindAddr:      "0.0.0.0",
+		BindPort:      7946,
+		SecretKey:     constant.Layer2MemberlistDefaultSecret,
+		KeyringSecret: constant.Layer2MemberlistDefaultSecret,
+
+		StepDownOnConflict: false,
+		Ownership:          constant.Layer2OwnershipMemberlist,
+		LeaseDuration:      15 * time.Second,
 	}
 }
 
@@ -30,4 +43,8 @@ func (v *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&v.BindAddr, "bind-addr", v.BindAddr, "specify the port on which the member list listens")
 	fs.IntVar(&v.BindPort, "bind-port", v.BindPort, "specify the address where the member list listens")
 	fs.StringVar(&v.SecretKey, "secret", v.SecretKey, "specify the memberlist's secret")
+	fs.StringVar(&v.KeyringSecret, "keyring-secret", v.KeyringSecret, "specify the secret watched to rotate the memberlist's keys, empty to disable")
+	fs.BoolVar(&v.StepDownOnConflict, "step-down-on-conflict", v.StepDownOnConflict, "specify whether to stop announcing an ip when another host claims it, the host with the lower mac keeps the ip")
+	fs.StringVar(&v.Ownership, "layer2-ownership", v.Ownership, "specify how the announcing node is elected, memberlist or lease")
+	fs.DurationVar(&v.LeaseDuration, "lease-duration", v.LeaseDuration, "specify the duration of the layer2 leases when layer2-ownership is lease")
 }
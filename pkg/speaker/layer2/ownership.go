@@ -0,0 +1,33 @@
+package layer2
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sort"
+)
+
+// Ownership decides which node announces an ip.
+type Ownership interface {
+	// Start runs until stopCh is closed.
+	Start(stopCh <-chan struct{}) error
+	// Elect returns true if the local node should announce ip, given the
+	// nodes that are able to serve it.
+	Elect(ip string, candidates []string) (bool, error)
+	// Release gives up any claim the local node holds on ip.
+	Release(ip string) error
+	// StepDown gives up ip claimed by another host, the local node isn't
+	// elected for it again until it is released.
+	StepDown(ip string) error
+}
+
+// sortCandidates sorts the nodes by the hash of node + load balancer ips. This
+// produces an ordering of ready nodes that is unique to all the services
+// with the same ip.
+func sortCandidates(ip string, nodes []string) {
+	sort.Slice(nodes, func(i, j int) bool {
+		hi := sha256.Sum256([]byte(nodes[i] + "#" + ip))
+		hj := sha256.Sum256([]byte(nodes[j] + "#" + ip))
+
+		return bytes.Compare(hi[:], hj[:]) < 0
+	})
+}
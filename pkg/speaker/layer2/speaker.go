@@ -0,0 +1,265 @@
+package layer2
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/speaker"
+	"github.com/openelb/openelb/pkg/util"
+	"github.com/openelb/openelb/pkg/util/iprange"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ speaker.Speaker = &layer2Speaker{}
+var _ speaker.BalancerOwner = &layer2Speaker{}
+
+func NewSpeaker(client *kubernetes.Clientset, recorder record.EventRecorder, opt *Options, reloadChan chan event.GenericEvent) (speaker.Speaker, error) {
+	l := &layer2Speaker{
+		recorder:   recorder,
+		stepDown:   opt.StepDownOnConflict,
+		announcers: map[string]Announcer{},
+	}
+
+	switch opt.Ownership {
+	case constant.Layer2OwnershipMemberlist:
+		ownership, err := newMemberlistOwnership(client, opt, reloadChan)
+		if err != nil {
+			return nil, err
+		}
+		l.ownership = ownership
+	case constant.Layer2OwnershipLease:
+		l.ownership = newLeaseOwnership(client, opt, l.handleOwnershipChange)
+	default:
+		return nil, fmt.Errorf("unsupported layer2 ownership %s", opt.Ownership)
+	}
+
+	return l, nil
+}
+
+type layer2Speaker struct {
+	ownership Ownership
+	recorder  record.EventRecorder
+	stepDown  bool
+
+	// nic - announcers
+	lock       sync.Mutex
+	announcers map[string]Announcer
+	// notify is called when the node may have gained or lost some ips
+	notify func()
+}
+
+func (l *layer2Speaker) SetBalancer(ip string, clusterNodes []corev1.Node) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, a := range l.announcers {
+		if a.ContainsIP(net.ParseIP(ip)) {
+			nodes := []string{}
+			for _, n := range clusterNodes {
+				nodes = append(nodes, n.GetName())
+			}
+
+			win, err := l.ownership.Elect(ip, nodes)
+			if err != nil {
+				return err
+			}
+			if !win {
+				return nil
+			}
+			if !a.Announcing(net.ParseIP(ip)) {
+				defer l.notifyOwnership()
+			}
+			return a.AddAnnouncedIP(net.ParseIP(ip))
+		}
+	}
+
+	klog.Warningf("The announcers of the speakers do not contain the %s", ip)
+	return nil
+}
+
+func (l *layer2Speaker) DelBalancer(ip string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if err := l.ownership.Release(ip); err != nil {
+		klog.Warningf("release ownership of %s error: %s", ip, err.Error())
+	}
+
+	for _, a := range l.announcers {
+		if a.ContainsIP(net.ParseIP(ip)) {
+			defer l.notifyOwnership()
+			return a.DelAnnouncedIP(net.ParseIP(ip))
+		}
+	}
+	return nil
+}
+
+// OwnBalancer returns true if the node is elected to announce ip.
+func (l *layer2Speaker) OwnBalancer(ip string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, a := range l.announcers {
+		if a.ContainsIP(net.ParseIP(ip)) {
+			return a.Announcing(net.ParseIP(ip))
+		}
+	}
+	return false
+}
+
+// NotifyOwnership sets the function called when the node may have gained or
+// lost some ips.
+func (l *layer2Speaker) NotifyOwnership(notify func()) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.notify = notify
+}
+
+// notifyOwnership calls notify, the caller must hold the lock.
+func (l *layer2Speaker) notifyOwnership() {
+	if l.notify != nil {
+		l.notify()
+	}
+}
+
+func (l *layer2Speaker) Start(stopCh <-chan struct{}) error {
+	defer l.unregisterAllAnnouncers()
+
+	return l.ownership.Start(stopCh)
+}
+
+func (l *layer2Speaker) ConfigureWithEIP(config speaker.Config, deleted bool) error {
+	netif, err := speaker.ParseInterface(config.Iface, config.NodeLabels)
+	if err != nil || netif == nil {
+		return err
+	}
+
+	if err := speaker.ValidateInterface(netif, config.IPRange); err != nil {
+		return err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if deleted {
+		return l.unregisterAnnouncer(config.Name, netif.Name)
+	}
+	return l.registerAnnouncer(config.Name, netif, config.IPRange)
+}
+
+func (l *layer2Speaker) registerAnnouncer(eipName string, netif *net.Interface, r iprange.Range) error {
+	a, exist := l.announcers[netif.Name]
+	if !exist {
+		// no announcer for the interface, create a new one
+		var err error
+		a, err = newAnnouncer(netif, r.Family(), l.handleConflict)
+		if err != nil {
+			return fmt.Errorf("new Announcer error. interface %s, error %s", netif.Name, err.Error())
+		}
+		klog.Infof("use interface %s to announce eip[%s]", netif.Name, eipName)
+
+		if err := a.Start(); err != nil {
+			return err
+		}
+		l.announcers[netif.Name] = a
+	}
+
+	a.RegisterIPRange(eipName, r)
+	return nil
+}
+
+func (l *layer2Speaker) unregisterAnnouncer(eipName, netifName string) error {
+	a, exist := l.announcers[netifName]
+	if !exist {
+		return nil
+	}
+
+	klog.Infof("cancel interface %s to announce eip[%s]'s arp", netifName, eipName)
+	a.UnregisterIPRange(eipName)
+	if a.Size() == 0 {
+		if err := a.Stop(); err != nil {
+			return err
+		}
+
+		delete(l.announcers, netifName)
+	}
+	return nil
+}
+
+// handleConflict reports another host claiming ip on the node's events,
+// and tells the announcer whether to step down. When both hosts see the
+// conflict, only the one announcing the greater mac steps down so the ip
+// isn't withdrawn by both of them. The step down goes through the ownership
+// so the local node isn't elected for ip again on the next resync, which
+// would flap against the other host.
+func (l *layer2Speaker) handleConflict(ip net.IP, own, foreign net.HardwareAddr) bool {
+	stepDown := l.stepDown && bytes.Compare(own, foreign) > 0
+	if l.recorder != nil {
+		node := &corev1.ObjectReference{Kind: "Node", Name: util.GetNodeName(), UID: types.UID(util.GetNodeName())}
+		l.recorder.Eventf(node, corev1.EventTypeWarning, "Layer2Conflict",
+			"ip %s announced by this node as %s is also claimed by %s, step down: %t", ip, own, foreign, stepDown)
+	}
+
+	if stepDown {
+		// the announcer is reading packets, don't block it on the apiserver
+		go func() {
+			if err := l.ownership.StepDown(ip.String()); err != nil {
+				klog.Warningf("step down from %s error: %s", ip, err.Error())
+			}
+
+			l.lock.Lock()
+			defer l.lock.Unlock()
+			l.notifyOwnership()
+		}()
+	}
+	return stepDown
+}
+
+// handleOwnershipChange starts or stops announcing ip when the ownership
+// backend observes the local node gaining or losing it outside of SetBalancer.
+func (l *layer2Speaker) handleOwnershipChange(ip string, owned bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, a := range l.announcers {
+		if !a.ContainsIP(net.ParseIP(ip)) {
+			continue
+		}
+
+		var err error
+		if owned {
+			klog.Infof("take over the announcement of %s", ip)
+			err = a.AddAnnouncedIP(net.ParseIP(ip))
+		} else {
+			klog.Infof("lost the announcement of %s", ip)
+			err = a.DelAnnouncedIP(net.ParseIP(ip))
+		}
+		if err != nil {
+			klog.Errorf("handle ownership change of %s error: %s", ip, err.Error())
+		}
+		l.notifyOwnership()
+		return
+	}
+}
+
+func (l *layer2Speaker) unregisterAllAnnouncers() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, a := range l.announcers {
+		if err := a.Stop(); err != nil {
+			klog.Errorf("stop announcer error. %s", err.Error())
+		}
+	}
+
+	l.announcers = map[string]Announcer{}
+}
@@ -15,7 +15,9 @@ import (
 	"github.com/openelb/openelb/pkg/util/iprange"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -30,11 +32,13 @@ type Manager struct {
 	client.Client
 	record.EventRecorder
 
-	mgr       manager.Manager
-	speakers  map[string]speakerWithCancelFunc
-	pools     map[string]*v1alpha2.Eip
-	waitGroup sync.WaitGroup
-	errChan   chan error
+	mgr      manager.Manager
+	speakers map[string]speakerWithCancelFunc
+	pools    map[string]*v1alpha2.Eip
+	// the values of the node labels selecting the interfaces of the eips
+	ifaceLabels map[string]string
+	waitGroup   sync.WaitGroup
+	errChan     chan error
 }
 
 func NewSpeakerManager(mgr manager.Manager) *Manager {
@@ -44,6 +48,7 @@ func NewSpeakerManager(mgr manager.Manager) *Manager {
 		EventRecorder: mgr.GetEventRecorderFor("speakerManager"),
 		speakers:      make(map[string]speakerWithCancelFunc, 0),
 		pools:         make(map[string]*v1alpha2.Eip, 0),
+		ifaceLabels:   make(map[string]string),
 		errChan:       make(chan error),
 	}
 }
@@ -120,8 +125,12 @@ func (m *Manager) HandleEIP(ctx context.Context, eip *v1alpha2.Eip) error {
 		return nil
 	}
 
-	if _, exist := m.speakers[eip.GetProtocol()]; !exist {
-		return fmt.Errorf("no registered speaker:[%s] eip:[%s]", eip.GetProtocol(), eip.GetName())
+	if len(m.registeredProtocols(eip)) == 0 {
+		err := fmt.Errorf("no registered speaker:[%s] eip:[%s]", strings.Join(eip.GetProtocols(), ","), eip.GetName())
+		if eip.DeletionTimestamp.IsZero() {
+			m.updateNodeStatus(ctx, eip, v1alpha2.NodeEipStatus{Error: err.Error()})
+		}
+		return err
 	}
 
 	oldData, exist := m.pools[eip.GetName()]
@@ -144,8 +153,13 @@ func (m *Manager) HandleEIP(ctx context.Context, eip *v1alpha2.Eip) error {
 		return nil
 	}
 
+	labelUpdate, err := m.isInterfaceLabelUpdate(ctx, eip)
+	if err != nil {
+		return err
+	}
+
 	// update speaker configurate
-	if m.isSpeakerConfigUpdate(eip.Spec, oldData.Spec) {
+	if labelUpdate || m.isSpeakerConfigUpdate(eip.Spec, oldData.Spec) {
 		klog.V(1).Infof("update protocol with eip:%s", eip.GetName())
 		if err := m.delBalancerWithEIP(ctx, oldData); err != nil {
 			return err
@@ -163,34 +177,70 @@ func (m *Manager) HandleEIP(ctx context.Context, eip *v1alpha2.Eip) error {
 	if !reflect.DeepEqual(eip.Status.Used, oldData.Status.Used) {
 		klog.V(1).Infof("update status with eip:%s", eip.GetName())
 		add, del := util.DiffMaps(oldData.Status.Used, eip.Status.Used)
-		if err := m.delBalancer(ctx, eip.GetProtocol(), del); err != nil {
+		if err := m.delBalancer(ctx, eip.GetProtocols(), del); err != nil {
 			return err
 		}
-		if err := m.setBalancer(ctx, eip.GetProtocol(), add); err != nil {
+		if err := m.setBalancer(ctx, eip.GetProtocols(), add); err != nil {
 			return err
 		}
 		m.pools[eip.GetName()] = eip
+		return nil
 	}
 
 	klog.V(1).Infof("no need to handle eip:%s", eip.GetName())
+	m.pools[eip.GetName()] = eip
 	return nil
 }
 
 // update speaker configurate
-// protocol change or interface change
+// protocols change, address change or interface change
 func (m *Manager) isSpeakerConfigUpdate(old, new v1alpha2.EipSpec) bool {
-	if old.Protocol != new.Protocol {
+	oldEip, newEip := v1alpha2.Eip{Spec: old}, v1alpha2.Eip{Spec: new}
+	if !reflect.DeepEqual(oldEip.GetProtocols(), newEip.GetProtocols()) {
+		return true
+	}
+
+	// the speakers announce the ips in the range registered with the eip
+	if old.Address != new.Address {
 		return true
 	}
 
-	if new.Protocol != constant.OpenELBProtocolBGP && old.Interface != new.Interface {
+	// the interface is only unused by bgp
+	if old.Interface != new.Interface && !reflect.DeepEqual(newEip.GetProtocols(), []string{constant.OpenELBProtocolBGP}) {
 		return true
 	}
 	return false
 }
 
+// isInterfaceLabelUpdate reports whether the node label selecting the
+// interface of the eip is changed since the speakers are configured.
+func (m *Manager) isInterfaceLabelUpdate(ctx context.Context, eip *v1alpha2.Eip) (bool, error) {
+	key, ok := interfaceLabel(eip.Spec.Interface)
+	if !ok {
+		return false, nil
+	}
+
+	labels, err := m.getNodeLabels(ctx)
+	if err != nil {
+		return false, err
+	}
+	return labels[key] != m.ifaceLabels[eip.Name], nil
+}
+
+// registeredProtocols returns the protocols of the eip whose speakers are
+// registered, the others are skipped, e.g. layer2 isn't enabled on the node.
+func (m *Manager) registeredProtocols(eip *v1alpha2.Eip) []string {
+	protocols := []string{}
+	for _, protocol := range eip.GetProtocols() {
+		if _, exist := m.speakers[protocol]; exist {
+			protocols = append(protocols, protocol)
+		}
+	}
+	return protocols
+}
+
 func (m *Manager) delBalancerWithEIP(ctx context.Context, eip *v1alpha2.Eip) error {
-	if err := m.delBalancer(ctx, eip.GetProtocol(), eip.Status.Used); err != nil {
+	if err := m.delBalancer(ctx, eip.GetProtocols(), eip.Status.Used); err != nil {
 		return err
 	}
 
@@ -199,20 +249,49 @@ func (m *Manager) delBalancerWithEIP(ctx context.Context, eip *v1alpha2.Eip) err
 		return err
 	}
 
-	c := Config{Name: eip.Name, Iface: eip.Spec.Interface, IPRange: r}
-	if err := m.speakers[eip.GetProtocol()].ConfigureWithEIP(c, true); err != nil {
-		m.Event(eip, corev1.EventTypeWarning, "ConfigSpeakerFailed", err.Error())
+	labels, err := m.getNodeLabels(ctx)
+	if err != nil {
 		return err
 	}
-	m.Event(eip, corev1.EventTypeNormal, "ConfigSpeaker", fmt.Sprintf("unconfig openelb %s speaker successfully", eip.GetProtocol()))
-	return nil
+	if key, ok := interfaceLabel(eip.Spec.Interface); ok {
+		// unconfig the interface selected when the speakers were configured
+		selected := map[string]string{}
+		for k, v := range labels {
+			selected[k] = v
+		}
+		selected[key] = m.ifaceLabels[eip.Name]
+		labels = selected
+	}
+
+	c := Config{Name: eip.Name, Iface: eip.Spec.Interface, IPRange: r, NodeLabels: labels}
+	errs := []error{}
+	for _, protocol := range m.registeredProtocols(eip) {
+		if err := m.speakers[protocol].ConfigureWithEIP(c, true); err != nil {
+			m.Event(eip, corev1.EventTypeWarning, "ConfigSpeakerFailed", err.Error())
+			errs = append(errs, err)
+			continue
+		}
+		m.Event(eip, corev1.EventTypeNormal, "ConfigSpeaker", fmt.Sprintf("unconfig openelb %s speaker successfully", protocol))
+	}
+	delete(m.ifaceLabels, eip.Name)
+	return utilerrors.NewAggregate(errs)
 }
 
-func (m *Manager) delBalancer(ctx context.Context, protocol string, usage map[string]string) error {
+func (m *Manager) delBalancer(ctx context.Context, protocols []string, usage map[string]string) error {
 	for ip, svcs := range usage {
-		if err := m.speakers[protocol].DelBalancer(ip); err != nil {
-			m.addSvcEventRecorder(ctx, svcs, corev1.EventTypeWarning, "DelBalancer", err.Error())
-			return err
+		errs := []error{}
+		for _, protocol := range protocols {
+			s, exist := m.speakers[protocol]
+			if !exist {
+				continue
+			}
+			if err := s.DelBalancer(ip); err != nil {
+				m.addSvcEventRecorder(ctx, svcs, corev1.EventTypeWarning, "DelBalancer", err.Error())
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) != 0 {
+			return utilerrors.NewAggregate(errs)
 		}
 
 		m.addSvcEventRecorder(ctx, svcs, corev1.EventTypeNormal, "DelBalancer", "success to withdraw announcement for service")
@@ -221,26 +300,47 @@ func (m *Manager) delBalancer(ctx context.Context, protocol string, usage map[st
 	return nil
 }
 
+// setBalancerWithEIP configures every registered speaker of the eip, the
+// balancers are set on the speakers configured successfully even if the
+// others failed, so a broken layer2 interface doesn't withdraw the bgp routes.
 func (m *Manager) setBalancerWithEIP(ctx context.Context, eip *v1alpha2.Eip) error {
 	r, err := iprange.ParseRange(eip.Spec.Address)
 	if err != nil {
 		return err
 	}
 
-	c := Config{Name: eip.Name, Iface: eip.Spec.Interface, IPRange: r}
-	if err := m.speakers[eip.GetProtocol()].ConfigureWithEIP(c, false); err != nil {
-		m.Event(eip, corev1.EventTypeWarning, "ConfigSpeakerFailed", err.Error())
+	labels, err := m.getNodeLabels(ctx)
+	if err != nil {
+		m.updateNodeStatus(ctx, eip, v1alpha2.NodeEipStatus{Error: err.Error()})
 		return err
 	}
-	m.Event(eip, corev1.EventTypeNormal, "ConfigSpeaker", fmt.Sprintf("config openelb %s speaker successfully", eip.GetProtocol()))
+	if key, ok := interfaceLabel(eip.Spec.Interface); ok {
+		m.ifaceLabels[eip.Name] = labels[key]
+	}
+
+	c := Config{Name: eip.Name, Iface: eip.Spec.Interface, IPRange: r, NodeLabels: labels}
+	configured := []string{}
+	errs := []error{}
+	for _, protocol := range m.registeredProtocols(eip) {
+		if err := m.speakers[protocol].ConfigureWithEIP(c, false); err != nil {
+			m.Event(eip, corev1.EventTypeWarning, "ConfigSpeakerFailed", err.Error())
+			errs = append(errs, err)
+			continue
+		}
+		m.Event(eip, corev1.EventTypeNormal, "ConfigSpeaker", fmt.Sprintf("config openelb %s speaker successfully", protocol))
+		configured = append(configured, protocol)
+	}
+	m.updateNodeStatus(ctx, eip, m.nodeStatus(eip, c, utilerrors.NewAggregate(errs)))
 
-	if err := m.setBalancer(ctx, eip.GetProtocol(), eip.Status.Used); err != nil {
+	if err := m.setBalancer(ctx, configured, eip.Status.Used); err != nil {
 		return err
 	}
-	return nil
+	return utilerrors.NewAggregate(errs)
 }
 
-func (m *Manager) setBalancer(ctx context.Context, protocol string, usage map[string]string) error {
+// setBalancer sets the balancers on the speakers of all the protocols, the
+// nexthops are computed once and shared by the speakers.
+func (m *Manager) setBalancer(ctx context.Context, protocols []string, usage map[string]string) error {
 	for ip, value := range usage {
 		nodes, err := m.getServiceNodes(ctx, ip, value)
 		if err != nil {
@@ -261,9 +361,19 @@ func (m *Manager) setBalancer(ctx context.Context, protocol string, usage map[st
 		sort.Slice(nodeNames, func(i, j int) bool {
 			return nodeNames[i] < nodeNames[j]
 		})
-		if err := m.speakers[protocol].SetBalancer(ip, nodes); err != nil {
-			m.addSvcEventRecorder(ctx, value, corev1.EventTypeWarning, "SetBalancer", err.Error())
-			return err
+		errs := []error{}
+		for _, protocol := range protocols {
+			s, exist := m.speakers[protocol]
+			if !exist {
+				continue
+			}
+			if err := s.SetBalancer(ip, nodes); err != nil {
+				m.addSvcEventRecorder(ctx, value, corev1.EventTypeWarning, "SetBalancer", err.Error())
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) != 0 {
+			return utilerrors.NewAggregate(errs)
 		}
 
 		m.addSvcEventRecorder(ctx, value, corev1.EventTypeNormal, "SetBalancer", fmt.Sprintf("success to add nexthops [%s]", strings.Join(nodeNames, ", ")))
@@ -271,6 +381,56 @@ func (m *Manager) setBalancer(ctx context.Context, protocol string, usage map[st
 	return nil
 }
 
+// nodeStatus returns the status of the eip on the node after the speaker is
+// configured with it, the interface is resolved again to report its name.
+func (m *Manager) nodeStatus(eip *v1alpha2.Eip, c Config, err error) v1alpha2.NodeEipStatus {
+	status := v1alpha2.NodeEipStatus{Ready: err == nil}
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	if (eip.HasProtocol(constant.OpenELBProtocolLayer2) || eip.HasProtocol(constant.OpenELBProtocolVip)) && c.Iface != "" {
+		if netif, err := ParseInterface(c.Iface, c.NodeLabels); err == nil {
+			status.Interface = netif.Name
+		}
+	}
+	return status
+}
+
+// updateNodeStatus records the status of the eip on the node in the status of
+// the eip, like the NodesPeerStatus of the BgpPeer.
+func (m *Manager) updateNodeStatus(ctx context.Context, eip *v1alpha2.Eip, status v1alpha2.NodeEipStatus) {
+	node := util.GetNodeName()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &v1alpha2.Eip{}
+		if err := m.Get(ctx, types.NamespacedName{Name: eip.Name}, latest); err != nil {
+			return err
+		}
+
+		if current, ok := latest.Status.NodesStatus[node]; ok && current == status {
+			return nil
+		}
+
+		if latest.Status.NodesStatus == nil {
+			latest.Status.NodesStatus = make(map[string]v1alpha2.NodeEipStatus)
+		}
+		latest.Status.NodesStatus[node] = status
+		return m.Status().Update(ctx, latest)
+	})
+	if err != nil {
+		klog.Warningf("update the status of eip %s on node %s failed, err: %s", eip.Name, node, err.Error())
+	}
+}
+
+// getNodeLabels returns the labels of the node of the speaker.
+func (m *Manager) getNodeLabels(ctx context.Context) (map[string]string, error) {
+	node := &corev1.Node{}
+	if err := m.Get(ctx, types.NamespacedName{Name: util.GetNodeName()}, node); err != nil {
+		return nil, fmt.Errorf("get node %s failed, err: %w", util.GetNodeName(), err)
+	}
+	return node.Labels, nil
+}
+
 func (m *Manager) addSvcEventRecorder(ctx context.Context, services, eventType, reason, message string) {
 	for _, str := range strings.Split(services, ";") {
 		svcInfo := strings.Split(str, "/")
@@ -319,7 +479,7 @@ func (m *Manager) HandleService(ctx context.Context, svc *corev1.Service) error
 		}
 	}
 
-	if err := m.setBalancer(ctx, eip.GetProtocol(), ingress); err != nil {
+	if err := m.setBalancer(ctx, eip.GetProtocols(), ingress); err != nil {
 		return err
 	}
 	return nil
@@ -405,18 +565,65 @@ func (m *Manager) getServiceNodes(ctx context.Context, ip, svcs string) ([]corev
 	return resultNodes, nil
 }
 
-func (m *Manager) ResyncEIPSpeaker(ctx context.Context) error {
+// OwnBalancer returns true if a speaker electing the node receiving the
+// traffic of the balancer elected the local node.
+func (m *Manager) OwnBalancer(ip string) bool {
+	for _, s := range m.speakers {
+		if owner, ok := s.Speaker.(BalancerOwner); ok && owner.OwnBalancer(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifyOwnership sets the function called when the ownership of the balancers
+// changes on all the speakers electing the node receiving the traffic.
+func (m *Manager) NotifyOwnership(notify func()) {
+	for _, s := range m.speakers {
+		if owner, ok := s.Speaker.(BalancerOwner); ok {
+			owner.NotifyOwnership(notify)
+		}
+	}
+}
+
+// ResyncEIPSpeaker sets the balancers of the eips of the protocol again, the
+// balancers of the speaker not used by any eip are deleted.
+func (m *Manager) ResyncEIPSpeaker(ctx context.Context, protocol string) error {
+	s, exist := m.speakers[protocol]
+	if !exist {
+		return nil
+	}
+
 	eips := &v1alpha2.EipList{}
 	if err := m.Client.List(ctx, eips, &client.ListOptions{}); err != nil {
 		return err
 	}
 
+	used := map[string]bool{}
 	for _, e := range eips.Items {
-		if e.Spec.Protocol != constant.OpenELBProtocolLayer2 {
+		if !util.ContainsString(e.GetSpeakerNames(), protocol) || !e.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		for ip := range e.Status.Used {
+			used[ip] = true
+		}
+		if err := m.setBalancer(ctx, []string{protocol}, e.Status.Used); err != nil {
+			klog.Warningf("resync speaker error: %s", err.Error())
+		}
+	}
+
+	lister, ok := s.Speaker.(BalancerLister)
+	if !ok {
+		return nil
+	}
+	for _, ip := range lister.ListBalancers() {
+		if used[ip] {
 			continue
 		}
 
-		if err := m.setBalancer(ctx, constant.OpenELBProtocolLayer2, e.Status.Used); err != nil {
+		klog.Infof("resync speaker %s delete orphaned balancer %s", protocol, ip)
+		if err := s.DelBalancer(ip); err != nil {
 			klog.Warningf("resync speaker error: %s", err.Error())
 		}
 	}
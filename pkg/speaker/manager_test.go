@@ -0,0 +1,258 @@
+package speaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/util/iprange"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestUpdateNodeStatus(t *testing.T) {
+	os.Setenv(constant.EnvNodeName, "node1")
+	defer os.Unsetenv(constant.EnvNodeName)
+
+	eip := &v1alpha2.Eip{
+		ObjectMeta: metav1.ObjectMeta{Name: "eip"},
+		Spec: v1alpha2.EipSpec{
+			Address:   "127.0.0.10-127.0.0.20",
+			Protocol:  constant.OpenELBProtocolLayer2,
+			Interface: "lo",
+		},
+		Status: v1alpha2.EipStatus{
+			NodesStatus: map[string]v1alpha2.NodeEipStatus{"node2": {Interface: "eth0", Ready: true}},
+		},
+	}
+	scheme := runtime.NewScheme()
+	_ = v1alpha2.AddToScheme(scheme)
+	m := &Manager{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(eip).WithStatusSubresource(eip).Build()}
+	ctx := context.Background()
+
+	c := Config{Name: eip.Name, Iface: eip.Spec.Interface}
+	m.updateNodeStatus(ctx, eip, m.nodeStatus(eip, c, errors.New("not in the same network segment")))
+	latest := &v1alpha2.Eip{}
+	assert.NoError(t, m.Get(ctx, types.NamespacedName{Name: eip.Name}, latest))
+	assert.Equal(t, map[string]v1alpha2.NodeEipStatus{
+		"node1": {Interface: "lo", Error: "not in the same network segment"},
+		"node2": {Interface: "eth0", Ready: true},
+	}, latest.Status.NodesStatus)
+
+	m.updateNodeStatus(ctx, eip, m.nodeStatus(eip, c, nil))
+	assert.NoError(t, m.Get(ctx, types.NamespacedName{Name: eip.Name}, latest))
+	assert.Equal(t, v1alpha2.NodeEipStatus{Interface: "lo", Ready: true}, latest.Status.NodesStatus["node1"])
+
+	// the bgp eips don't need an interface
+	eip.Spec.Protocol = constant.OpenELBProtocolBGP
+	assert.Equal(t, v1alpha2.NodeEipStatus{Ready: true}, m.nodeStatus(eip, c, nil))
+}
+
+type fakeSpeaker struct {
+	balancers  map[string][]string
+	configured bool
+	err        error
+	// the range configured, the balancers out of it are refused if set
+	ipRange iprange.Range
+	// the node labels of the configurations
+	labels []map[string]string
+}
+
+func (f *fakeSpeaker) SetBalancer(ip string, nexthops []corev1.Node) error {
+	if f.ipRange != nil && !f.ipRange.Contains(net.ParseIP(ip)) {
+		return fmt.Errorf("the announcers of the speakers do not contain the %s", ip)
+	}
+	names := []string{}
+	for _, node := range nexthops {
+		names = append(names, node.Name)
+	}
+	f.balancers[ip] = names
+	return nil
+}
+
+func (f *fakeSpeaker) DelBalancer(ip string) error {
+	delete(f.balancers, ip)
+	return nil
+}
+
+func (f *fakeSpeaker) Start(stopCh <-chan struct{}) error {
+	return nil
+}
+
+func (f *fakeSpeaker) ConfigureWithEIP(config Config, deleted bool) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.configured = !deleted
+	f.labels = append(f.labels, config.NodeLabels)
+	if f.ipRange != nil && !deleted {
+		f.ipRange = config.IPRange
+	}
+	return nil
+}
+
+func TestHandleMixedProtocolEIP(t *testing.T) {
+	os.Setenv(constant.EnvNodeName, "node1")
+	defer os.Unsetenv(constant.EnvNodeName)
+
+	eip := &v1alpha2.Eip{
+		ObjectMeta: metav1.ObjectMeta{Name: "eip"},
+		Spec: v1alpha2.EipSpec{
+			Address:   "127.0.0.10-127.0.0.20",
+			Protocols: []string{constant.OpenELBProtocolLayer2, constant.OpenELBProtocolBGP, constant.OpenELBProtocolVip},
+			Interface: "lo",
+		},
+		Status: v1alpha2.EipStatus{Used: map[string]string{"127.0.0.10": "default/svc"}},
+	}
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "svc"}}
+	ep := &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "svc"}}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	scheme := runtime.NewScheme()
+	_ = v1alpha2.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	m := &Manager{
+		Client:        fake.NewClientBuilder().WithScheme(scheme).WithObjects(eip, svc, ep, node).WithStatusSubresource(eip).Build(),
+		EventRecorder: record.NewFakeRecorder(100),
+		speakers:      map[string]speakerWithCancelFunc{},
+		pools:         map[string]*v1alpha2.Eip{},
+	}
+	layer2 := &fakeSpeaker{balancers: map[string][]string{}}
+	bgp := &fakeSpeaker{balancers: map[string][]string{}}
+	m.speakers[constant.OpenELBProtocolLayer2] = speakerWithCancelFunc{Speaker: layer2}
+	m.speakers[constant.OpenELBProtocolBGP] = speakerWithCancelFunc{Speaker: bgp}
+	ctx := context.Background()
+
+	// the vip speaker isn't registered on the node, it's skipped
+	assert.NoError(t, m.HandleEIP(ctx, eip))
+	assert.True(t, layer2.configured)
+	assert.True(t, bgp.configured)
+	assert.Equal(t, map[string][]string{"127.0.0.10": {"node1"}}, layer2.balancers)
+	assert.Equal(t, map[string][]string{"127.0.0.10": {"node1"}}, bgp.balancers)
+
+	// a failed speaker doesn't withdraw the balancers of the others
+	delete(m.pools, eip.Name)
+	layer2.err = errors.New("invalid interface")
+	bgp.balancers = map[string][]string{}
+	assert.Error(t, m.HandleEIP(ctx, eip))
+	assert.Equal(t, map[string][]string{"127.0.0.10": {"node1"}}, bgp.balancers)
+	layer2.err = nil
+	assert.NoError(t, m.HandleEIP(ctx, eip))
+
+	updated := eip.DeepCopy()
+	updated.Status.Used = map[string]string{"127.0.0.11": "default/svc"}
+	assert.NoError(t, m.HandleEIP(ctx, updated))
+	assert.Equal(t, map[string][]string{"127.0.0.11": {"node1"}}, layer2.balancers)
+	assert.Equal(t, map[string][]string{"127.0.0.11": {"node1"}}, bgp.balancers)
+
+	now := metav1.Now()
+	updated.DeletionTimestamp = &now
+	assert.NoError(t, m.HandleEIP(ctx, updated))
+	assert.Empty(t, layer2.balancers)
+	assert.Empty(t, bgp.balancers)
+	assert.False(t, layer2.configured)
+	assert.False(t, bgp.configured)
+}
+
+func TestHandleResizedEIP(t *testing.T) {
+	os.Setenv(constant.EnvNodeName, "node1")
+	defer os.Unsetenv(constant.EnvNodeName)
+
+	eip := &v1alpha2.Eip{
+		ObjectMeta: metav1.ObjectMeta{Name: "eip"},
+		Spec: v1alpha2.EipSpec{
+			Address:   "127.0.0.10-127.0.0.11",
+			Protocol:  constant.OpenELBProtocolLayer2,
+			Interface: "lo",
+		},
+		Status: v1alpha2.EipStatus{Used: map[string]string{"127.0.0.10": "default/svc"}},
+	}
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "svc"}}
+	svc2 := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "svc2"}}
+	ep := &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "svc"}}
+	ep2 := &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "svc2"}}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	scheme := runtime.NewScheme()
+	_ = v1alpha2.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	m := &Manager{
+		Client:        fake.NewClientBuilder().WithScheme(scheme).WithObjects(eip, svc, svc2, ep, ep2, node).WithStatusSubresource(eip).Build(),
+		EventRecorder: record.NewFakeRecorder(100),
+		speakers:      map[string]speakerWithCancelFunc{},
+		pools:         map[string]*v1alpha2.Eip{},
+	}
+	initial, _ := iprange.ParseRange(eip.Spec.Address)
+	layer2 := &fakeSpeaker{balancers: map[string][]string{}, ipRange: initial}
+	m.speakers[constant.OpenELBProtocolLayer2] = speakerWithCancelFunc{Speaker: layer2}
+	ctx := context.Background()
+	assert.NoError(t, m.HandleEIP(ctx, eip))
+
+	// the grown range is registered again before the new ips are announced
+	grown := eip.DeepCopy()
+	grown.Spec.Address = "127.0.0.10-127.0.0.20"
+	assert.NoError(t, m.HandleEIP(ctx, grown))
+	assert.Equal(t, grown, m.pools[eip.Name])
+
+	allocated := grown.DeepCopy()
+	allocated.Status.Used["127.0.0.15"] = "default/svc2"
+	assert.NoError(t, m.HandleEIP(ctx, allocated))
+	assert.Equal(t, map[string][]string{"127.0.0.10": {"node1"}, "127.0.0.15": {"node1"}}, layer2.balancers)
+}
+
+func TestHandleLabelSelectedEIP(t *testing.T) {
+	os.Setenv(constant.EnvNodeName, "node1")
+	defer os.Unsetenv(constant.EnvNodeName)
+
+	key := "openelb.io/interface"
+	eip := &v1alpha2.Eip{
+		ObjectMeta: metav1.ObjectMeta{Name: "eip"},
+		Spec: v1alpha2.EipSpec{
+			Address:   "127.0.0.10-127.0.0.20",
+			Protocol:  constant.OpenELBProtocolLayer2,
+			Interface: "label:" + key,
+		},
+	}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{key: "lo"}}}
+	scheme := runtime.NewScheme()
+	_ = v1alpha2.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	m := &Manager{
+		Client:        fake.NewClientBuilder().WithScheme(scheme).WithObjects(eip, node).WithStatusSubresource(eip).Build(),
+		EventRecorder: record.NewFakeRecorder(100),
+		speakers:      map[string]speakerWithCancelFunc{},
+		pools:         map[string]*v1alpha2.Eip{},
+		ifaceLabels:   map[string]string{},
+	}
+	layer2 := &fakeSpeaker{balancers: map[string][]string{}}
+	m.speakers[constant.OpenELBProtocolLayer2] = speakerWithCancelFunc{Speaker: layer2}
+	ctx := context.Background()
+
+	assert.NoError(t, m.HandleEIP(ctx, eip))
+	assert.Equal(t, "lo", m.ifaceLabels[eip.Name])
+	assert.NoError(t, m.HandleEIP(ctx, eip))
+	assert.Len(t, layer2.labels, 1, "the speakers aren't configured again if the label isn't changed")
+
+	// the interface selected by the old label is unconfigured
+	node.Labels[key] = "eth0"
+	assert.NoError(t, m.Update(ctx, node))
+	assert.NoError(t, m.HandleEIP(ctx, eip))
+	assert.Len(t, layer2.labels, 3)
+	assert.Equal(t, "lo", layer2.labels[1][key])
+	assert.Equal(t, "eth0", layer2.labels[2][key])
+	assert.True(t, layer2.configured)
+	assert.Equal(t, "eth0", m.ifaceLabels[eip.Name])
+
+	// the failure of getting the node isn't reported as a missing label
+	assert.NoError(t, m.Delete(ctx, node))
+	err := m.HandleEIP(ctx, eip)
+	assert.ErrorContains(t, err, "get node node1 failed")
+}
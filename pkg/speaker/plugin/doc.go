@@ -0,0 +1,7 @@
+// Package plugin implements a speaker calling an out-of-tree program over
+// grpc, e.g. a cloud route-table programmer, FRR or an SDN controller. The
+// program serves the Speaker service of api/speaker.proto and is selected by
+// the protocol of the eips it's registered with.
+package plugin
+
+//go:generate protoc -I api --go_out=api --go_opt=paths=source_relative --go-grpc_out=api --go-grpc_opt=paths=source_relative speaker.proto
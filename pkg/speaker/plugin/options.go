@@ -0,0 +1,49 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+type Options struct {
+	// the grpc endpoints of the plugin speakers by their protocols
+	Plugins map[string]string
+	Timeout time.Duration
+}
+
+func NewOptions() *Options {
+	return &Options{
+		Plugins: map[string]string{},
+		Timeout: 10 * time.Second,
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringToStringVar(&o.Plugins, "speaker-plugin", o.Plugins, "specify the grpc endpoint of an external speaker by its protocol, e.g. cloud=unix:///var/run/openelb/cloud.sock, the eips select it by the protocol, can be repeated")
+	fs.DurationVar(&o.Timeout, "speaker-plugin-timeout", o.Timeout, "specify the timeout of the calls to the external speakers")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+	for protocol, endpoint := range o.Plugins {
+		switch protocol {
+		case constant.OpenELBProtocolBGP, constant.OpenELBProtocolLayer2, constant.OpenELBProtocolVip, constant.OpenELBProtocolDummy:
+			errs = append(errs, fmt.Errorf("speaker plugin %s conflicts with the in-tree speaker", protocol))
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Label(protocol) {
+			errs = append(errs, fmt.Errorf("invalid speaker plugin %s: %s", protocol, msg))
+		}
+		if endpoint == "" {
+			errs = append(errs, fmt.Errorf("speaker plugin %s has no endpoint", protocol))
+		}
+	}
+	if o.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("speaker plugin timeout should be positive"))
+	}
+	return errs
+}
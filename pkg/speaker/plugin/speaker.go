@@ -0,0 +1,202 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openelb/openelb/api/v1alpha2"
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/speaker"
+	"github.com/openelb/openelb/pkg/speaker/plugin/api"
+	"github.com/openelb/openelb/pkg/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ speaker.Speaker = &pluginSpeaker{}
+var _ speaker.BalancerLister = &pluginSpeaker{}
+
+// NewSpeaker returns the speaker of the protocol calling the plugin at the
+// endpoint, the endpoint is dialed lazily so the plugin may start later.
+func NewSpeaker(protocol, endpoint string, timeout time.Duration, reloadChan chan event.GenericEvent) (speaker.Speaker, error) {
+	conn, err := grpc.Dial(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+
+	return newSpeaker(protocol, conn, timeout, reloadChan), nil
+}
+
+func newSpeaker(protocol string, conn *grpc.ClientConn, timeout time.Duration, reloadChan chan event.GenericEvent) *pluginSpeaker {
+	return &pluginSpeaker{
+		protocol:   protocol,
+		conn:       conn,
+		client:     api.NewSpeakerClient(conn),
+		timeout:    timeout,
+		reloadChan: reloadChan,
+		eips:       map[string]*api.ConfigureWithEIPRequest{},
+	}
+}
+
+type pluginSpeaker struct {
+	protocol   string
+	conn       *grpc.ClientConn
+	client     api.SpeakerClient
+	timeout    time.Duration
+	reloadChan chan event.GenericEvent
+
+	// the eips configured, replayed after the plugin is restarted
+	lock sync.Mutex
+	eips map[string]*api.ConfigureWithEIPRequest
+}
+
+func (p *pluginSpeaker) SetBalancer(ip string, nexthops []corev1.Node) error {
+	request := &api.SetBalancerRequest{Ip: ip}
+	for _, node := range nexthops {
+		request.Nexthops = append(request.Nexthops, toNode(node))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	_, err := p.client.SetBalancer(ctx, request)
+	return p.wrap(err)
+}
+
+func (p *pluginSpeaker) DelBalancer(ip string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	_, err := p.client.DelBalancer(ctx, &api.DelBalancerRequest{Ip: ip})
+	return p.wrap(err)
+}
+
+func (p *pluginSpeaker) ConfigureWithEIP(config speaker.Config, deleted bool) error {
+	request := &api.ConfigureWithEIPRequest{
+		Name:       config.Name,
+		Interface:  config.Iface,
+		Node:       util.GetNodeName(),
+		NodeLabels: config.NodeLabels,
+		Deleted:    deleted,
+	}
+	if config.IPRange != nil {
+		request.Address = config.IPRange.String()
+	}
+
+	if err := p.configure(request); err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if deleted {
+		delete(p.eips, config.Name)
+	} else {
+		p.eips[config.Name] = request
+	}
+	return nil
+}
+
+func (p *pluginSpeaker) configure(request *api.ConfigureWithEIPRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	_, err := p.client.ConfigureWithEIP(ctx, request)
+	return p.wrap(err)
+}
+
+// ListBalancers returns the balancers of the plugin, none if the plugin
+// doesn't implement it so nothing is deleted by the resync.
+func (p *pluginSpeaker) ListBalancers() []string {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	resp, err := p.client.ListBalancers(ctx, &api.ListBalancersRequest{})
+	if err != nil {
+		if status.Code(err) != codes.Unimplemented {
+			klog.Warningf("list the balancers of speaker plugin %s: %s", p.protocol, err.Error())
+		}
+		return nil
+	}
+
+	ips := append([]string{}, resp.GetIps()...)
+	sort.Strings(ips)
+	return ips
+}
+
+// Start watches the connection to the plugin, the eips are configured again
+// and the balancers are resynced every time it's (re)connected.
+func (p *pluginSpeaker) Start(stopCh <-chan struct{}) error {
+	defer p.conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
+	p.conn.Connect()
+	state := p.conn.GetState()
+	for {
+		if state == connectivity.Ready {
+			p.resync(stopCh)
+		}
+		if !p.conn.WaitForStateChange(ctx, state) {
+			return nil
+		}
+		state = p.conn.GetState()
+		if state == connectivity.Idle {
+			p.conn.Connect()
+		}
+	}
+}
+
+// resync replays the eips configured and asks the speaker manager to set the
+// balancers of the protocol again.
+func (p *pluginSpeaker) resync(stopCh <-chan struct{}) {
+	p.lock.Lock()
+	requests := make([]*api.ConfigureWithEIPRequest, 0, len(p.eips))
+	for _, request := range p.eips {
+		requests = append(requests, proto.Clone(request).(*api.ConfigureWithEIPRequest))
+	}
+	p.lock.Unlock()
+
+	for _, request := range requests {
+		if err := p.configure(request); err != nil {
+			klog.Warningf("resync eip %s: %s", request.Name, err.Error())
+		}
+	}
+
+	if p.reloadChan == nil {
+		return
+	}
+	evt := v1alpha2.Eip{}
+	evt.Name = p.protocol
+	evt.Namespace = constant.PluginReloadEIPNamespace
+	select {
+	case p.reloadChan <- event.GenericEvent{Object: &evt}:
+	case <-stopCh:
+	}
+}
+
+func (p *pluginSpeaker) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("speaker plugin %s: %s", p.protocol, status.Convert(err).Message())
+}
+
+func toNode(node corev1.Node) *api.Node {
+	n := &api.Node{Name: node.Name, Labels: node.Labels}
+	for _, addr := range node.Status.Addresses {
+		n.Addresses = append(n.Addresses, &api.NodeAddress{Type: string(addr.Type), Address: addr.Address})
+	}
+	return n
+}
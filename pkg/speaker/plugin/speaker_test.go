@@ -0,0 +1,156 @@
+package plugin
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/speaker"
+	"github.com/openelb/openelb/pkg/speaker/plugin/api"
+	"github.com/openelb/openelb/pkg/util/iprange"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+type fakePlugin struct {
+	api.UnimplementedSpeakerServer
+
+	lock      sync.Mutex
+	balancers map[string][]*api.Node
+	eips      map[string]*api.ConfigureWithEIPRequest
+}
+
+func (f *fakePlugin) SetBalancer(ctx context.Context, in *api.SetBalancerRequest) (*api.SetBalancerResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.balancers[in.Ip] = in.Nexthops
+	return &api.SetBalancerResponse{}, nil
+}
+
+func (f *fakePlugin) DelBalancer(ctx context.Context, in *api.DelBalancerRequest) (*api.DelBalancerResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.balancers, in.Ip)
+	return &api.DelBalancerResponse{}, nil
+}
+
+func (f *fakePlugin) ConfigureWithEIP(ctx context.Context, in *api.ConfigureWithEIPRequest) (*api.ConfigureWithEIPResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if in.Deleted {
+		delete(f.eips, in.Name)
+	} else {
+		f.eips[in.Name] = in
+	}
+	return &api.ConfigureWithEIPResponse{}, nil
+}
+
+func (f *fakePlugin) configured() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	names := []string{}
+	for name := range f.eips {
+		names = append(names, name)
+	}
+	return names
+}
+
+func serve(lis *bufconn.Listener, plugin *fakePlugin) *grpc.Server {
+	server := grpc.NewServer()
+	api.RegisterSpeakerServer(server, plugin)
+	go server.Serve(lis)
+	return server
+}
+
+// listener is the listener of the fake plugin, replaced when it's restarted.
+type listener struct {
+	lock sync.Mutex
+	lis  *bufconn.Listener
+}
+
+func (l *listener) restart() *bufconn.Listener {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.lis = bufconn.Listen(1 << 20)
+	return l.lis
+}
+
+func (l *listener) dial(ctx context.Context, _ string) (net.Conn, error) {
+	l.lock.Lock()
+	lis := l.lis
+	l.lock.Unlock()
+	return lis.DialContext(ctx)
+}
+
+func TestPluginSpeaker(t *testing.T) {
+	l := &listener{}
+	lis := l.restart()
+	plugin := &fakePlugin{balancers: map[string][]*api.Node{}, eips: map[string]*api.ConfigureWithEIPRequest{}}
+	server := serve(lis, plugin)
+
+	conn, err := grpc.Dial("passthrough:///bufnet",
+		grpc.WithContextDialer(l.dial),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	reloadChan := make(chan event.GenericEvent, 1)
+	s := newSpeaker("cloud", conn, time.Second, reloadChan)
+
+	r, err := iprange.ParseRange("192.168.0.1-192.168.0.10")
+	assert.NoError(t, err)
+	assert.NoError(t, s.ConfigureWithEIP(speaker.Config{Name: "eip", IPRange: r, Iface: "eth0"}, false))
+	assert.Equal(t, "192.168.0.1-192.168.0.10", plugin.eips["eip"].Address)
+	assert.Equal(t, "eth0", plugin.eips["eip"].Interface)
+
+	node := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"zone": "a"}},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+		}},
+	}
+	assert.NoError(t, s.SetBalancer("192.168.0.1", []corev1.Node{node}))
+	assert.Len(t, plugin.balancers["192.168.0.1"], 1)
+	assert.Equal(t, "node1", plugin.balancers["192.168.0.1"][0].Name)
+	assert.Equal(t, map[string]string{"zone": "a"}, plugin.balancers["192.168.0.1"][0].Labels)
+	assert.Equal(t, "10.0.0.1", plugin.balancers["192.168.0.1"][0].Addresses[0].Address)
+
+	assert.NoError(t, s.DelBalancer("192.168.0.1"))
+	assert.Empty(t, plugin.balancers)
+
+	// the plugin doesn't implement ListBalancers
+	assert.Nil(t, s.ListBalancers())
+
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, s.Start(stopCh))
+	}()
+
+	evt := <-reloadChan
+	assert.Equal(t, "cloud", evt.Object.GetName())
+	assert.Equal(t, constant.PluginReloadEIPNamespace, evt.Object.GetNamespace())
+
+	// the eips are configured again after the plugin is restarted
+	server.Stop()
+	restarted := &fakePlugin{balancers: map[string][]*api.Node{}, eips: map[string]*api.ConfigureWithEIPRequest{}}
+	server = serve(l.restart(), restarted)
+	defer server.Stop()
+
+	select {
+	case <-reloadChan:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no reload after the plugin is restarted")
+	}
+	assert.Equal(t, []string{"eip"}, restarted.configured())
+
+	close(stopCh)
+	<-done
+}
@@ -0,0 +1,285 @@
+package speaker
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openelb/openelb/pkg/nettool"
+	"github.com/openelb/openelb/pkg/nettool/iptables"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const mangleTable = "mangle"
+
+// SourceRangesReconciler enforces the loadBalancerSourceRanges of the services
+// on the ips owned by the node, the traffic from the other sources is dropped
+// in mangle PREROUTING before it's forwarded by kube-proxy. The ownership may
+// move without any change of the services, the speakers notify the changes
+// they observe on Changes and the rules are resynced periodically for the
+// others, e.g. keepalived.
+type SourceRangesReconciler struct {
+	client.Client
+	record.EventRecorder
+	Iptables     iptables.IptablesIface
+	Ip6tables    iptables.IptablesIface
+	Owner        func(ip string) bool
+	ResyncPeriod time.Duration
+	Changes      chan event.GenericEvent
+
+	lock sync.Mutex
+	// the rules in the chain of every family, absent if the chain isn't created
+	applied map[bool][][]string
+	// the chain jumped to from PREROUTING of every family
+	chains map[bool]string
+	// whether the chains left by the previous speaker are deleted
+	cleaned bool
+	// the ips of the services whose source ranges are ignored, to record the
+	// event once
+	ignored map[string]bool
+}
+
+// Reconcile syncs the chains with all the services, the request is ignored
+// since the chains are shared by all of them.
+func (r *SourceRangesReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	services := &corev1.ServiceList{}
+	if err := r.List(ctx, services); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	desired := r.desiredRules(services.Items)
+
+	for _, ipv6 := range []bool{false, true} {
+		ipt := r.iptables(ipv6)
+		if ipt == nil {
+			if len(desired[ipv6]) != 0 {
+				klog.Warningf("no ip6tables to enforce the source ranges of %d rules", len(desired[ipv6]))
+			}
+			continue
+		}
+
+		var err error
+		if len(desired[ipv6]) != 0 {
+			err = r.sync(ipv6, desired[ipv6])
+		} else if _, exist := r.applied[ipv6]; exist || !r.cleaned {
+			err = r.cleanup(ipv6)
+		}
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	r.cleaned = true
+	return ctrl.Result{RequeueAfter: r.ResyncPeriod}, nil
+}
+
+func (r *SourceRangesReconciler) iptables(ipv6 bool) iptables.IptablesIface {
+	if ipv6 {
+		return r.Ip6tables
+	}
+	return r.Iptables
+}
+
+// desiredRules returns the rules of the chain of every family, the services
+// are sorted so the rules don't change unless the services do. The source
+// ranges of another family don't apply to an ip, so the ip isn't restricted
+// if none of its family is valid and a warning is recorded on the service.
+// The caller must hold the lock.
+func (r *SourceRangesReconciler) desiredRules(services []corev1.Service) map[bool][][]string {
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Namespace+"/"+services[i].Name < services[j].Namespace+"/"+services[j].Name
+	})
+
+	rules := map[bool][][]string{}
+	ignored := map[string]bool{}
+	for _, svc := range services {
+		if !IsOpenELBService(&svc) || !svc.DeletionTimestamp.IsZero() || len(svc.Spec.LoadBalancerSourceRanges) == 0 {
+			continue
+		}
+
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			ip := net.ParseIP(ingress.IP)
+			if ip == nil || !r.Owner(ingress.IP) {
+				continue
+			}
+			ipv6 := ip.To4() == nil
+
+			ranges := []string{}
+			for _, source := range svc.Spec.LoadBalancerSourceRanges {
+				_, cidr, err := net.ParseCIDR(source)
+				if err != nil {
+					klog.Warningf("service %s/%s has an invalid source range %s", svc.Namespace, svc.Name, source)
+					continue
+				}
+				if (cidr.IP.To4() == nil) == ipv6 {
+					ranges = append(ranges, cidr.String())
+				}
+			}
+			if len(ranges) == 0 {
+				key := svc.Namespace + "/" + svc.Name + "/" + ip.String()
+				ignored[key] = true
+				if !r.ignored[key] {
+					klog.Warningf("service %s/%s has no valid source range for %s, it isn't restricted", svc.Namespace, svc.Name, ip)
+					r.Eventf(&svc, corev1.EventTypeWarning, "SourceRangesIgnored",
+						"no valid loadBalancerSourceRanges for %s, the traffic from all the sources is allowed", ip)
+				}
+				continue
+			}
+
+			for _, port := range svc.Spec.Ports {
+				rules[ipv6] = append(rules[ipv6], nettool.GenerateSourceRangesRules(ip.String(), string(port.Protocol), port.Port, ranges)...)
+			}
+		}
+	}
+	r.ignored = ignored
+	return rules
+}
+
+// sync replaces the rules of the family if they're changed or missing, they
+// are filled in another chain before PREROUTING jumps to it so the traffic is
+// never let through by a partial chain. The caller must hold the lock.
+func (r *SourceRangesReconciler) sync(ipv6 bool, rules [][]string) error {
+	ipt := r.iptables(ipv6)
+	applied, exist := r.applied[ipv6]
+	if exist && reflect.DeepEqual(applied, rules) {
+		complete, err := r.complete(ipt, r.chains[ipv6], rules)
+		if err != nil || complete {
+			return err
+		}
+	}
+
+	delete(r.applied, ipv6)
+	chain, err := nettool.SwapJumpChain(ipt, mangleTable, "PREROUTING", nettool.SourceRangesChains, rules)
+	if err != nil {
+		return err
+	}
+	if r.applied == nil {
+		r.applied = map[bool][][]string{}
+		r.chains = map[bool]string{}
+	}
+	r.applied[ipv6] = rules
+	r.chains[ipv6] = chain
+	return nil
+}
+
+// complete returns true if PREROUTING jumps to the chain and none of its
+// rules is deleted by another program.
+func (r *SourceRangesReconciler) complete(ipt iptables.IptablesIface, chain string, rules [][]string) (bool, error) {
+	ok, err := ipt.Exists(mangleTable, "PREROUTING", "-j", chain)
+	if err != nil || !ok {
+		return false, err
+	}
+	for _, rule := range rules {
+		ok, err := ipt.Exists(mangleTable, chain, rule...)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// cleanup deletes the chains of the family, the caller must hold the lock.
+func (r *SourceRangesReconciler) cleanup(ipv6 bool) error {
+	for _, chain := range nettool.SourceRangesChains {
+		if err := nettool.DeleteJumpChain(r.iptables(ipv6), mangleTable, "PREROUTING", chain); err != nil {
+			return err
+		}
+	}
+	delete(r.applied, ipv6)
+	delete(r.chains, ipv6)
+	return nil
+}
+
+// Start deletes the chains when the speaker is stopped, the ips are owned by
+// another node afterwards.
+func (r *SourceRangesReconciler) Start(ctx context.Context) error {
+	<-ctx.Done()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, ipv6 := range []bool{false, true} {
+		if r.iptables(ipv6) == nil {
+			continue
+		}
+		if err := r.cleanup(ipv6); err != nil {
+			klog.Errorf("failed to delete the chains %v: %v", nettool.SourceRangesChains, err)
+		}
+	}
+	return nil
+}
+
+func (r *SourceRangesReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	enqueue := handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "source-ranges"}}}
+	})
+	p := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			svc, ok := e.Object.(*corev1.Service)
+			return ok && IsOpenELBService(svc)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			old, ok := e.ObjectOld.(*corev1.Service)
+			if !ok {
+				return false
+			}
+			new, ok := e.ObjectNew.(*corev1.Service)
+			if !ok {
+				return false
+			}
+			return IsOpenELBService(old) || IsOpenELBService(new)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			svc, ok := e.Object.(*corev1.Service)
+			return ok && IsOpenELBService(svc)
+		},
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("SourceRangesController").
+		Watches(&corev1.Service{}, enqueue, builder.WithPredicates(p)).
+		WatchesRawSource(&source.Channel{Source: r.Changes}, enqueue).
+		Complete(r)
+}
+
+// notify triggers a reconcile, it's dropped if one is already pending since
+// all the services are reconciled at once.
+func (r *SourceRangesReconciler) notify() {
+	select {
+	case r.Changes <- event.GenericEvent{Object: &corev1.Service{}}:
+	default:
+	}
+}
+
+func SetupSourceRangesReconciler(ipt, ip6t iptables.IptablesIface, owner BalancerOwner, resyncPeriod time.Duration, mgr ctrl.Manager) error {
+	sourceRanges := &SourceRangesReconciler{
+		Client:        mgr.GetClient(),
+		EventRecorder: mgr.GetEventRecorderFor("source-ranges"),
+		Iptables:      ipt,
+		Ip6tables:     ip6t,
+		Owner:         owner.OwnBalancer,
+		ResyncPeriod:  resyncPeriod,
+		Changes:       make(chan event.GenericEvent, 1),
+	}
+	if err := sourceRanges.SetupWithManager(mgr); err != nil {
+		return err
+	}
+	owner.NotifyOwnership(sourceRanges.notify)
+
+	return mgr.Add(sourceRanges)
+}
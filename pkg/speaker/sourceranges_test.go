@@ -0,0 +1,202 @@
+package speaker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/nettool"
+	"github.com/openelb/openelb/pkg/nettool/iptables"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestSourceRangesReconciler(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "web",
+			Annotations: map[string]string{constant.OpenELBAnnotationKey: constant.OpenELBAnnotationValue},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:                     corev1.ServiceTypeLoadBalancer,
+			Ports:                    []corev1.ServicePort{{Port: 80, Protocol: corev1.ProtocolTCP}},
+			LoadBalancerSourceRanges: []string{"10.0.0.0/8", "192.168.1.1/32", "fd00::/64"},
+		},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+			Ingress: []corev1.LoadBalancerIngress{{IP: "172.22.0.10"}, {IP: "fd00:1::10"}},
+		}},
+	}
+	open := svc.DeepCopy()
+	open.Name = "open"
+	open.Spec.LoadBalancerSourceRanges = nil
+	open.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "172.22.0.11"}}
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(svc, open).Build()
+	ipt := iptables.NewFakeIPTables()
+	ip6t := iptables.NewFakeIPTables()
+	owned := map[string]bool{"172.22.0.10": true, "172.22.0.11": true, "fd00:1::10": true}
+	recorder := record.NewFakeRecorder(10)
+	r := &SourceRangesReconciler{
+		Client:        c,
+		EventRecorder: recorder,
+		Iptables:      ipt,
+		Ip6tables:     ip6t,
+		Owner:         func(ip string) bool { return owned[ip] },
+	}
+
+	ctx := context.Background()
+	reconcile := func() {
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "source-ranges"}})
+		assert.NoError(t, err)
+	}
+	// the rules of the chain jumped to from PREROUTING
+	rules := func(fake *iptables.FakeIPTables) [][]string {
+		result := [][]string{}
+		jumps := fake.Data[mangleTable]["PREROUTING"]
+		if len(jumps) != 1 {
+			return result
+		}
+		for _, rule := range fake.Data[mangleTable][jumps[0].Rule[1]] {
+			result = append(result, rule.Rule)
+		}
+		return result
+	}
+	chains := func(fake *iptables.FakeIPTables) []string {
+		result := []string{}
+		for _, chain := range nettool.SourceRangesChains {
+			if _, exist := fake.Data[mangleTable][chain]; exist {
+				result = append(result, chain)
+			}
+		}
+		return result
+	}
+
+	reconcile()
+	assert.Equal(t, nettool.GenerateSourceRangesRules("172.22.0.10", "tcp", 80, []string{"10.0.0.0/8", "192.168.1.1/32"}), rules(ipt))
+	assert.Equal(t, nettool.GenerateSourceRangesRules("fd00:1::10", "tcp", 80, []string{"fd00::/64"}), rules(ip6t))
+	exist, _ := ipt.Exists(mangleTable, "PREROUTING", "-j", nettool.SourceRangesChains[0])
+	assert.True(t, exist)
+	assert.Equal(t, []string{nettool.SourceRangesChains[0]}, chains(ipt))
+
+	// the rules flushed by another program are restored in the other chain
+	assert.NoError(t, ipt.ClearChain(mangleTable, nettool.SourceRangesChains[0]))
+	reconcile()
+	assert.Len(t, rules(ipt), 3)
+	assert.Equal(t, []string{nettool.SourceRangesChains[1]}, chains(ipt))
+
+	svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{Port: 53, Protocol: corev1.ProtocolUDP})
+	svc.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0/8"}
+	assert.NoError(t, c.Update(ctx, svc))
+	reconcile()
+	assert.Equal(t, append(
+		nettool.GenerateSourceRangesRules("172.22.0.10", "tcp", 80, []string{"10.0.0.0/8"}),
+		nettool.GenerateSourceRangesRules("172.22.0.10", "udp", 53, []string{"10.0.0.0/8"})...), rules(ipt))
+	// no ipv6 range, the ipv6 ip isn't restricted and the service is warned once
+	assert.Empty(t, chains(ip6t))
+	assert.Equal(t, "Warning SourceRangesIgnored no valid loadBalancerSourceRanges for fd00:1::10, the traffic from all the sources is allowed", <-recorder.Events)
+	reconcile()
+	assert.Empty(t, recorder.Events)
+
+	// the rules are removed when the ownership moves
+	owned["172.22.0.10"] = false
+	reconcile()
+	assert.Empty(t, chains(ipt))
+	assert.Empty(t, ipt.Data[mangleTable]["PREROUTING"])
+
+	owned["172.22.0.10"] = true
+	reconcile()
+	assert.Len(t, rules(ipt), 4)
+
+	assert.NoError(t, c.Delete(ctx, svc))
+	reconcile()
+	assert.Empty(t, chains(ipt))
+	assert.Empty(t, chains(ip6t))
+
+	// and when the speaker is stopped
+	assert.NoError(t, c.Create(ctx, &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: svc.Namespace, Name: svc.Name, Annotations: svc.Annotations},
+		Spec:       svc.Spec,
+		Status:     svc.Status,
+	}))
+	reconcile()
+	assert.Len(t, rules(ipt), 4)
+	stopped, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.NoError(t, r.Start(stopped))
+	assert.Empty(t, chains(ipt))
+	assert.Empty(t, chains(ip6t))
+}
+
+func TestSourceRangesInvalid(t *testing.T) {
+	svc := corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "web",
+			Annotations: map[string]string{constant.OpenELBAnnotationKey: constant.OpenELBAnnotationValue},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:                     corev1.ServiceTypeLoadBalancer,
+			Ports:                    []corev1.ServicePort{{Port: 80, Protocol: corev1.ProtocolTCP}},
+			LoadBalancerSourceRanges: []string{"10.0.0.0", "10.0.0.0/33"},
+		},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+			Ingress: []corev1.LoadBalancerIngress{{IP: "172.22.0.10"}},
+		}},
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := &SourceRangesReconciler{
+		EventRecorder: recorder,
+		Owner:         func(ip string) bool { return true },
+	}
+
+	// all the ranges are invalid, the ip isn't restricted
+	assert.Empty(t, r.desiredRules([]corev1.Service{svc}))
+	assert.Len(t, recorder.Events, 1)
+	assert.Empty(t, r.desiredRules([]corev1.Service{svc}))
+	assert.Len(t, recorder.Events, 1)
+
+	// the service is warned again once it's fixed and broken again
+	svc.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0/8"}
+	assert.Len(t, r.desiredRules([]corev1.Service{svc})[false], 2)
+	svc.Spec.LoadBalancerSourceRanges = []string{"10.0.0.0"}
+	assert.Empty(t, r.desiredRules([]corev1.Service{svc}))
+	assert.Len(t, recorder.Events, 2)
+}
+
+func TestSourceRangesNotify(t *testing.T) {
+	r := &SourceRangesReconciler{Changes: make(chan event.GenericEvent, 1)}
+	// the notifications are coalesced without blocking the speakers
+	r.notify()
+	r.notify()
+	assert.Len(t, r.Changes, 1)
+}
+
+func TestOwnBalancer(t *testing.T) {
+	m := &Manager{speakers: map[string]speakerWithCancelFunc{
+		constant.OpenELBProtocolBGP:    {Speaker: &fakeSpeaker{}},
+		constant.OpenELBProtocolLayer2: {Speaker: &fakeOwner{owned: map[string]bool{"172.22.0.10": true}}},
+	}}
+	assert.True(t, m.OwnBalancer("172.22.0.10"))
+	assert.False(t, m.OwnBalancer("172.22.0.11"))
+}
+
+type fakeOwner struct {
+	fakeSpeaker
+	owned map[string]bool
+}
+
+func (f *fakeOwner) OwnBalancer(ip string) bool {
+	return f.owned[ip]
+}
+
+func (f *fakeOwner) NotifyOwnership(notify func()) {}
@@ -5,18 +5,79 @@ import (
 	"net"
 	"strings"
 
+	"github.com/openelb/openelb/pkg/util"
 	"github.com/openelb/openelb/pkg/util/iprange"
 	"github.com/vishvananda/netlink"
 )
 
-func ParseInterface(ifaceName string) (iface *net.Interface, err error) {
+const (
+	// can_reach:<ip> selects the interface of the route to the ip
+	InterfaceSelectorCanReach = "can_reach"
+	// subnet:<cidr> selects the interface holding an address in the subnet
+	InterfaceSelectorSubnet = "subnet"
+	// label:<key> selects the interface named by the label of the node
+	InterfaceSelectorLabel = "label"
+	// default_route[:ipv4|ipv6] selects the interface of the default route
+	InterfaceSelectorDefaultRoute = "default_route"
+)
+
+// InterfaceError is the failure of resolving or validating the interface of
+// an eip on the node.
+type InterfaceError struct {
+	Node     string
+	Selector string
+	Err      error
+}
+
+func (e *InterfaceError) Error() string {
+	return fmt.Sprintf("node %s: interface %q: %v", e.Node, e.Selector, e.Err)
+}
+
+func (e *InterfaceError) Unwrap() error {
+	return e.Err
+}
+
+func interfaceError(selector string, err error) error {
+	return &InterfaceError{Node: util.GetNodeName(), Selector: selector, Err: err}
+}
+
+// ParseInterface resolves the interface of the selector on the node, it's
+// either a name or one of the selectors, the labels of the node are used by
+// the label selector.
+func ParseInterface(ifaceName string, nodeLabels map[string]string) (iface *net.Interface, err error) {
+	iface, err = parseInterface(ifaceName, nodeLabels)
+	if err != nil {
+		return nil, interfaceError(ifaceName, err)
+	}
+	return iface, nil
+}
+
+// interfaceLabel returns the key of the node label selecting the interface,
+// ok is false if the interface isn't selected by a label.
+func interfaceLabel(ifaceName string) (key string, ok bool) {
 	strs := strings.SplitN(ifaceName, ":", 2)
+	if len(strs) != 2 || strs[0] != InterfaceSelectorLabel {
+		return "", false
+	}
+	return strs[1], true
+}
+
+func parseInterface(ifaceName string, nodeLabels map[string]string) (*net.Interface, error) {
+	strs := strings.SplitN(ifaceName, ":", 2)
+	if strs[0] == InterfaceSelectorDefaultRoute {
+		family := ""
+		if len(strs) == 2 {
+			family = strs[1]
+		}
+		return defaultRouteInterface(family)
+	}
+
 	if len(strs) == 1 {
 		return net.InterfaceByName(ifaceName)
 	}
 
 	switch strs[0] {
-	case "can_reach":
+	case InterfaceSelectorCanReach:
 		ip := net.ParseIP(strs[1])
 		if ip == nil {
 			return nil, fmt.Errorf("invalid can_reach address %s", strs[1])
@@ -27,31 +88,103 @@ func ParseInterface(ifaceName string) (iface *net.Interface, err error) {
 			return nil, err
 		}
 
-		iface, err = net.InterfaceByIndex(routers[0].LinkIndex)
+		return nonLoopback(routers[0].LinkIndex)
+	case InterfaceSelectorSubnet:
+		_, subnet, err := net.ParseCIDR(strs[1])
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("invalid subnet %s", strs[1])
 		}
-
-		if iface.Name == "lo" {
-			return nil, fmt.Errorf("invalid interface lo")
+		return subnetInterface(subnet)
+	case InterfaceSelectorLabel:
+		name := nodeLabels[strs[1]]
+		if name == "" {
+			return nil, fmt.Errorf("node has no label %s", strs[1])
 		}
+		return net.InterfaceByName(name)
 	default:
-		return nil, fmt.Errorf("invalid interface string, now only support can_reach")
+		return nil, fmt.Errorf("invalid interface string, should be a name or %s:<ip>, %s:<cidr>, %s:<key>, %s[:ipv4|ipv6]",
+			InterfaceSelectorCanReach, InterfaceSelectorSubnet, InterfaceSelectorLabel, InterfaceSelectorDefaultRoute)
+	}
+}
+
+func nonLoopback(index int) (*net.Interface, error) {
+	iface, err := net.InterfaceByIndex(index)
+	if err != nil {
+		return nil, err
 	}
 
+	if iface.Name == "lo" {
+		return nil, fmt.Errorf("invalid interface lo")
+	}
 	return iface, nil
 }
 
+// subnetInterface returns the first interface holding an address in the
+// subnet.
+func subnetInterface(subnet *net.IPNet) (*net.Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ifaces {
+		addrs, err := ifaces[i].Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ip, _, err := net.ParseCIDR(addr.String())
+			if err == nil && subnet.Contains(ip) {
+				return &ifaces[i], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no interface has an address in %s", subnet.String())
+}
+
+// defaultRouteInterface returns the interface of the default route of the
+// family, the ipv4 one is preferred if the family is empty.
+func defaultRouteInterface(family string) (*net.Interface, error) {
+	families := []int{}
+	switch family {
+	case "":
+		families = append(families, netlink.FAMILY_V4, netlink.FAMILY_V6)
+	case "ipv4":
+		families = append(families, netlink.FAMILY_V4)
+	case "ipv6":
+		families = append(families, netlink.FAMILY_V6)
+	default:
+		return nil, fmt.Errorf("invalid default route family %s, should be ipv4 or ipv6", family)
+	}
+
+	for _, f := range families {
+		routes, err := netlink.RouteList(nil, f)
+		if err != nil {
+			return nil, err
+		}
+		for _, route := range routes {
+			if route.LinkIndex > 0 && (route.Dst == nil || route.Dst.IP.IsUnspecified()) {
+				return nonLoopback(route.LinkIndex)
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no default route found")
+}
+
+// ValidateInterface validates the eip is in the same network segment as an
+// address of the interface.
 func ValidateInterface(netif *net.Interface, r iprange.Range) error {
 	addrs, err := netif.Addrs()
 	if err != nil {
-		return err
+		return interfaceError(netif.Name, err)
 	}
 
 	for _, addr := range addrs {
 		ip, cidrnet, err := net.ParseCIDR(addr.String())
 		if err != nil {
-			return err
+			return interfaceError(netif.Name, err)
 		}
 
 		if ip.To4() != nil {
@@ -66,5 +199,5 @@ func ValidateInterface(netif *net.Interface, r iprange.Range) error {
 		}
 	}
 
-	return fmt.Errorf("%s's ip and the eip[%s] are not in the same network segment", netif.Name, r.String())
+	return interfaceError(netif.Name, fmt.Errorf("the ips of the interface and the eip[%s] are not in the same network segment", r.String()))
 }
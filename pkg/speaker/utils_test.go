@@ -0,0 +1,64 @@
+package speaker
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/util/iprange"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInterface(t *testing.T) {
+	os.Setenv(constant.EnvNodeName, "node1")
+	defer os.Unsetenv(constant.EnvNodeName)
+
+	labels := map[string]string{"openelb.kubesphere.io/interface": "lo"}
+	tests := []struct {
+		name    string
+		iface   string
+		want    string
+		wantErr bool
+	}{
+		{name: "name", iface: "lo", want: "lo"},
+		{name: "subnet", iface: "subnet:127.0.0.0/8", want: "lo"},
+		{name: "label", iface: "label:openelb.kubesphere.io/interface", want: "lo"},
+		{name: "invalid subnet", iface: "subnet:127.0.0.1", wantErr: true},
+		{name: "no interface in the subnet", iface: "subnet:203.0.113.0/24", wantErr: true},
+		{name: "missing label", iface: "label:missing", wantErr: true},
+		{name: "invalid default route family", iface: "default_route:ipv5", wantErr: true},
+		{name: "unknown selector", iface: "mac:00:00:00:00:00:00", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iface, err := ParseInterface(tt.iface, labels)
+			if tt.wantErr {
+				ifaceErr := &InterfaceError{}
+				assert.True(t, errors.As(err, &ifaceErr))
+				assert.Equal(t, "node1", ifaceErr.Node)
+				assert.Equal(t, tt.iface, ifaceErr.Selector)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, iface.Name)
+		})
+	}
+}
+
+func TestValidateInterface(t *testing.T) {
+	os.Setenv(constant.EnvNodeName, "node1")
+	defer os.Unsetenv(constant.EnvNodeName)
+
+	lo, err := ParseInterface("lo", nil)
+	assert.NoError(t, err)
+
+	r, err := iprange.ParseRange("127.0.0.10-127.0.0.20")
+	assert.NoError(t, err)
+	assert.NoError(t, ValidateInterface(lo, r))
+
+	r, err = iprange.ParseRange("192.0.2.10-192.0.2.20")
+	assert.NoError(t, err)
+	err = ValidateInterface(lo, r)
+	assert.EqualError(t, err, `node node1: interface "lo": the ips of the interface and the eip[192.0.2.10-192.0.2.20] are not in the same network segment`)
+}
@@ -5,7 +5,6 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"html/template"
 	"io"
 	"net"
 	"os"
@@ -13,7 +12,9 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/openelb/openelb/pkg/speaker"
@@ -33,11 +34,17 @@ const (
 )
 
 var _ speaker.Speaker = &keepAlived{}
+var _ speaker.BalancerOwner = &keepAlived{}
 
 type keepAlived struct {
+	lock           sync.Mutex
 	client         *kubernetes.Clientset
 	logPath        string
 	args           string
+	trackScripts   []string
+	trackInterval  time.Duration
+	defaults       nodeSettings
+	settings       nodeSettings
 	cmd            *exec.Cmd
 	keepalivedTmpl *template.Template
 	instances      map[string]*instances
@@ -52,20 +59,26 @@ type instances struct {
 	RouteID  uint32
 	Svcips   []string
 	Priority int
+	Preempt  bool
 	Enabled  bool
 }
 
-func NewKeepAlived(client *kubernetes.Clientset, logPath, args string) (*keepAlived, error) {
+func NewKeepAlived(client *kubernetes.Clientset, opt *VipOptions) (*keepAlived, error) {
 	tmpl, err := template.ParseFiles(keepalivedTmpl)
 	if err != nil {
 		return nil, err
 	}
 
+	defaults := defaultNodeSettings(opt.Preempt)
 	return &keepAlived{
 		client:         client,
 		keepalivedTmpl: tmpl,
-		logPath:        logPath,
-		args:           args,
+		logPath:        opt.LogPath,
+		args:           opt.KeepAlivedArgs,
+		trackScripts:   opt.TrackScripts,
+		trackInterval:  opt.TrackInterval,
+		defaults:       defaults,
+		settings:       defaults,
 		idAlloc:        idalloc.New(256),
 		configs:        make(map[string]*speaker.Config),
 		instances:      make(map[string]*instances),
@@ -74,6 +87,13 @@ func NewKeepAlived(client *kubernetes.Clientset, logPath, args string) (*keepAli
 }
 
 func (k *keepAlived) SetBalancer(vip string, nodes []corev1.Node) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if settings, ok := localNodeSettings(nodes, k.defaults); ok {
+		k.applySettings(settings)
+	}
+
 	iface := k.getInterfaces(vip)
 	if iface == "" {
 		return fmt.Errorf("no interface found for VIP %s", vip)
@@ -86,7 +106,7 @@ func (k *keepAlived) SetBalancer(vip string, nodes []corev1.Node) error {
 	}
 
 	// generate new record
-	bytes := k.getNodeSha256Bytes(nodes)
+	bytes := getNodeSha256Bytes(nodes)
 	instanceName := hex.EncodeToString(bytes[:]) + "-" + iface
 	klog.Infof("generate instanceName %s", instanceName)
 	instance, exist := k.instances[instanceName]
@@ -100,11 +120,12 @@ func (k *keepAlived) SetBalancer(vip string, nodes []corev1.Node) error {
 			Name:     instanceName,
 			Iface:    iface,
 			RouteID:  routeid,
-			Priority: 100,
+			Priority: k.settings.Priority,
+			Preempt:  k.settings.Preempt,
 		}
 		k.instances[instanceName] = instance
 	}
-	instance.Enabled = k.isNodeInList(nodes)
+	instance.Enabled = isNodeInList(nodes)
 	instance.Svcips = append(instance.Svcips, vip)
 	k.vips[vip] = instanceName
 
@@ -142,6 +163,35 @@ func (k *keepAlived) cleanRecord(vip, instanceName string) {
 	}
 }
 
+// OwnBalancer returns true if the vip is assigned to the node by keepalived,
+// i.e. the node is the master of its instance.
+func (k *keepAlived) OwnBalancer(vip string) bool {
+	k.lock.Lock()
+	_, exist := k.vips[vip]
+	k.lock.Unlock()
+
+	return exist && isLocalAddress(vip)
+}
+
+// NotifyOwnership is a no-op, keepalived moves the vips out of the speaker so
+// their ownership is only resynced periodically.
+func (k *keepAlived) NotifyOwnership(notify func()) {}
+
+// isLocalAddress returns true if ip is an address of an interface of the node.
+func isLocalAddress(ip string) bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		klog.Warningf("list the addresses of the node error: %s", err.Error())
+		return false
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(net.ParseIP(ip)) {
+			return true
+		}
+	}
+	return false
+}
+
 // getInterfaces returns the interface name for the given VIP
 func (k *keepAlived) getInterfaces(vip string) string {
 	for _, c := range k.configs {
@@ -153,7 +203,7 @@ func (k *keepAlived) getInterfaces(vip string) string {
 }
 
 // getNodeSha256Bytes returns the sha256 hash of the node names
-func (k *keepAlived) getNodeSha256Bytes(nodes []corev1.Node) [32]byte {
+func getNodeSha256Bytes(nodes []corev1.Node) [32]byte {
 	nodenames := []string{}
 	for _, node := range nodes {
 		nodenames = append(nodenames, node.Name)
@@ -168,7 +218,7 @@ func (k *keepAlived) getNodeSha256Bytes(nodes []corev1.Node) [32]byte {
 }
 
 // isNodeInList returns true if the node is in the nodes list
-func (k *keepAlived) isNodeInList(nodes []corev1.Node) bool {
+func isNodeInList(nodes []corev1.Node) bool {
 	for _, node := range nodes {
 		if node.Name == util.GetNodeName() {
 			return true
@@ -179,6 +229,9 @@ func (k *keepAlived) isNodeInList(nodes []corev1.Node) bool {
 }
 
 func (k *keepAlived) DelBalancer(vip string) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
 	instanceName, exist := k.vips[vip]
 	if !exist {
 		return nil
@@ -198,11 +251,16 @@ func (k *keepAlived) DelBalancer(vip string) error {
 }
 
 func (k *keepAlived) Start(stopCh <-chan struct{}) error {
-	if err := k.WriteCfg(); err != nil {
+	k.lock.Lock()
+	err := k.WriteCfg()
+	k.lock.Unlock()
+	if err != nil {
 		klog.Error(err)
 		return err
 	}
 
+	watchLocalNode(k.client, k.defaults, stopCh, k.updateSettings)
+
 	var logWriter io.WriteCloser
 	if k.logPath != "" {
 		logWriter = &lumberjack.Logger{
@@ -253,7 +311,7 @@ func (k *keepAlived) Start(stopCh <-chan struct{}) error {
 }
 
 func (k *keepAlived) ConfigureWithEIP(config speaker.Config, deleted bool) error {
-	netif, err := speaker.ParseInterface(config.Iface)
+	netif, err := speaker.ParseInterface(config.Iface, config.NodeLabels)
 	if err != nil || netif == nil {
 		return err
 	}
@@ -262,6 +320,9 @@ func (k *keepAlived) ConfigureWithEIP(config speaker.Config, deleted bool) error
 		return err
 	}
 
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
 	if deleted {
 		delete(k.configs, config.Name)
 	} else {
@@ -270,6 +331,40 @@ func (k *keepAlived) ConfigureWithEIP(config speaker.Config, deleted bool) error
 	return nil
 }
 
+// updateSettings reconfigures keepalived when the local node changes its settings.
+func (k *keepAlived) updateSettings(settings nodeSettings) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if !k.applySettings(settings) {
+		return
+	}
+
+	if err := k.WriteCfg(); err != nil {
+		klog.Error(err)
+		return
+	}
+	if err := k.Reload(); err != nil {
+		klog.Error(err)
+	}
+}
+
+// applySettings sets the priority and preemption of every instance and
+// reports whether they changed.
+func (k *keepAlived) applySettings(settings nodeSettings) bool {
+	if settings == k.settings {
+		return false
+	}
+
+	klog.Infof("vip priority %d, preempt %t", settings.Priority, settings.Preempt)
+	k.settings = settings
+	for _, instance := range k.instances {
+		instance.Priority = settings.Priority
+		instance.Preempt = settings.Preempt
+	}
+	return true
+}
+
 // Reload sends SIGHUP to keepalived to reload the configuration.
 func (k *keepAlived) Reload() error {
 	klog.Info("Waiting for keepalived to start")
@@ -310,9 +405,18 @@ func (k *keepAlived) WriteCfg() error {
 	}
 	defer w.Close()
 
+	interval := int(k.trackInterval.Seconds())
+	if interval < 1 {
+		interval = 1
+	}
+
 	if err = k.keepalivedTmpl.Execute(w, map[string]interface{}{
 		"name":      util.GetNodeName(),
 		"instances": k.instances,
+		"scripts":   k.trackScripts,
+		"interval":  interval,
+		"fall":      trackFall,
+		"rise":      trackRise,
 	}); err != nil {
 		return fmt.Errorf("unexpected error creating keepalived.cfg: %v", err)
 	}
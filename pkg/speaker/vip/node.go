@@ -0,0 +1,104 @@
+package vip
+
+import (
+	"strconv"
+
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultPriority = 100
+	// 255 is reserved for the router owning the addresses
+	maxPriority = 254
+)
+
+// nodeSettings are the VRRP settings of the local node. They apply to every
+// instance the node takes part in, so the node with the highest priority
+// becomes master of the vips it is a candidate for.
+type nodeSettings struct {
+	Priority int
+	Preempt  bool
+}
+
+func defaultNodeSettings(preempt bool) nodeSettings {
+	return nodeSettings{Priority: defaultPriority, Preempt: preempt}
+}
+
+// parseNodeSettings reads the settings from the node, labels take precedence
+// over annotations. Invalid values fall back to the defaults.
+func parseNodeSettings(node *corev1.Node, defaults nodeSettings) nodeSettings {
+	settings := defaults
+
+	if value := nodeValue(node, constant.OpenELBVipPriority); value != "" {
+		priority, err := strconv.Atoi(value)
+		if err != nil || priority < 1 || priority > maxPriority {
+			klog.Warningf("invalid vip priority %q of node %s, should be within [1, %d]", value, node.Name, maxPriority)
+		} else {
+			settings.Priority = priority
+		}
+	}
+
+	if value := nodeValue(node, constant.OpenELBVipPreempt); value != "" {
+		preempt, err := strconv.ParseBool(value)
+		if err != nil {
+			klog.Warningf("invalid vip preempt %q of node %s", value, node.Name)
+		} else {
+			settings.Preempt = preempt
+		}
+	}
+
+	return settings
+}
+
+func nodeValue(node *corev1.Node, key string) string {
+	if value := node.Labels[key]; value != "" {
+		return value
+	}
+	return node.Annotations[key]
+}
+
+// localNodeSettings returns the settings of the local node if it is in nodes.
+func localNodeSettings(nodes []corev1.Node, defaults nodeSettings) (nodeSettings, bool) {
+	for i := range nodes {
+		if nodes[i].Name == util.GetNodeName() {
+			return parseNodeSettings(&nodes[i], defaults), true
+		}
+	}
+	return defaults, false
+}
+
+// watchLocalNode calls onChange with the settings of the local node every
+// time its labels or annotations change.
+func watchLocalNode(client kubernetes.Interface, defaults nodeSettings, stopCh <-chan struct{}, onChange func(nodeSettings)) {
+	if client == nil {
+		return
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", util.GetNodeName()).String()
+		}))
+
+	update := func(obj interface{}) {
+		node, ok := obj.(*corev1.Node)
+		if !ok {
+			return
+		}
+		onChange(parseNodeSettings(node, defaults))
+	}
+	factory.Core().V1().Nodes().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: update,
+		UpdateFunc: func(_, obj interface{}) {
+			update(obj)
+		},
+	})
+	factory.Start(stopCh)
+}
@@ -0,0 +1,49 @@
+package vip
+
+import (
+	"testing"
+
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseNodeSettings(t *testing.T) {
+	defaults := defaultNodeSettings(false)
+
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		want        nodeSettings
+	}{
+		{
+			name: "defaults",
+			want: defaults,
+		},
+		{
+			name:        "annotations",
+			annotations: map[string]string{constant.OpenELBVipPriority: "150", constant.OpenELBVipPreempt: "true"},
+			want:        nodeSettings{Priority: 150, Preempt: true},
+		},
+		{
+			name:        "labels take precedence",
+			labels:      map[string]string{constant.OpenELBVipPriority: "200"},
+			annotations: map[string]string{constant.OpenELBVipPriority: "150"},
+			want:        nodeSettings{Priority: 200},
+		},
+		{
+			name:        "invalid values",
+			annotations: map[string]string{constant.OpenELBVipPriority: "255", constant.OpenELBVipPreempt: "yes please"},
+			want:        defaults,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: tt.labels, Annotations: tt.annotations}}
+			assert.Equal(t, tt.want, parseNodeSettings(node, defaults))
+		})
+	}
+}
@@ -1,25 +1,43 @@
 package vip
 
 import (
+	"time"
+
+	"github.com/openelb/openelb/pkg/constant"
 	"github.com/spf13/pflag"
 )
 
 type VipOptions struct {
 	EnableVIP      bool
+	Backend        string
 	LogPath        string
 	KeepAlivedArgs string
+	Preempt        bool
+	AdvertInterval time.Duration
+	TrackScripts   []string
+	TrackInterval  time.Duration
 }
 
 func NewVipOptions() *VipOptions {
 	return &VipOptions{
 		EnableVIP:      false,
+		Backend:        constant.VipBackendKeepalived,
 		LogPath:        "",
 		KeepAlivedArgs: "",
+		Preempt:        false,
+		AdvertInterval: time.Second,
+		TrackScripts:   []string{},
+		TrackInterval:  2 * time.Second,
 	}
 }
 
 func (v *VipOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&v.EnableVIP, "enable-keepalived-vip", v.EnableVIP, "specify whether to start keepalived-vip")
+	fs.StringVar(&v.Backend, "vip-backend", v.Backend, "specify the vrrp implementation of the vip speaker, keepalived or vrrp(in-process)")
 	fs.StringVar(&v.LogPath, "log-path", v.LogPath, "specify the path of the keepalived log file")
 	fs.StringVar(&v.KeepAlivedArgs, "keepalived-args", v.KeepAlivedArgs, "specify the arguments of keepalived")
+	fs.BoolVar(&v.Preempt, "vrrp-preempt", v.Preempt, "specify whether a higher priority backup takes over the vip, overridden by the "+constant.OpenELBVipPreempt+" node label or annotation")
+	fs.DurationVar(&v.AdvertInterval, "vrrp-advert-interval", v.AdvertInterval, "specify the vrrp advertisement interval, only for the vrrp backend")
+	fs.StringArrayVar(&v.TrackScripts, "vip-track-script", v.TrackScripts, "specify a health check, an executable and its arguments, the node gives up its vips while it fails, can be repeated")
+	fs.DurationVar(&v.TrackInterval, "vip-track-interval", v.TrackInterval, "specify the interval of the health checks of the track scripts and interfaces")
 }
@@ -0,0 +1,25 @@
+package vip
+
+import (
+	"fmt"
+
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/speaker"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NewSpeaker returns the vip speaker of the configured backend.
+func NewSpeaker(client *kubernetes.Clientset, opt *VipOptions) (speaker.Speaker, error) {
+	switch opt.Backend {
+	case constant.VipBackendKeepalived:
+		keepalived, err := NewKeepAlived(client, opt)
+		if err != nil {
+			return nil, err
+		}
+		return keepalived, nil
+	case constant.VipBackendVrrp:
+		return NewVrrpSpeaker(client, opt), nil
+	default:
+		return nil, fmt.Errorf("unsupported vip backend %s", opt.Backend)
+	}
+}
@@ -0,0 +1,127 @@
+package vip
+
+import (
+	"context"
+	"net"
+	"os/exec"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// consecutive failed checks before an interface is marked faulty
+	trackFall = 2
+	// consecutive successful checks before a faulty interface recovers
+	trackRise = 2
+)
+
+// tracker checks the health of the node the way keepalived does with
+// track_script and track_interface. A node gives up the vips of a faulty
+// interface until it recovers.
+type tracker struct {
+	scripts  []string
+	interval time.Duration
+	states   map[string]*trackState
+	// replaced in tests
+	runScript func(ctx context.Context, script string) error
+	linkUp    func(iface string) bool
+}
+
+type trackState struct {
+	healthy bool
+	count   int
+}
+
+func newTracker(scripts []string, interval time.Duration) *tracker {
+	return &tracker{
+		scripts:   scripts,
+		interval:  interval,
+		states:    make(map[string]*trackState),
+		runScript: runScript,
+		linkUp:    linkUp,
+	}
+}
+
+func runScript(ctx context.Context, script string) error {
+	return exec.CommandContext(ctx, "sh", "-c", script).Run()
+}
+
+func linkUp(iface string) bool {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return false
+	}
+	return ifi.Flags&net.FlagUp != 0 && ifi.Flags&net.FlagRunning != 0
+}
+
+// probe runs the track scripts and checks the interfaces, it does not change
+// the tracked states so that it can run without holding the speaker lock.
+func (t *tracker) probe(ifaces []string) map[string]bool {
+	scriptsOK := true
+	for _, script := range t.scripts {
+		ctx, cancel := context.WithTimeout(context.Background(), t.interval)
+		err := t.runScript(ctx, script)
+		cancel()
+		if err != nil {
+			klog.Warningf("vip track script %q failed: %v", script, err)
+			scriptsOK = false
+			break
+		}
+	}
+
+	results := make(map[string]bool, len(ifaces))
+	for _, iface := range ifaces {
+		up := t.linkUp(iface)
+		if !up {
+			klog.Warningf("vip track interface %s is down", iface)
+		}
+		results[iface] = scriptsOK && up
+	}
+	return results
+}
+
+// observe records the probe results and returns the interfaces whose health changed.
+func (t *tracker) observe(results map[string]bool) []string {
+	changed := []string{}
+	for iface, ok := range results {
+		state, exist := t.states[iface]
+		if !exist {
+			state = &trackState{healthy: true}
+			t.states[iface] = state
+		}
+
+		if ok == state.healthy {
+			state.count = 0
+			continue
+		}
+
+		state.count++
+		if (state.healthy && state.count >= trackFall) || (!state.healthy && state.count >= trackRise) {
+			state.healthy = ok
+			state.count = 0
+			changed = append(changed, iface)
+			if ok {
+				klog.Infof("vip track interface %s recovered", iface)
+			} else {
+				klog.Warningf("vip track interface %s is faulty, giving up its vips", iface)
+			}
+		}
+	}
+	return changed
+}
+
+// healthy reports whether the vips of iface may be held by the local node.
+func (t *tracker) healthy(iface string) bool {
+	state, exist := t.states[iface]
+	return !exist || state.healthy
+}
+
+// forget drops the state of interfaces no longer in use.
+func (t *tracker) forget(inUse map[string]bool) {
+	for iface := range t.states {
+		if !inUse[iface] {
+			delete(t.states, iface)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package vip
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	tr := newTracker([]string{"check-kube-proxy"}, time.Second)
+	scriptErr := error(nil)
+	up := map[string]bool{"eth0": true, "eth1": true}
+	tr.runScript = func(context.Context, string) error { return scriptErr }
+	tr.linkUp = func(iface string) bool { return up[iface] }
+
+	ifaces := []string{"eth0", "eth1"}
+	assert.Empty(t, tr.observe(tr.probe(ifaces)))
+	assert.True(t, tr.healthy("eth0"))
+
+	// a single failure is tolerated
+	up["eth1"] = false
+	assert.Empty(t, tr.observe(tr.probe(ifaces)))
+	assert.True(t, tr.healthy("eth1"))
+	assert.Equal(t, []string{"eth1"}, tr.observe(tr.probe(ifaces)))
+	assert.False(t, tr.healthy("eth1"))
+	assert.True(t, tr.healthy("eth0"))
+
+	// a failing script marks every interface faulty
+	up["eth1"] = true
+	scriptErr = errors.New("exit status 1")
+	tr.observe(tr.probe(ifaces))
+	assert.ElementsMatch(t, []string{"eth0"}, tr.observe(tr.probe(ifaces)))
+	assert.False(t, tr.healthy("eth0"))
+	assert.False(t, tr.healthy("eth1"))
+
+	scriptErr = nil
+	tr.observe(tr.probe(ifaces))
+	assert.ElementsMatch(t, []string{"eth0", "eth1"}, tr.observe(tr.probe(ifaces)))
+
+	tr.forget(map[string]bool{"eth0": true})
+	assert.Len(t, tr.states, 1)
+}
@@ -0,0 +1,429 @@
+package vip
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openelb/openelb/pkg/metrics"
+	"github.com/openelb/openelb/pkg/speaker"
+	"github.com/openelb/openelb/pkg/speaker/vip/vrrp"
+	"github.com/openelb/openelb/pkg/util/idalloc"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+var _ speaker.Speaker = &vrrpSpeaker{}
+var _ speaker.BalancerOwner = &vrrpSpeaker{}
+
+// vrrpSpeaker is the in-process replacement of keepAlived. Instances are built
+// the same way, but every address family of an instance runs its own VRRPv3
+// virtual router on the interface.
+type vrrpSpeaker struct {
+	lock      sync.Mutex
+	client    kubernetes.Interface
+	interval  time.Duration
+	defaults  nodeSettings
+	settings  nodeSettings
+	tracker   *tracker
+	instances map[string]*vrrpInstance
+	vips      map[string]string
+	configs   map[string]*speaker.Config
+	routers   map[routerKey]*vrrp.Router
+	idAlloc   idalloc.IDAllocator
+	// newRouter opens the sockets of a router, replaced in tests
+	newRouter func(iface string, ipv6 bool) (*vrrp.Router, error)
+	// notify is called when an instance transitions
+	notify func()
+}
+
+type vrrpInstance struct {
+	instances
+	running map[bool]*vrrp.Instance
+}
+
+type routerKey struct {
+	iface string
+	ipv6  bool
+}
+
+func NewVrrpSpeaker(client kubernetes.Interface, opt *VipOptions) *vrrpSpeaker {
+	defaults := defaultNodeSettings(opt.Preempt)
+	return &vrrpSpeaker{
+		client:    client,
+		interval:  opt.AdvertInterval,
+		defaults:  defaults,
+		settings:  defaults,
+		tracker:   newTracker(opt.TrackScripts, opt.TrackInterval),
+		idAlloc:   idalloc.New(256),
+		instances: make(map[string]*vrrpInstance),
+		vips:      make(map[string]string),
+		configs:   make(map[string]*speaker.Config),
+		routers:   make(map[routerKey]*vrrp.Router),
+		newRouter: newVrrpRouter,
+	}
+}
+
+func newVrrpRouter(iface string, ipv6 bool) (*vrrp.Router, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := vrrp.NewConn(ifi, ipv6)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := vrrp.NewAddressManager(ifi, ipv6)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return vrrp.NewRouter(conn, addrs), nil
+}
+
+func (v *vrrpSpeaker) SetBalancer(vip string, nodes []corev1.Node) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if settings, ok := localNodeSettings(nodes, v.defaults); ok {
+		v.applySettings(settings)
+	}
+
+	iface := v.getInterfaces(vip)
+	if iface == "" {
+		return fmt.Errorf("no interface found for VIP %s", vip)
+	}
+
+	bytes := getNodeSha256Bytes(nodes)
+	instanceName := hex.EncodeToString(bytes[:]) + "-" + iface
+
+	// clean the old record, keep the instance running if the vip stays in it
+	value, exist := v.vips[vip]
+	if exist && value != instanceName {
+		if err := v.cleanRecord(vip, value); err != nil {
+			klog.Error(err)
+		}
+	}
+
+	// generate new record
+	instance, exist := v.instances[instanceName]
+	if !exist {
+		routeid, err := v.idAlloc.AllocateWithHash(bytes)
+		if err != nil {
+			return err
+		}
+
+		instance = &vrrpInstance{
+			instances: instances{
+				Name:     instanceName,
+				Iface:    iface,
+				RouteID:  routeid,
+				Priority: v.settings.Priority,
+				Preempt:  v.settings.Preempt,
+			},
+			running: make(map[bool]*vrrp.Instance),
+		}
+		v.instances[instanceName] = instance
+	}
+	instance.Enabled = isNodeInList(nodes)
+	if value != instanceName {
+		instance.Svcips = append(instance.Svcips, vip)
+		v.vips[vip] = instanceName
+	}
+
+	return v.sync(instance)
+}
+
+func (v *vrrpSpeaker) DelBalancer(vip string) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	instanceName, exist := v.vips[vip]
+	if !exist {
+		return nil
+	}
+	delete(v.vips, vip)
+
+	return v.cleanRecord(vip, instanceName)
+}
+
+// cleanRecord removes vip from the instance and stops the instance once it is empty.
+func (v *vrrpSpeaker) cleanRecord(vip, instanceName string) error {
+	instance, ok := v.instances[instanceName]
+	if !ok {
+		return nil
+	}
+
+	for i, ip := range instance.Svcips {
+		if ip == vip {
+			instance.Svcips = append(instance.Svcips[:i:i], instance.Svcips[i+1:]...)
+			break
+		}
+	}
+
+	if len(instance.Svcips) == 0 {
+		delete(v.instances, instanceName)
+		v.idAlloc.Free(instance.RouteID)
+	}
+	return v.sync(instance)
+}
+
+// sync runs one virtual router for every address family of the instance's
+// VIPs while the local node is one of its candidates and its interface is healthy.
+func (v *vrrpSpeaker) sync(instance *vrrpInstance) error {
+	addresses := map[bool][]net.IP{}
+	if instance.Enabled && v.tracker.healthy(instance.Iface) {
+		for _, vip := range instance.Svcips {
+			ip := net.ParseIP(vip)
+			if ip == nil {
+				continue
+			}
+			ipv6 := ip.To4() == nil
+			addresses[ipv6] = append(addresses[ipv6], ip)
+		}
+	}
+
+	var errs []error
+	for _, ipv6 := range []bool{false, true} {
+		key := routerKey{iface: instance.Iface, ipv6: ipv6}
+		running := instance.running[ipv6]
+
+		if len(addresses[ipv6]) == 0 {
+			if running != nil {
+				v.routers[key].RemoveInstance(uint8(instance.RouteID))
+				delete(instance.running, ipv6)
+				metrics.DeleteVrrpStateMetrics(instance.Name, strconv.Itoa(int(instance.RouteID)), family(ipv6))
+				v.closeRouter(key)
+			}
+			continue
+		}
+
+		config := vrrp.DefaultConfig(uint8(instance.RouteID))
+		config.Priority = uint8(instance.Priority)
+		config.Preempt = instance.Preempt
+		config.Interval = v.interval
+		config.Addresses = addresses[ipv6]
+
+		if running != nil {
+			if err := running.Update(config); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		router, err := v.getRouter(key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		running, err = router.AddInstance(config, stateReporter(instance.Name, ipv6, v.notify))
+		if err != nil {
+			errs = append(errs, err)
+			v.closeRouter(key)
+			continue
+		}
+		instance.running[ipv6] = running
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("sync vrrp instance %s: %v", instance.Name, errs)
+	}
+	return nil
+}
+
+func (v *vrrpSpeaker) getRouter(key routerKey) (*vrrp.Router, error) {
+	if router, exist := v.routers[key]; exist {
+		return router, nil
+	}
+
+	router, err := v.newRouter(key.iface, key.ipv6)
+	if err != nil {
+		return nil, fmt.Errorf("start vrrp on %s: %v", key.iface, err)
+	}
+	v.routers[key] = router
+	return router, nil
+}
+
+// closeRouter closes the sockets of an interface once no instance uses them.
+func (v *vrrpSpeaker) closeRouter(key routerKey) {
+	router, exist := v.routers[key]
+	if !exist || router.Len() > 0 {
+		return
+	}
+
+	delete(v.routers, key)
+	if err := router.Close(); err != nil {
+		klog.Errorf("close vrrp on %s: %v", key.iface, err)
+	}
+}
+
+// stateReporter logs and exports the state transitions of an instance, and
+// calls notify since the instance may have gained or lost its vips.
+func stateReporter(name string, ipv6 bool, notify func()) vrrp.StateHandler {
+	return func(vrid uint8, state vrrp.State) {
+		klog.Infof("vrrp instance %s(%s) transitioned to %s", name, family(ipv6), state)
+		metrics.UpdateVrrpStateMetrics(name, strconv.Itoa(int(vrid)), family(ipv6), float64(state))
+		if notify != nil {
+			notify()
+		}
+	}
+}
+
+func family(ipv6 bool) string {
+	if ipv6 {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// OwnBalancer returns true if the node is the master of the instance of the vip.
+func (v *vrrpSpeaker) OwnBalancer(vip string) bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	instance, exist := v.instances[v.vips[vip]]
+	if !exist {
+		return false
+	}
+	running, exist := instance.running[net.ParseIP(vip).To4() == nil]
+	return exist && running.State() == vrrp.StateMaster
+}
+
+// NotifyOwnership sets the function called when an instance transitions, it
+// applies to the instances started afterwards.
+func (v *vrrpSpeaker) NotifyOwnership(notify func()) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.notify = notify
+}
+
+// getInterfaces returns the interface name for the given VIP
+func (v *vrrpSpeaker) getInterfaces(vip string) string {
+	for _, c := range v.configs {
+		if c.IPRange.Contains(net.ParseIP(vip)) {
+			return c.Iface
+		}
+	}
+	return ""
+}
+
+// applySettings sets the priority and preemption of every instance.
+func (v *vrrpSpeaker) applySettings(settings nodeSettings) {
+	if settings == v.settings {
+		return
+	}
+
+	klog.Infof("vip priority %d, preempt %t", settings.Priority, settings.Preempt)
+	v.settings = settings
+	for _, instance := range v.instances {
+		instance.Priority = settings.Priority
+		instance.Preempt = settings.Preempt
+		if err := v.sync(instance); err != nil {
+			klog.Error(err)
+		}
+	}
+}
+
+func (v *vrrpSpeaker) updateSettings(settings nodeSettings) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.applySettings(settings)
+}
+
+// track checks the health of the interfaces in use, and stops or restarts
+// the instances of the interfaces whose health changed.
+func (v *vrrpSpeaker) track() {
+	v.lock.Lock()
+	inUse := map[string]bool{}
+	for _, instance := range v.instances {
+		if instance.Enabled {
+			inUse[instance.Iface] = true
+		}
+	}
+	v.lock.Unlock()
+
+	ifaces := make([]string, 0, len(inUse))
+	for iface := range inUse {
+		ifaces = append(ifaces, iface)
+	}
+	results := v.tracker.probe(ifaces)
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.tracker.forget(inUse)
+	for _, iface := range v.tracker.observe(results) {
+		for _, instance := range v.instances {
+			if instance.Iface != iface {
+				continue
+			}
+			if err := v.sync(instance); err != nil {
+				klog.Error(err)
+			}
+		}
+	}
+}
+
+// Start tracks the local node until stopCh is closed, then resigns every
+// instance so that the backups take over without waiting for the master down interval.
+func (v *vrrpSpeaker) Start(stopCh <-chan struct{}) error {
+	watchLocalNode(v.client, v.defaults, stopCh, v.updateSettings)
+
+	ticker := time.NewTicker(v.tracker.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			v.shutdown()
+			return nil
+		case <-ticker.C:
+			v.track()
+		}
+	}
+}
+
+func (v *vrrpSpeaker) shutdown() {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	for key, router := range v.routers {
+		if err := router.Close(); err != nil {
+			klog.Errorf("close vrrp on %s: %v", key.iface, err)
+		}
+	}
+	v.routers = make(map[routerKey]*vrrp.Router)
+	for _, instance := range v.instances {
+		for ipv6 := range instance.running {
+			metrics.DeleteVrrpStateMetrics(instance.Name, strconv.Itoa(int(instance.RouteID)), family(ipv6))
+		}
+		instance.running = make(map[bool]*vrrp.Instance)
+	}
+}
+
+func (v *vrrpSpeaker) ConfigureWithEIP(config speaker.Config, deleted bool) error {
+	netif, err := speaker.ParseInterface(config.Iface, config.NodeLabels)
+	if err != nil || netif == nil {
+		return err
+	}
+	config.Iface = netif.Name
+	if err := speaker.ValidateInterface(netif, config.IPRange); err != nil {
+		return err
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if deleted {
+		delete(v.configs, config.Name)
+	} else {
+		v.configs[config.Name] = &config
+	}
+	return nil
+}
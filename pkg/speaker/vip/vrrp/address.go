@@ -0,0 +1,133 @@
+package vrrp
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+
+	"github.com/mdlayher/arp"
+	"github.com/mdlayher/ethernet"
+	"github.com/mdlayher/ndp"
+	"github.com/vishvananda/netlink"
+	"k8s.io/klog/v2"
+)
+
+// AddressManager owns the virtual addresses on an interface while the instance is master.
+type AddressManager interface {
+	// AddAddress configures ip on the interface and announces it to the segment.
+	AddAddress(ip net.IP) error
+	// DelAddress removes ip from the interface.
+	DelAddress(ip net.IP) error
+	Close() error
+}
+
+var _ AddressManager = &linkAddresses{}
+
+type linkAddresses struct {
+	ifi    *net.Interface
+	handle *netlink.Handle
+	link   netlink.Link
+	arp    *arp.Client
+	ndp    *ndp.Conn
+}
+
+// NewAddressManager manages the virtual addresses of ifi. The netlink and
+// announcement sockets are opened in the network namespace of the caller.
+func NewAddressManager(ifi *net.Interface, ipv6 bool) (AddressManager, error) {
+	handle, err := netlink.NewHandle()
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := handle.LinkByIndex(ifi.Index)
+	if err != nil {
+		handle.Delete()
+		return nil, fmt.Errorf("get link %s: %v", ifi.Name, err)
+	}
+
+	l := &linkAddresses{ifi: ifi, handle: handle, link: link}
+	if ipv6 {
+		l.ndp, _, err = ndp.Listen(ifi, ndp.LinkLocal)
+	} else {
+		l.arp, err = arp.Dial(ifi)
+	}
+	if err != nil {
+		handle.Delete()
+		return nil, fmt.Errorf("open announcement socket on %s: %v", ifi.Name, err)
+	}
+
+	return l, nil
+}
+
+func (l *linkAddresses) AddAddress(ip net.IP) error {
+	if err := l.handle.AddrReplace(l.link, hostAddr(ip)); err != nil {
+		return fmt.Errorf("add address %s to %s: %v", ip, l.ifi.Name, err)
+	}
+
+	return l.gratuitous(ip)
+}
+
+func (l *linkAddresses) DelAddress(ip net.IP) error {
+	if err := l.handle.AddrDel(l.link, hostAddr(ip)); err != nil && !isNotExist(err) {
+		return fmt.Errorf("delete address %s from %s: %v", ip, l.ifi.Name, err)
+	}
+
+	return nil
+}
+
+// gratuitous tells the neighbours that the virtual address moved to this interface.
+func (l *linkAddresses) gratuitous(ip net.IP) error {
+	klog.V(4).Infof("send gratuitous announcement: %s-%s", ip, l.ifi.HardwareAddr)
+
+	if l.arp != nil {
+		for _, op := range []arp.Operation{arp.OperationRequest, arp.OperationReply} {
+			pkt, err := arp.NewPacket(op, l.ifi.HardwareAddr, ip.To4(), ethernet.Broadcast, ip.To4())
+			if err != nil {
+				return err
+			}
+			if err := l.arp.WriteTo(pkt, ethernet.Broadcast); err != nil {
+				return fmt.Errorf("send gratuitous arp packet: %v", err)
+			}
+		}
+		return nil
+	}
+
+	target, ok := netip.AddrFromSlice(ip.To16())
+	if !ok {
+		return fmt.Errorf("invalid address %s", ip)
+	}
+	na := &ndp.NeighborAdvertisement{
+		Override:      true,
+		TargetAddress: target,
+		Options: []ndp.Option{
+			&ndp.LinkLayerAddress{
+				Direction: ndp.Target,
+				Addr:      l.ifi.HardwareAddr,
+			},
+		},
+	}
+	return l.ndp.WriteTo(na, nil, netip.IPv6LinkLocalAllNodes())
+}
+
+func (l *linkAddresses) Close() error {
+	defer l.handle.Delete()
+	if l.arp != nil {
+		return l.arp.Close()
+	}
+	return l.ndp.Close()
+}
+
+func hostAddr(ip net.IP) *netlink.Addr {
+	bits := net.IPv6len * 8
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = net.IPv4len * 8
+	}
+
+	return &netlink.Addr{IPNet: &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}}
+}
+
+func isNotExist(err error) bool {
+	return err == syscall.EADDRNOTAVAIL
+}
@@ -0,0 +1,228 @@
+package vrrp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"syscall"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// readBufferSize is large enough for an advertisement carrying 255 IPv6 addresses.
+const readBufferSize = headerLen + 255*net.IPv6len
+
+// Conn sends and receives advertisements of one address family on one interface.
+type Conn interface {
+	// WriteAdvertisement multicasts the advertisement to the VRRP group.
+	WriteAdvertisement(a *Advertisement) error
+	// ReadAdvertisement blocks until a valid advertisement is received and
+	// returns it together with its source address.
+	ReadAdvertisement() (*Advertisement, net.IP, error)
+	// LocalAddr returns the primary address used as the advertisement source.
+	LocalAddr() net.IP
+	Close() error
+}
+
+var (
+	_ Conn = &ipv4Conn{}
+	_ Conn = &ipv6Conn{}
+)
+
+type ipv4Conn struct {
+	ifi   *net.Interface
+	local net.IP
+	pc    *ipv4.PacketConn
+	buf   []byte
+}
+
+type ipv6Conn struct {
+	ifi   *net.Interface
+	local net.IP
+	pc    *ipv6.PacketConn
+	buf   []byte
+}
+
+// NewConn opens a raw VRRP socket bound to ifi for the family of ipv6.
+func NewConn(ifi *net.Interface, ipv6 bool) (Conn, error) {
+	local, err := primaryAddr(ifi, ipv6)
+	if err != nil {
+		return nil, err
+	}
+
+	if ipv6 {
+		return newIPv6Conn(ifi, local)
+	}
+	return newIPv4Conn(ifi, local)
+}
+
+func newIPv4Conn(ifi *net.Interface, local net.IP) (*ipv4Conn, error) {
+	c, err := listen(ifi, "ip4:112", "0.0.0.0")
+	if err != nil {
+		return nil, err
+	}
+
+	pc := ipv4.NewPacketConn(c)
+	group := &net.IPAddr{IP: multicastGroupV4}
+	for _, f := range []func() error{
+		func() error { return pc.JoinGroup(ifi, group) },
+		func() error { return pc.SetMulticastInterface(ifi) },
+		func() error { return pc.SetMulticastTTL(advertisementTTL) },
+		func() error { return pc.SetMulticastLoopback(false) },
+		func() error { return pc.SetControlMessage(ipv4.FlagTTL|ipv4.FlagDst|ipv4.FlagInterface, true) },
+	} {
+		if err := f(); err != nil {
+			pc.Close()
+			return nil, fmt.Errorf("setup vrrp socket on %s: %v", ifi.Name, err)
+		}
+	}
+
+	return &ipv4Conn{ifi: ifi, local: local, pc: pc, buf: make([]byte, readBufferSize)}, nil
+}
+
+func newIPv6Conn(ifi *net.Interface, local net.IP) (*ipv6Conn, error) {
+	c, err := listen(ifi, "ip6:112", "::")
+	if err != nil {
+		return nil, err
+	}
+
+	pc := ipv6.NewPacketConn(c)
+	group := &net.IPAddr{IP: multicastGroupV6}
+	for _, f := range []func() error{
+		func() error { return pc.JoinGroup(ifi, group) },
+		func() error { return pc.SetMulticastInterface(ifi) },
+		func() error { return pc.SetMulticastHopLimit(advertisementHopLim) },
+		func() error { return pc.SetMulticastLoopback(false) },
+		func() error { return pc.SetControlMessage(ipv6.FlagHopLimit|ipv6.FlagDst|ipv6.FlagInterface, true) },
+	} {
+		if err := f(); err != nil {
+			pc.Close()
+			return nil, fmt.Errorf("setup vrrp socket on %s: %v", ifi.Name, err)
+		}
+	}
+
+	return &ipv6Conn{ifi: ifi, local: local, pc: pc, buf: make([]byte, readBufferSize)}, nil
+}
+
+// listen opens a raw socket that only receives packets from ifi.
+func listen(ifi *net.Interface, network, address string) (net.PacketConn, error) {
+	lc := net.ListenConfig{
+		Control: func(_, _ string, c syscall.RawConn) error {
+			var serr error
+			if err := c.Control(func(fd uintptr) {
+				serr = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, ifi.Name)
+			}); err != nil {
+				return err
+			}
+			return serr
+		},
+	}
+
+	c, err := lc.ListenPacket(context.Background(), network, address)
+	if err != nil {
+		return nil, fmt.Errorf("listen vrrp on %s: %v", ifi.Name, err)
+	}
+	return c, nil
+}
+
+// primaryAddr returns the first IPv4 address, or the link-local IPv6 address of ifi.
+func primaryAddr(ifi *net.Interface, ipv6 bool) (net.IP, error) {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if !ipv6 && ipnet.IP.To4() != nil {
+			return ipnet.IP.To4(), nil
+		}
+		if ipv6 && ipnet.IP.To4() == nil && ipnet.IP.IsLinkLocalUnicast() {
+			return ipnet.IP, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no usable address for vrrp found on %s", ifi.Name)
+}
+
+func (c *ipv4Conn) WriteAdvertisement(a *Advertisement) error {
+	b, err := a.Marshal(c.local, multicastGroupV4)
+	if err != nil {
+		return err
+	}
+
+	cm := &ipv4.ControlMessage{Src: c.local, IfIndex: c.ifi.Index}
+	_, err = c.pc.WriteTo(b, cm, &net.IPAddr{IP: multicastGroupV4})
+	return err
+}
+
+func (c *ipv4Conn) ReadAdvertisement() (*Advertisement, net.IP, error) {
+	b := c.buf
+	for {
+		n, cm, src, err := c.pc.ReadFrom(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cm == nil || cm.TTL != advertisementTTL || !cm.Dst.Equal(multicastGroupV4) {
+			continue
+		}
+
+		srcIP := src.(*net.IPAddr).IP
+		a, err := ParseAdvertisement(b[:n], srcIP, cm.Dst)
+		if err != nil {
+			continue
+		}
+		return a, srcIP, nil
+	}
+}
+
+func (c *ipv4Conn) LocalAddr() net.IP {
+	return c.local
+}
+
+func (c *ipv4Conn) Close() error {
+	return c.pc.Close()
+}
+
+func (c *ipv6Conn) WriteAdvertisement(a *Advertisement) error {
+	b, err := a.Marshal(c.local, multicastGroupV6)
+	if err != nil {
+		return err
+	}
+
+	cm := &ipv6.ControlMessage{Src: c.local, IfIndex: c.ifi.Index}
+	_, err = c.pc.WriteTo(b, cm, &net.IPAddr{IP: multicastGroupV6, Zone: c.ifi.Name})
+	return err
+}
+
+func (c *ipv6Conn) ReadAdvertisement() (*Advertisement, net.IP, error) {
+	b := c.buf
+	for {
+		n, cm, src, err := c.pc.ReadFrom(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cm == nil || cm.HopLimit != advertisementHopLim || !cm.Dst.Equal(multicastGroupV6) {
+			continue
+		}
+
+		srcIP := src.(*net.IPAddr).IP
+		a, err := ParseAdvertisement(b[:n], srcIP, cm.Dst)
+		if err != nil {
+			continue
+		}
+		return a, srcIP, nil
+	}
+}
+
+func (c *ipv6Conn) LocalAddr() net.IP {
+	return c.local
+}
+
+func (c *ipv6Conn) Close() error {
+	return c.pc.Close()
+}
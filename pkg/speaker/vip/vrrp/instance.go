@@ -0,0 +1,344 @@
+package vrrp
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// State is the state of a VRRP instance as defined in RFC 5798 section 6.4.
+type State int
+
+const (
+	StateInitialize State = iota
+	StateBackup
+	StateMaster
+)
+
+func (s State) String() string {
+	switch s {
+	case StateInitialize:
+		return "INIT"
+	case StateBackup:
+		return "BACKUP"
+	case StateMaster:
+		return "MASTER"
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", int(s))
+}
+
+// StateHandler is called every time an instance transitions to a new state.
+type StateHandler func(vrid uint8, state State)
+
+// Config is the configuration of a single virtual router.
+type Config struct {
+	VRID     uint8
+	Priority uint8
+	// Preempt allows a higher priority backup to take over from a lower priority master.
+	Preempt   bool
+	Interval  time.Duration
+	Addresses []net.IP
+}
+
+// DefaultConfig returns the configuration keepalived used to be started with.
+func DefaultConfig(vrid uint8) Config {
+	return Config{
+		VRID:     vrid,
+		Priority: defaultPriority,
+		Preempt:  false,
+		Interval: time.Second,
+	}
+}
+
+func (c Config) validate() error {
+	if c.VRID == 0 {
+		return fmt.Errorf("invalid vrid 0")
+	}
+	if c.Priority == priorityStepDown {
+		return fmt.Errorf("invalid priority 0 for vrid %d", c.VRID)
+	}
+	if c.Interval < centisecond || c.Interval > maxAdvertInterval*centisecond {
+		return fmt.Errorf("invalid advertisement interval %s for vrid %d", c.Interval, c.VRID)
+	}
+	return nil
+}
+
+type received struct {
+	adv *Advertisement
+	src net.IP
+}
+
+// Instance is a virtual router. It owns its addresses while it is master.
+type Instance struct {
+	conn          Conn
+	addrs         AddressManager
+	onStateChange StateHandler
+
+	lock     sync.Mutex
+	config   Config
+	state    State
+	assigned map[string]net.IP
+
+	recvCh   chan received
+	updateCh chan struct{}
+	stopCh   chan struct{}
+	done     chan struct{}
+}
+
+// NewInstance creates a stopped virtual router sending on conn.
+func NewInstance(conn Conn, addrs AddressManager, config Config, onStateChange StateHandler) (*Instance, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	return &Instance{
+		conn:          conn,
+		addrs:         addrs,
+		onStateChange: onStateChange,
+		config:        config,
+		state:         StateInitialize,
+		assigned:      make(map[string]net.IP),
+		recvCh:        make(chan received, 16),
+		updateCh:      make(chan struct{}, 1),
+		stopCh:        make(chan struct{}),
+		done:          make(chan struct{}),
+	}, nil
+}
+
+// Start runs the state machine until Stop is called.
+func (i *Instance) Start() {
+	go i.run()
+}
+
+// Stop resigns mastership, releases the addresses and waits for the state machine to exit.
+func (i *Instance) Stop() {
+	select {
+	case <-i.stopCh:
+	default:
+		close(i.stopCh)
+	}
+	<-i.done
+}
+
+// State returns the current state of the instance.
+func (i *Instance) State() State {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.state
+}
+
+// Config returns the current configuration of the instance.
+func (i *Instance) Config() Config {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.config
+}
+
+// Update changes priority, preemption, interval and addresses of a running
+// instance. The VRID of an instance cannot be changed.
+func (i *Instance) Update(config Config) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	i.lock.Lock()
+	if config.VRID != i.config.VRID {
+		i.lock.Unlock()
+		return fmt.Errorf("vrid of instance %d cannot be changed to %d", i.config.VRID, config.VRID)
+	}
+	i.config = config
+	i.lock.Unlock()
+
+	select {
+	case i.updateCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Deliver hands a received advertisement to the state machine.
+func (i *Instance) Deliver(adv *Advertisement, src net.IP) {
+	select {
+	case i.recvCh <- received{adv: adv, src: src}:
+	case <-i.done:
+	}
+}
+
+func (i *Instance) run() {
+	defer close(i.done)
+
+	config := i.Config()
+	masterAdverInterval := config.Interval
+	timer := time.NewTimer(config.Interval)
+	defer timer.Stop()
+
+	if config.Priority == priorityOwner {
+		i.advertise(config, config.Priority)
+		i.becomeMaster(config)
+	} else {
+		i.becomeBackup()
+		resetTimer(timer, masterDownInterval(masterAdverInterval, config.Priority))
+	}
+
+	for {
+		select {
+		case <-i.stopCh:
+			if i.State() == StateMaster {
+				i.advertise(i.Config(), priorityStepDown)
+			}
+			i.release()
+			i.setState(StateInitialize)
+			return
+
+		case <-timer.C:
+			config := i.Config()
+			i.advertise(config, config.Priority)
+			if i.State() == StateBackup {
+				i.becomeMaster(config)
+			}
+			resetTimer(timer, config.Interval)
+
+		case r := <-i.recvCh:
+			config := i.Config()
+			if r.adv.Interval <= 0 {
+				r.adv.Interval = config.Interval
+			}
+			switch i.State() {
+			case StateBackup:
+				if r.adv.Priority == priorityStepDown {
+					resetTimer(timer, skewTime(masterAdverInterval, config.Priority))
+				} else if !config.Preempt || r.adv.Priority >= config.Priority {
+					masterAdverInterval = r.adv.Interval
+					resetTimer(timer, masterDownInterval(masterAdverInterval, config.Priority))
+				}
+			case StateMaster:
+				if r.adv.Priority == priorityStepDown {
+					i.advertise(config, config.Priority)
+					resetTimer(timer, config.Interval)
+				} else if preferred(r, config.Priority, i.conn.LocalAddr()) {
+					klog.Infof("vrrp instance %d: %s with priority %d takes over", config.VRID, r.src, r.adv.Priority)
+					masterAdverInterval = r.adv.Interval
+					resetTimer(timer, masterDownInterval(masterAdverInterval, config.Priority))
+					i.becomeBackup()
+				}
+			}
+
+		case <-i.updateCh:
+			if i.State() == StateMaster {
+				config := i.Config()
+				i.assign(config)
+				// let the backups learn about the new priority and addresses right away
+				i.advertise(config, config.Priority)
+			}
+		}
+	}
+}
+
+// preferred reports whether the sender of r wins the election against the
+// local router, higher priority first and higher primary address second.
+func preferred(r received, priority uint8, local net.IP) bool {
+	if r.adv.Priority != priority {
+		return r.adv.Priority > priority
+	}
+	return bytes.Compare(r.src.To16(), local.To16()) > 0
+}
+
+func (i *Instance) advertise(config Config, priority uint8) {
+	adv := &Advertisement{
+		VRID:      config.VRID,
+		Priority:  priority,
+		Interval:  config.Interval,
+		Addresses: config.Addresses,
+	}
+	if err := i.conn.WriteAdvertisement(adv); err != nil {
+		klog.Errorf("vrrp instance %d: send advertisement: %v", config.VRID, err)
+	}
+}
+
+func (i *Instance) becomeMaster(config Config) {
+	i.assign(config)
+	i.setState(StateMaster)
+}
+
+func (i *Instance) becomeBackup() {
+	i.release()
+	i.setState(StateBackup)
+}
+
+// assign configures the addresses of config and removes the ones no longer in it.
+func (i *Instance) assign(config Config) {
+	desired := make(map[string]net.IP, len(config.Addresses))
+	for _, ip := range config.Addresses {
+		desired[ip.String()] = ip
+	}
+
+	for key, ip := range i.assigned {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		if err := i.addrs.DelAddress(ip); err != nil {
+			klog.Errorf("vrrp instance %d: %v", config.VRID, err)
+			continue
+		}
+		delete(i.assigned, key)
+	}
+
+	for key, ip := range desired {
+		if _, ok := i.assigned[key]; ok {
+			continue
+		}
+		if err := i.addrs.AddAddress(ip); err != nil {
+			klog.Errorf("vrrp instance %d: %v", config.VRID, err)
+			continue
+		}
+		i.assigned[key] = ip
+	}
+}
+
+func (i *Instance) release() {
+	for key, ip := range i.assigned {
+		if err := i.addrs.DelAddress(ip); err != nil {
+			klog.Errorf("vrrp instance %d: %v", i.Config().VRID, err)
+			continue
+		}
+		delete(i.assigned, key)
+	}
+}
+
+func (i *Instance) setState(state State) {
+	i.lock.Lock()
+	old := i.state
+	i.state = state
+	vrid := i.config.VRID
+	i.lock.Unlock()
+
+	if old == state {
+		return
+	}
+	klog.Infof("vrrp instance %d: %s -> %s", vrid, old, state)
+	if i.onStateChange != nil {
+		i.onStateChange(vrid, state)
+	}
+}
+
+func skewTime(masterAdverInterval time.Duration, priority uint8) time.Duration {
+	return time.Duration(256-int64(priority)) * masterAdverInterval / 256
+}
+
+func masterDownInterval(masterAdverInterval time.Duration, priority uint8) time.Duration {
+	return 3*masterAdverInterval + skewTime(masterAdverInterval, priority)
+}
+
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
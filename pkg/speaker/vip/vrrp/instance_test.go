@@ -0,0 +1,215 @@
+package vrrp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testInterval = 20 * time.Millisecond
+
+// fakeBus is an in-memory segment delivering advertisements to every other conn.
+type fakeBus struct {
+	lock  sync.Mutex
+	conns []*fakeConn
+}
+
+type fakeConn struct {
+	bus    *fakeBus
+	local  net.IP
+	recvCh chan received
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (b *fakeBus) conn(local string) *fakeConn {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	c := &fakeConn{bus: b, local: net.ParseIP(local), recvCh: make(chan received, 64), closed: make(chan struct{})}
+	b.conns = append(b.conns, c)
+	return c
+}
+
+func (c *fakeConn) WriteAdvertisement(a *Advertisement) error {
+	c.bus.lock.Lock()
+	defer c.bus.lock.Unlock()
+
+	for _, peer := range c.bus.conns {
+		if peer == c {
+			continue
+		}
+		select {
+		case peer.recvCh <- received{adv: a, src: c.local}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (c *fakeConn) ReadAdvertisement() (*Advertisement, net.IP, error) {
+	select {
+	case r := <-c.recvCh:
+		return r.adv, r.src, nil
+	case <-c.closed:
+		return nil, nil, errors.New("closed")
+	}
+}
+
+func (c *fakeConn) LocalAddr() net.IP {
+	return c.local
+}
+
+func (c *fakeConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+type fakeAddresses struct {
+	lock  sync.Mutex
+	addrs map[string]bool
+}
+
+func newFakeAddresses() *fakeAddresses {
+	return &fakeAddresses{addrs: make(map[string]bool)}
+}
+
+func (f *fakeAddresses) AddAddress(ip net.IP) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.addrs[ip.String()] = true
+	return nil
+}
+
+func (f *fakeAddresses) DelAddress(ip net.IP) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.addrs, ip.String())
+	return nil
+}
+
+func (f *fakeAddresses) Close() error {
+	return nil
+}
+
+func (f *fakeAddresses) has(ip string) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.addrs[ip]
+}
+
+type testRouter struct {
+	*Router
+	addrs *fakeAddresses
+}
+
+func newTestRouter(bus *fakeBus, local string) *testRouter {
+	addrs := newFakeAddresses()
+	return &testRouter{Router: NewRouter(bus.conn(local), addrs), addrs: addrs}
+}
+
+func testConfig(priority uint8, preempt bool) Config {
+	return Config{
+		VRID:      10,
+		Priority:  priority,
+		Preempt:   preempt,
+		Interval:  testInterval,
+		Addresses: []net.IP{net.ParseIP("192.168.0.100")},
+	}
+}
+
+func eventuallyState(t *testing.T, i *Instance, state State) {
+	t.Helper()
+	assert.Eventually(t, func() bool { return i.State() == state }, 2*time.Second, testInterval/2, "want %s", state)
+}
+
+func TestInstance_Election(t *testing.T) {
+	bus := &fakeBus{}
+	r1 := newTestRouter(bus, "192.168.0.1")
+	r2 := newTestRouter(bus, "192.168.0.2")
+	defer r1.Close()
+	defer r2.Close()
+
+	var lock sync.Mutex
+	var states []State
+	i1, err := r1.AddInstance(testConfig(100, false), func(vrid uint8, state State) {
+		lock.Lock()
+		defer lock.Unlock()
+		assert.Equal(t, uint8(10), vrid)
+		states = append(states, state)
+	})
+	assert.NoError(t, err)
+	i2, err := r2.AddInstance(testConfig(100, false), nil)
+	assert.NoError(t, err)
+
+	// equal priorities, the higher primary address wins
+	eventuallyState(t, i2, StateMaster)
+	eventuallyState(t, i1, StateBackup)
+	assert.True(t, r2.addrs.has("192.168.0.100"))
+	assert.False(t, r1.addrs.has("192.168.0.100"))
+
+	// the master resigns with priority 0, the backup takes over
+	r2.RemoveInstance(10)
+	assert.Equal(t, StateInitialize, i2.State())
+	assert.False(t, r2.addrs.has("192.168.0.100"))
+	eventuallyState(t, i1, StateMaster)
+	assert.True(t, r1.addrs.has("192.168.0.100"))
+
+	lock.Lock()
+	assert.Contains(t, states, StateMaster)
+	lock.Unlock()
+
+	_, err = r1.AddInstance(testConfig(100, false), nil)
+	assert.Error(t, err, "duplicated vrid")
+}
+
+func TestInstance_Preempt(t *testing.T) {
+	bus := &fakeBus{}
+	r1 := newTestRouter(bus, "192.168.0.1")
+	r2 := newTestRouter(bus, "192.168.0.2")
+	defer r1.Close()
+	defer r2.Close()
+
+	i1, err := r1.AddInstance(testConfig(100, false), nil)
+	assert.NoError(t, err)
+	eventuallyState(t, i1, StateMaster)
+
+	// without preemption a higher priority router stays backup
+	i2, err := r2.AddInstance(testConfig(200, false), nil)
+	assert.NoError(t, err)
+	time.Sleep(10 * testInterval)
+	assert.Equal(t, StateBackup, i2.State())
+	assert.Equal(t, StateMaster, i1.State())
+
+	// with preemption it takes over
+	assert.NoError(t, i2.Update(testConfig(200, true)))
+	eventuallyState(t, i2, StateMaster)
+	eventuallyState(t, i1, StateBackup)
+	assert.False(t, r1.addrs.has("192.168.0.100"))
+	assert.True(t, r2.addrs.has("192.168.0.100"))
+}
+
+func TestInstance_UpdateAddresses(t *testing.T) {
+	bus := &fakeBus{}
+	r := newTestRouter(bus, "192.168.0.1")
+	defer r.Close()
+
+	i, err := r.AddInstance(testConfig(255, false), nil)
+	assert.NoError(t, err)
+	eventuallyState(t, i, StateMaster)
+	assert.True(t, r.addrs.has("192.168.0.100"))
+
+	config := testConfig(255, false)
+	config.Addresses = []net.IP{net.ParseIP("192.168.0.101")}
+	assert.NoError(t, i.Update(config))
+	assert.Eventually(t, func() bool {
+		return r.addrs.has("192.168.0.101") && !r.addrs.has("192.168.0.100")
+	}, time.Second, testInterval)
+
+	config.VRID = 11
+	assert.Error(t, i.Update(config))
+}
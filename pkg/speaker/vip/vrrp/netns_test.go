@@ -0,0 +1,117 @@
+package vrrp
+
+import (
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+// TestRouter_Veth runs two virtual routers in separate network namespaces
+// connected by a veth pair.
+func TestRouter_Veth(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("creating network namespaces requires root")
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	origin, err := netns.Get()
+	require.NoError(t, err)
+	defer origin.Close()
+	defer netns.Set(origin)
+
+	nsA, err := netns.New()
+	require.NoError(t, err)
+	defer nsA.Close()
+	require.NoError(t, netlink.LinkAdd(&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "vrrp-a"}, PeerName: "vrrp-b"}))
+	peer, err := netlink.LinkByName("vrrp-b")
+	require.NoError(t, err)
+
+	nsB, err := netns.New()
+	require.NoError(t, err)
+	defer nsB.Close()
+	require.NoError(t, netns.Set(nsA))
+	require.NoError(t, netlink.LinkSetNsFd(peer, int(nsB)))
+
+	routerA, linkA := setupVethRouter(t, nsA, "vrrp-a", "10.99.0.1/24")
+	defer routerA.Close()
+	routerB, linkB := setupVethRouter(t, nsB, "vrrp-b", "10.99.0.2/24")
+	defer routerB.Close()
+	require.NoError(t, netns.Set(origin))
+
+	handleA, err := netlink.NewHandleAt(nsA)
+	require.NoError(t, err)
+	defer handleA.Delete()
+	handleB, err := netlink.NewHandleAt(nsB)
+	require.NoError(t, err)
+	defer handleB.Delete()
+
+	hasVIP := func(h *netlink.Handle, link netlink.Link) bool {
+		addrs, err := h.AddrList(link, netlink.FAMILY_V4)
+		if err != nil {
+			return false
+		}
+		for _, a := range addrs {
+			if a.IP.Equal(net.ParseIP("10.99.0.100")) {
+				return true
+			}
+		}
+		return false
+	}
+
+	config := Config{
+		VRID:      42,
+		Priority:  100,
+		Interval:  100 * time.Millisecond,
+		Addresses: []net.IP{net.ParseIP("10.99.0.100")},
+	}
+	instanceA, err := routerA.AddInstance(config, nil)
+	require.NoError(t, err)
+
+	config.Priority = 150
+	config.Preempt = true
+	instanceB, err := routerB.AddInstance(config, nil)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return instanceB.State() == StateMaster && instanceA.State() == StateBackup
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return hasVIP(handleB, linkB) && !hasVIP(handleA, linkA)
+	}, time.Second, 50*time.Millisecond)
+
+	routerB.RemoveInstance(42)
+	assert.False(t, hasVIP(handleB, linkB))
+	assert.Eventually(t, func() bool {
+		return instanceA.State() == StateMaster && hasVIP(handleA, linkA)
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+// setupVethRouter configures the interface in ns and opens the router sockets inside it.
+func setupVethRouter(t *testing.T, ns netns.NsHandle, name, cidr string) (*Router, netlink.Link) {
+	require.NoError(t, netns.Set(ns))
+
+	link, err := netlink.LinkByName(name)
+	require.NoError(t, err)
+	addr, err := netlink.ParseAddr(cidr)
+	require.NoError(t, err)
+	require.NoError(t, netlink.AddrAdd(link, addr))
+	require.NoError(t, netlink.LinkSetUp(link))
+
+	ifi, err := net.InterfaceByName(name)
+	require.NoError(t, err)
+	conn, err := NewConn(ifi, false)
+	require.NoError(t, err)
+	addrs, err := NewAddressManager(ifi, false)
+	require.NoError(t, err)
+
+	return NewRouter(conn, addrs), link
+}
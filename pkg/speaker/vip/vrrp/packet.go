@@ -0,0 +1,150 @@
+package vrrp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// ProtocolVRRP is the IP protocol number of VRRP.
+	ProtocolVRRP = 112
+
+	version             = 3
+	typeAdvertisement   = 1
+	headerLen           = 8
+	maxAdvertInterval   = 0x0fff
+	centisecond         = 10 * time.Millisecond
+	defaultPriority     = 100
+	priorityOwner       = 255
+	priorityStepDown    = 0
+	advertisementTTL    = 255
+	advertisementHopLim = 255
+)
+
+var (
+	multicastGroupV4 = net.IPv4(224, 0, 0, 18)
+	multicastGroupV6 = net.ParseIP("ff02::12")
+)
+
+// Advertisement is a VRRPv3 advertisement as defined in RFC 5798 section 5.
+type Advertisement struct {
+	VRID     uint8
+	Priority uint8
+	// Interval is the advertisement interval, sent with a centisecond resolution.
+	Interval  time.Duration
+	Addresses []net.IP
+}
+
+// Marshal encodes the advertisement, the checksum covers the pseudo-header
+// built from src and dst.
+func (a *Advertisement) Marshal(src, dst net.IP) ([]byte, error) {
+	v4 := dst.To4() != nil
+	addrLen := net.IPv6len
+	if v4 {
+		addrLen = net.IPv4len
+	}
+
+	if len(a.Addresses) > 255 {
+		return nil, fmt.Errorf("too many addresses: %d", len(a.Addresses))
+	}
+
+	interval := a.Interval / centisecond
+	if interval <= 0 || interval > maxAdvertInterval {
+		return nil, fmt.Errorf("invalid advertisement interval %s", a.Interval)
+	}
+
+	b := make([]byte, headerLen+addrLen*len(a.Addresses))
+	b[0] = version<<4 | typeAdvertisement
+	b[1] = a.VRID
+	b[2] = a.Priority
+	b[3] = uint8(len(a.Addresses))
+	binary.BigEndian.PutUint16(b[4:6], uint16(interval)&maxAdvertInterval)
+	for i, ip := range a.Addresses {
+		addr := ip.To16()
+		if v4 {
+			addr = ip.To4()
+		}
+		if addr == nil {
+			return nil, fmt.Errorf("address %s does not match the family of %s", ip, dst)
+		}
+		copy(b[headerLen+i*addrLen:], addr)
+	}
+
+	binary.BigEndian.PutUint16(b[6:8], checksum(b, src, dst))
+	return b, nil
+}
+
+// ParseAdvertisement decodes and validates an advertisement received from src on dst.
+func ParseAdvertisement(b []byte, src, dst net.IP) (*Advertisement, error) {
+	if len(b) < headerLen {
+		return nil, fmt.Errorf("packet too short: %d bytes", len(b))
+	}
+
+	if b[0]>>4 != version {
+		return nil, fmt.Errorf("unsupported vrrp version %d", b[0]>>4)
+	}
+	if b[0]&0x0f != typeAdvertisement {
+		return nil, fmt.Errorf("unsupported vrrp type %d", b[0]&0x0f)
+	}
+
+	addrLen := net.IPv6len
+	if dst.To4() != nil {
+		addrLen = net.IPv4len
+	}
+	count := int(b[3])
+	if len(b) < headerLen+count*addrLen {
+		return nil, fmt.Errorf("packet too short for %d addresses: %d bytes", count, len(b))
+	}
+	b = b[:headerLen+count*addrLen]
+
+	if checksum(b, src, dst) != 0 {
+		return nil, fmt.Errorf("invalid checksum")
+	}
+
+	a := &Advertisement{
+		VRID:     b[1],
+		Priority: b[2],
+		Interval: time.Duration(binary.BigEndian.Uint16(b[4:6])&maxAdvertInterval) * centisecond,
+	}
+	for i := 0; i < count; i++ {
+		ip := make(net.IP, addrLen)
+		copy(ip, b[headerLen+i*addrLen:])
+		a.Addresses = append(a.Addresses, ip)
+	}
+	return a, nil
+}
+
+// checksum computes the internet checksum of b prefixed by the IPv4 or IPv6
+// pseudo-header. A message carrying a valid checksum sums to zero.
+func checksum(b []byte, src, dst net.IP) uint16 {
+	var pseudo []byte
+	if src4, dst4 := src.To4(), dst.To4(); src4 != nil && dst4 != nil {
+		pseudo = make([]byte, 12)
+		copy(pseudo[0:4], src4)
+		copy(pseudo[4:8], dst4)
+		pseudo[9] = ProtocolVRRP
+		binary.BigEndian.PutUint16(pseudo[10:12], uint16(len(b)))
+	} else {
+		pseudo = make([]byte, 40)
+		copy(pseudo[0:16], src.To16())
+		copy(pseudo[16:32], dst.To16())
+		binary.BigEndian.PutUint32(pseudo[32:36], uint32(len(b)))
+		pseudo[39] = ProtocolVRRP
+	}
+
+	var sum uint32
+	for _, data := range [][]byte{pseudo, b} {
+		for i := 0; i+1 < len(data); i += 2 {
+			sum += uint32(binary.BigEndian.Uint16(data[i:]))
+		}
+		if len(data)%2 == 1 {
+			sum += uint32(data[len(data)-1]) << 8
+		}
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
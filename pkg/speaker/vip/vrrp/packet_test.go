@@ -0,0 +1,61 @@
+package vrrp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvertisement_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		src  net.IP
+		dst  net.IP
+		adv  Advertisement
+	}{
+		{
+			name: "ipv4",
+			src:  net.ParseIP("192.168.0.2"),
+			dst:  multicastGroupV4,
+			adv: Advertisement{VRID: 7, Priority: 100, Interval: time.Second,
+				Addresses: []net.IP{net.ParseIP("192.168.0.100").To4(), net.ParseIP("192.168.0.101").To4()}},
+		},
+		{
+			name: "ipv6",
+			src:  net.ParseIP("fe80::1"),
+			dst:  multicastGroupV6,
+			adv: Advertisement{VRID: 255, Priority: 254, Interval: 250 * time.Millisecond,
+				Addresses: []net.IP{net.ParseIP("2001:db8::100")}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.adv.Marshal(tt.src, tt.dst)
+			assert.NoError(t, err)
+
+			got, err := ParseAdvertisement(b, tt.src, tt.dst)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.adv, *got)
+
+			_, err = ParseAdvertisement(b, net.ParseIP("192.168.0.3"), tt.dst)
+			assert.Error(t, err, "checksum covers the source address")
+
+			b[0] = 2<<4 | typeAdvertisement
+			_, err = ParseAdvertisement(b, tt.src, tt.dst)
+			assert.Error(t, err, "vrrpv2 is not supported")
+		})
+	}
+}
+
+func TestAdvertisement_MarshalInvalid(t *testing.T) {
+	adv := Advertisement{VRID: 1, Priority: 100, Interval: time.Hour}
+	_, err := adv.Marshal(net.ParseIP("192.168.0.2"), multicastGroupV4)
+	assert.Error(t, err)
+
+	adv = Advertisement{VRID: 1, Priority: 100, Interval: time.Second, Addresses: []net.IP{net.ParseIP("2001:db8::1")}}
+	_, err = adv.Marshal(net.ParseIP("192.168.0.2"), multicastGroupV4)
+	assert.Error(t, err, "address family mismatch")
+}
@@ -0,0 +1,125 @@
+package vrrp
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// Router runs the virtual routers of one address family on one interface and
+// dispatches the received advertisements to them by VRID.
+type Router struct {
+	conn  Conn
+	addrs AddressManager
+
+	lock      sync.Mutex
+	instances map[uint8]*Instance
+
+	closeCh chan struct{}
+	done    chan struct{}
+}
+
+// NewRouter takes ownership of conn and addrs and starts receiving advertisements.
+func NewRouter(conn Conn, addrs AddressManager) *Router {
+	r := &Router{
+		conn:      conn,
+		addrs:     addrs,
+		instances: make(map[uint8]*Instance),
+		closeCh:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go r.receive()
+	return r
+}
+
+func (r *Router) receive() {
+	defer close(r.done)
+
+	local := r.conn.LocalAddr()
+	for {
+		adv, src, err := r.conn.ReadAdvertisement()
+		if err != nil {
+			select {
+			case <-r.closeCh:
+			default:
+				klog.Errorf("vrrp receive on %s: %v", local, err)
+			}
+			return
+		}
+		if src.Equal(local) {
+			continue
+		}
+
+		r.lock.Lock()
+		instance := r.instances[adv.VRID]
+		r.lock.Unlock()
+		if instance != nil {
+			instance.Deliver(adv, src)
+		}
+	}
+}
+
+// AddInstance creates and starts a virtual router.
+func (r *Router) AddInstance(config Config, onStateChange StateHandler) (*Instance, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, exist := r.instances[config.VRID]; exist {
+		return nil, fmt.Errorf("vrrp instance %d already exists", config.VRID)
+	}
+
+	instance, err := NewInstance(r.conn, r.addrs, config, onStateChange)
+	if err != nil {
+		return nil, err
+	}
+	r.instances[config.VRID] = instance
+	instance.Start()
+	return instance, nil
+}
+
+// Instance returns the running virtual router with the given VRID.
+func (r *Router) Instance(vrid uint8) *Instance {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.instances[vrid]
+}
+
+// RemoveInstance stops the virtual router and releases its addresses.
+func (r *Router) RemoveInstance(vrid uint8) {
+	r.lock.Lock()
+	instance, exist := r.instances[vrid]
+	delete(r.instances, vrid)
+	r.lock.Unlock()
+
+	if exist {
+		instance.Stop()
+	}
+}
+
+// Len returns the number of running virtual routers.
+func (r *Router) Len() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.instances)
+}
+
+// Close stops every virtual router and closes the underlying sockets.
+func (r *Router) Close() error {
+	r.lock.Lock()
+	instances := r.instances
+	r.instances = make(map[uint8]*Instance)
+	r.lock.Unlock()
+
+	for _, instance := range instances {
+		instance.Stop()
+	}
+
+	close(r.closeCh)
+	err := r.conn.Close()
+	<-r.done
+	if aerr := r.addrs.Close(); err == nil {
+		err = aerr
+	}
+	return err
+}
@@ -0,0 +1,179 @@
+package vip
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openelb/openelb/pkg/constant"
+	"github.com/openelb/openelb/pkg/speaker"
+	"github.com/openelb/openelb/pkg/speaker/vip/vrrp"
+	"github.com/openelb/openelb/pkg/util/iprange"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// silentConn is a segment without any other router.
+type silentConn struct {
+	local  net.IP
+	closed chan struct{}
+}
+
+func (c *silentConn) WriteAdvertisement(*vrrp.Advertisement) error { return nil }
+
+func (c *silentConn) ReadAdvertisement() (*vrrp.Advertisement, net.IP, error) {
+	<-c.closed
+	return nil, nil, errors.New("closed")
+}
+
+func (c *silentConn) LocalAddr() net.IP { return c.local }
+
+func (c *silentConn) Close() error {
+	close(c.closed)
+	return nil
+}
+
+type fakeAddresses struct {
+	lock  sync.Mutex
+	addrs map[string]bool
+}
+
+func (f *fakeAddresses) AddAddress(ip net.IP) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.addrs[ip.String()] = true
+	return nil
+}
+
+func (f *fakeAddresses) DelAddress(ip net.IP) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	delete(f.addrs, ip.String())
+	return nil
+}
+
+func (f *fakeAddresses) Close() error { return nil }
+
+func (f *fakeAddresses) has(ip string) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.addrs[ip]
+}
+
+func TestVrrpSpeaker(t *testing.T) {
+	t.Setenv(constant.EnvNodeName, "node1")
+
+	opt := NewVipOptions()
+	opt.AdvertInterval = 10 * time.Millisecond
+	v := NewVrrpSpeaker(nil, opt)
+
+	addrs := map[bool]*fakeAddresses{}
+	v.newRouter = func(iface string, ipv6 bool) (*vrrp.Router, error) {
+		assert.Equal(t, "eth0", iface)
+		local := "192.168.0.1"
+		if ipv6 {
+			local = "fe80::1"
+		}
+		addrs[ipv6] = &fakeAddresses{addrs: map[string]bool{}}
+		return vrrp.NewRouter(&silentConn{local: net.ParseIP(local), closed: make(chan struct{})}, addrs[ipv6]), nil
+	}
+
+	for name, cidr := range map[string]string{"v4": "192.168.0.100-192.168.0.110", "v6": "2001:db8::100-2001:db8::110"} {
+		r, err := iprange.ParseRange(cidr)
+		assert.NoError(t, err)
+		v.configs[name] = &speaker.Config{Name: name, IPRange: r, Iface: "eth0"}
+	}
+
+	nodes := []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}, {ObjectMeta: metav1.ObjectMeta{Name: "node2"}}}
+	assert.NoError(t, v.SetBalancer("192.168.0.100", nodes))
+	assert.NoError(t, v.SetBalancer("192.168.0.101", nodes))
+	assert.NoError(t, v.SetBalancer("2001:db8::100", nodes))
+	assert.Error(t, v.SetBalancer("10.0.0.1", nodes), "no interface for the vip")
+	assert.Len(t, v.instances, 1)
+	assert.Len(t, v.routers, 2)
+
+	assert.Eventually(t, func() bool {
+		return addrs[false].has("192.168.0.100") && addrs[false].has("192.168.0.101") && addrs[true].has("2001:db8::100")
+	}, time.Second, 10*time.Millisecond)
+
+	// setting the same nodes again keeps the instance running
+	assert.NoError(t, v.SetBalancer("192.168.0.100", nodes))
+	for _, instance := range v.instances {
+		assert.Equal(t, []string{"192.168.0.100", "192.168.0.101", "2001:db8::100"}, instance.Svcips)
+		assert.Equal(t, vrrp.StateMaster, instance.running[false].State())
+	}
+	assert.True(t, v.OwnBalancer("192.168.0.100"))
+	assert.False(t, v.OwnBalancer("192.168.0.102"))
+
+	// the last ipv6 vip stops the ipv6 router
+	assert.NoError(t, v.DelBalancer("2001:db8::100"))
+	assert.False(t, addrs[true].has("2001:db8::100"))
+	assert.Len(t, v.routers, 1)
+
+	// moving the vips to other nodes stops the local instance
+	others := []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node2"}}}
+	assert.NoError(t, v.SetBalancer("192.168.0.100", others))
+	assert.NoError(t, v.SetBalancer("192.168.0.101", others))
+	assert.False(t, addrs[false].has("192.168.0.100"))
+	assert.False(t, v.OwnBalancer("192.168.0.100"))
+	assert.Len(t, v.instances, 1)
+	assert.Len(t, v.routers, 0)
+
+	stopCh := make(chan struct{})
+	close(stopCh)
+	assert.NoError(t, v.Start(stopCh))
+}
+
+func TestVrrpSpeaker_NodeSettingsAndTracking(t *testing.T) {
+	t.Setenv(constant.EnvNodeName, "node1")
+
+	opt := NewVipOptions()
+	opt.AdvertInterval = 10 * time.Millisecond
+	v := NewVrrpSpeaker(nil, opt)
+
+	addrs := &fakeAddresses{addrs: map[string]bool{}}
+	v.newRouter = func(iface string, ipv6 bool) (*vrrp.Router, error) {
+		return vrrp.NewRouter(&silentConn{local: net.ParseIP("192.168.0.1"), closed: make(chan struct{})}, addrs), nil
+	}
+	r, err := iprange.ParseRange("192.168.0.100-192.168.0.110")
+	assert.NoError(t, err)
+	v.configs["v4"] = &speaker.Config{Name: "v4", IPRange: r, Iface: "eth0"}
+
+	up := true
+	v.tracker.linkUp = func(string) bool { return up }
+
+	node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1",
+		Annotations: map[string]string{constant.OpenELBVipPriority: "150", constant.OpenELBVipPreempt: "true"}}}
+	assert.NoError(t, v.SetBalancer("192.168.0.100", []corev1.Node{node}))
+
+	assert.Len(t, v.instances, 1)
+	var instance *vrrpInstance
+	for _, i := range v.instances {
+		instance = i
+	}
+	config := instance.running[false].Config()
+	assert.Equal(t, uint8(150), config.Priority)
+	assert.True(t, config.Preempt)
+
+	v.updateSettings(nodeSettings{Priority: 120})
+	config = instance.running[false].Config()
+	assert.Equal(t, uint8(120), config.Priority)
+	assert.False(t, config.Preempt)
+
+	// the node gives up the vip once its interface is faulty
+	assert.Eventually(t, func() bool { return addrs.has("192.168.0.100") }, time.Second, 10*time.Millisecond)
+	up = false
+	v.track()
+	v.track()
+	assert.Nil(t, instance.running[false])
+	assert.False(t, addrs.has("192.168.0.100"))
+	assert.Len(t, v.routers, 0)
+
+	up = true
+	v.track()
+	v.track()
+	assert.NotNil(t, instance.running[false])
+}